@@ -25,18 +25,33 @@ type CacheServerSpec struct {
 	// Etcd configures the etcd cluster that this cache server should be using.
 	Etcd EtcdConfig `json:"etcd"`
 
-	// Optional: Image overwrites the container image used to deploy the cache server.
+	// Optional: Image overwrites the container image used to deploy the cache server. If no tag is set,
+	// the kcp version of the RootShard referencing this cache server is used.
 	Image *ImageSpec `json:"image,omitempty"`
 }
 
 // CacheServerStatus defines the observed state of CacheServer
 type CacheServerStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Replicas is the desired number of replicas for the cache server.
+	Replicas int32 `json:"replicas,omitempty"`
+	// ReadyReplicas is the number of cache server replicas that are ready.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Version is the kcp version (i.e. image tag) that the cache server is configured to run.
+	Version string `json:"version,omitempty"`
+	// ResolvedImage is the image digest the cache server has been pinned to, if digest resolution is enabled.
+	ResolvedImage *ResolvedImage `json:"resolvedImage,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // CacheServer is the Schema for the cacheservers API
 type CacheServer struct {
@@ -18,20 +18,167 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PausedAnnotation can be set to "true" on any kcp-operator resource to pause its reconciliation, e.g. to
+// manually change the generated objects during a maintenance window. The operator keeps publishing the
+// observed status of a paused resource, but does not create, update or delete any objects for it.
+const PausedAnnotation = "operator.kcp.io/paused"
+
+// ConditionType is the type of a status condition set on kcp-operator resources.
+type ConditionType string
+
+const (
+	// ConditionTypeAvailable signals that at least one replica of a component is ready to serve requests.
+	ConditionTypeAvailable ConditionType = "Available"
+	// ConditionTypeExposed signals that a component has been assigned an external address.
+	ConditionTypeExposed ConditionType = "Exposed"
+	// ConditionTypeVirtualWorkspacesAvailable signals that the standalone virtual-workspaces server of a shard is ready.
+	ConditionTypeVirtualWorkspacesAvailable ConditionType = "VirtualWorkspacesAvailable"
+	// ConditionTypeUpgraded signals that all components of a kcp setup run the desired kcp version.
+	ConditionTypeUpgraded ConditionType = "Upgraded"
+	// ConditionTypeVersionCompatible signals that a component's kcp version is supported and within the
+	// supported version skew relative to its RootShard. Components are not updated while this is false.
+	ConditionTypeVersionCompatible ConditionType = "VersionCompatible"
+	// ConditionTypeImageResolved signals that the image tag of a component has been resolved to a digest.
+	// It is only set if digest resolution has been enabled in the component's ImageSpec.
+	ConditionTypeImageResolved ConditionType = "ImageResolved"
+	// ConditionTypePaused signals that reconciliation has been paused via the PausedAnnotation.
+	ConditionTypePaused ConditionType = "Paused"
+	// ConditionTypeDeletionBlocked signals that the deletion of a Shard is blocked by its deletion protection.
+	ConditionTypeDeletionBlocked ConditionType = "DeletionBlocked"
+	// ConditionTypeScheduled signals that snapshots of an EtcdBackup are being scheduled.
+	ConditionTypeScheduled ConditionType = "Scheduled"
+	// ConditionTypeTargetScaledDown signals that the target of an EtcdRestore has been scaled down.
+	ConditionTypeTargetScaledDown ConditionType = "TargetScaledDown"
+	// ConditionTypeSnapshotRestored signals that the snapshot of an EtcdRestore has been restored into etcd.
+	ConditionTypeSnapshotRestored ConditionType = "SnapshotRestored"
+	// ConditionTypeTargetScaledUp signals that the target of an EtcdRestore is available again.
+	ConditionTypeTargetScaledUp ConditionType = "TargetScaledUp"
+	// ConditionTypeEtcdCertificateValid signals whether the etcd client certificate Secret is valid.
+	ConditionTypeEtcdCertificateValid ConditionType = "EtcdCertificateValid"
+	// ConditionTypeEtcdReachable signals whether the configured etcd endpoints pass a health check.
+	ConditionTypeEtcdReachable ConditionType = "EtcdReachable"
+	// ConditionTypeKubeconfigReady signals that the kubeconfig of a Kubeconfig object has been written to its Secret.
+	ConditionTypeKubeconfigReady ConditionType = "Ready"
+	// ConditionTypePermissionsBound signals that the ClusterRoleBindings of a Kubeconfig have been created in kcp.
+	ConditionTypePermissionsBound ConditionType = "PermissionsBound"
+	// ConditionTypeSecretMirrored signals that the kubeconfig Secret has been copied into all configured namespaces.
+	ConditionTypeSecretMirrored ConditionType = "SecretMirrored"
+	// ConditionTypeBreakGlassGranted signals that users have been granted access to the admin kubeconfig of a
+	// RootShard via the BreakGlassAnnotation.
+	ConditionTypeBreakGlassGranted ConditionType = "BreakGlassGranted"
+)
+
+// ConditionReason is a machine-readable reason for a status condition.
+type ConditionReason string
+
+const (
+	ConditionReasonReplicasReady      ConditionReason = "ReplicasReady"
+	ConditionReasonReplicasNotReady   ConditionReason = "ReplicasNotReady"
+	ConditionReasonRootShardNotFound  ConditionReason = "RootShardNotFound"
+	ConditionReasonAddressAssigned    ConditionReason = "AddressAssigned"
+	ConditionReasonAddressPending     ConditionReason = "AddressPending"
+	ConditionReasonCANotConfigured    ConditionReason = "CANotConfigured"
+	ConditionReasonRefNotPermitted    ConditionReason = "ReferenceNotPermitted"
+	ConditionReasonUpgradeCompleted   ConditionReason = "UpgradeCompleted"
+	ConditionReasonUpgradeInProgress  ConditionReason = "UpgradeInProgress"
+	ConditionReasonUpgradeFailed      ConditionReason = "UpgradeFailed"
+	ConditionReasonVersionSupported   ConditionReason = "VersionSupported"
+	ConditionReasonVersionUnknown     ConditionReason = "VersionUnknown"
+	ConditionReasonUnsupportedVersion ConditionReason = "UnsupportedVersion"
+	ConditionReasonUnsupportedSkew    ConditionReason = "UnsupportedVersionSkew"
+	ConditionReasonDigestResolved     ConditionReason = "DigestResolved"
+	ConditionReasonDigestFailed       ConditionReason = "DigestResolutionFailed"
+	ConditionReasonPaused             ConditionReason = "ReconciliationPaused"
+	ConditionReasonClustersRemaining  ConditionReason = "LogicalClustersRemaining"
+	ConditionReasonBackupScheduled    ConditionReason = "BackupScheduled"
+	ConditionReasonBackupSuspended    ConditionReason = "BackupSuspended"
+	ConditionReasonTargetNotFound     ConditionReason = "TargetNotFound"
+	ConditionReasonBackupNotFound     ConditionReason = "BackupNotFound"
+	ConditionReasonRestoreConflict    ConditionReason = "RestoreInProgress"
+	ConditionReasonInProgress         ConditionReason = "InProgress"
+	ConditionReasonSucceeded          ConditionReason = "Succeeded"
+	ConditionReasonFailed             ConditionReason = "Failed"
+	ConditionReasonCertificateValid   ConditionReason = "CertificateValid"
+	ConditionReasonCertificateInvalid ConditionReason = "CertificateInvalid"
+	ConditionReasonCertificateExpired ConditionReason = "CertificateExpired"
+	ConditionReasonCertificateExpiry  ConditionReason = "CertificateExpiringSoon"
+	ConditionReasonSecretNotFound     ConditionReason = "SecretNotFound"
+	ConditionReasonEndpointsHealthy   ConditionReason = "EndpointsHealthy"
+	ConditionReasonEndpointsUnhealthy ConditionReason = "EndpointsUnhealthy"
+	ConditionReasonEtcdUnreachable    ConditionReason = "EtcdUnreachable"
+	ConditionReasonKubeconfigWritten  ConditionReason = "KubeconfigWritten"
+	ConditionReasonClusterRolesBound  ConditionReason = "ClusterRolesBound"
+	ConditionReasonBindingFailed      ConditionReason = "BindingFailed"
+	ConditionReasonRevoked            ConditionReason = "Revoked"
+	ConditionReasonInvalidOutput      ConditionReason = "InvalidOutput"
+	ConditionReasonSecretCopied       ConditionReason = "SecretCopied"
+	ConditionReasonMirrorFailed       ConditionReason = "MirrorFailed"
+	ConditionReasonAccessGranted      ConditionReason = "AccessGranted"
 )
 
 // ImageSpec defines settings for using a specific image and overwriting the default images used.
 type ImageSpec struct {
 	// Repository is the container image repository to use for KCP containers. Defaults to `ghcr.io/kcp-dev/kcp`.
 	Repository string `json:"repository,omitempty"`
-	// Tag is the container image tag to use for KCP containers. Defaults to the latest kcp release that the operator supports.
+	// Tag is the container image tag to use for KCP containers. If set, it takes precedence over the kcp version
+	// configured on the RootShard and excludes the component from managed upgrades. Defaults to the version
+	// configured on the RootShard, or the latest kcp release that the operator supports.
 	Tag string `json:"tag,omitempty"`
+	// Digest pins the container image to the given digest (e.g. `sha256:...`). The tag is kept in the image
+	// reference for readability, but the container runtime only uses the digest.
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// ResolveDigest makes the operator resolve the image tag to a digest once and record it in the status of
+	// the object. Subsequent rollouts use the recorded digest, so retagging an image does not change what is
+	// running. The tag is only resolved again when the image reference changes. Ignored if Digest is set.
+	// +optional
+	ResolveDigest bool `json:"resolveDigest,omitempty"`
 	// Optional: ImagePullSecrets is a list of secret references that should be used as image pull secrets (e.g. when a private registry is used).
 	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
 }
 
+// ResolvedImage records the digest an image tag has been resolved to.
+type ResolvedImage struct {
+	// Image is the image reference (repository and tag) that has been resolved.
+	Image string `json:"image"`
+	// Digest is the digest the image reference pointed to at the time of resolution.
+	Digest string `json:"digest"`
+	// ResolvedAt is the time the digest has been resolved.
+	ResolvedAt metav1.Time `json:"resolvedAt,omitempty"`
+}
+
+// ShardURLs are the URLs under which a (root) shard is reachable.
+type ShardURLs struct {
+	// Internal is the base URL of the shard's Service, reachable from within the cluster.
+	Internal string `json:"internal,omitempty"`
+	// External is the URL under which clients reach the shard from outside the cluster (via the front-proxy).
+	External string `json:"external,omitempty"`
+	// VirtualWorkspaces is the base URL of the virtual workspaces served for this shard.
+	VirtualWorkspaces string `json:"virtualWorkspaces,omitempty"`
+}
+
+// ShardInventory describes what a (root) shard is hosting, as reported by kcp.
+type ShardInventory struct {
+	// LogicalClusters is the number of logical clusters stored on the shard.
+	LogicalClusters int32 `json:"logicalClusters"`
+	// Workspaces is the number of Workspace objects stored on the shard, i.e. the number of child
+	// workspaces of the logical clusters on this shard. The workspaces themselves can be scheduled
+	// onto any shard.
+	Workspaces int32 `json:"workspaces"`
+	// URLs are the URLs published on the shard's kcp Shard object in the root workspace.
+	URLs ShardURLs `json:"urls,omitempty"`
+	// LastUpdated is the time the inventory has been collected.
+	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+}
+
 type RootShardConfig struct {
-	// Reference references a local RootShard object.
+	// Reference references a RootShard object. Only name and namespace are evaluated. If the namespace
+	// is empty, the RootShard is expected in the namespace of the referencing object. References to
+	// RootShards in other namespaces must be permitted by a ReferenceGrant in the RootShard's namespace.
 	Reference *corev1.ObjectReference `json:"ref,omitempty"`
 }
 
@@ -40,9 +187,33 @@ type EtcdConfig struct {
 	Endpoints []string `json:"endpoints"`
 	// ClientCert configures the client certificate used to access etcd.
 	ClientCert EtcdCertificate `json:"clientCert"`
+	// Optional: KeyPrefix is the etcd key prefix under which kcp stores its data. Defaults to "/registry".
+	// Multiple components can share one etcd cluster as long as they use distinct, non-nested prefixes.
+	// +kubebuilder:validation:Pattern=`^/[^/]+(/[^/]+)*$`
+	KeyPrefix string `json:"keyPrefix,omitempty"`
 }
 
 type EtcdCertificate struct {
-	// SecretRef is the reference to a v1.Secret object that contains the TLS certificate.
+	// SecretRef is the reference to a v1.Secret object that contains the TLS certificate. The Secret must
+	// contain the keys "tls.crt", "tls.key" and "ca.crt". "ca.crt" may contain multiple CA certificates,
+	// e.g. to trust both the old and the new etcd CA while it is being rotated. Pods are restarted whenever
+	// the Secret changes.
 	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// Optional: IssuerRef references a cert-manager issuer that issues the client certificate into the
+	// Secret referenced by SecretRef. If not set, the Secret has to be provided.
+	IssuerRef *IssuerReference `json:"issuerRef,omitempty"`
+}
+
+// IssuerReference references a cert-manager Issuer (in the same namespace) or ClusterIssuer.
+type IssuerReference struct {
+	// Name is the name of the issuer.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Kind is the kind of the issuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	Kind string `json:"kind,omitempty"`
+	// Group is the API group of the issuer. Defaults to "cert-manager.io"; external issuers use their own group.
+	Group string `json:"group,omitempty"`
 }
@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EtcdBackupTargetKind is the kind of object whose etcd is backed up.
+// +kubebuilder:validation:Enum=RootShard;Shard;CacheServer
+type EtcdBackupTargetKind string
+
+const (
+	EtcdBackupTargetRootShard   EtcdBackupTargetKind = "RootShard"
+	EtcdBackupTargetShard       EtcdBackupTargetKind = "Shard"
+	EtcdBackupTargetCacheServer EtcdBackupTargetKind = "CacheServer"
+)
+
+// EtcdBackupSpec defines the desired state of EtcdBackup.
+type EtcdBackupSpec struct {
+	// Target references the RootShard, Shard or CacheServer (in the same namespace) whose etcd cluster
+	// should be backed up. The etcd endpoints and client certificate configured on the target are used.
+	Target EtcdBackupTarget `json:"target"`
+
+	// Schedule is the cron schedule on which snapshots are taken, e.g. "0 */6 * * *".
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Optional: Suspend stops scheduling new snapshots. Already running snapshots are not affected.
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Storage configures where snapshots are stored.
+	Storage EtcdBackupStorage `json:"storage"`
+
+	// Optional: Retention is the number of snapshots to keep. Older snapshots are deleted after each
+	// successful snapshot. Defaults to 7.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=7
+	Retention int32 `json:"retention,omitempty"`
+
+	// Optional: EtcdImage is the container image providing etcdctl, which is used to take the snapshots.
+	EtcdImage string `json:"etcdImage,omitempty"`
+}
+
+type EtcdBackupTarget struct {
+	// Kind is the kind of the target object.
+	Kind EtcdBackupTargetKind `json:"kind"`
+	// Name is the name of the target object.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// EtcdBackupStorage configures where snapshots are stored. Exactly one storage backend must be configured.
+// +kubebuilder:validation:XValidation:rule="has(self.pvc) != has(self.s3)",message="exactly one of pvc or s3 must be set"
+type EtcdBackupStorage struct {
+	// PVC stores snapshots on a PersistentVolumeClaim in the namespace of the EtcdBackup.
+	PVC *PVCBackupStorage `json:"pvc,omitempty"`
+	// S3 stores snapshots in a bucket of an S3-compatible object storage.
+	S3 *S3BackupStorage `json:"s3,omitempty"`
+}
+
+type PVCBackupStorage struct {
+	// ClaimName is the name of the PersistentVolumeClaim.
+	// +kubebuilder:validation:MinLength=1
+	ClaimName string `json:"claimName"`
+	// Optional: Path is the directory on the volume that snapshots are written to. Defaults to the root of the volume.
+	Path string `json:"path,omitempty"`
+	// Optional: Image is the container image used to copy snapshots onto the volume and apply the retention policy.
+	Image string `json:"image,omitempty"`
+}
+
+type S3BackupStorage struct {
+	// Endpoint is the URL of the S3-compatible endpoint, e.g. "https://s3.eu-central-1.amazonaws.com"
+	// or "http://minio.minio.svc:9000".
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Endpoint string `json:"endpoint"`
+	// Bucket is the name of the bucket to store snapshots in.
+	// +kubebuilder:validation:MinLength=1
+	Bucket string `json:"bucket"`
+	// Optional: Prefix is prepended to the object keys of all snapshots.
+	Prefix string `json:"prefix,omitempty"`
+	// CredentialsSecretRef references a Secret in the namespace of the EtcdBackup with the keys
+	// "accessKeyID" and "secretAccessKey".
+	CredentialsSecretRef corev1.LocalObjectReference `json:"credentialsSecretRef"`
+	// Optional: Insecure disables TLS certificate verification for the endpoint.
+	Insecure bool `json:"insecure,omitempty"`
+	// Optional: Image is the container image providing the MinIO client (mc), used to upload snapshots
+	// and apply the retention policy.
+	Image string `json:"image,omitempty"`
+}
+
+// EtcdBackupRun describes a single snapshot run.
+type EtcdBackupRun struct {
+	// JobName is the name of the Job that took the snapshot.
+	JobName string `json:"jobName"`
+	// StartTime is the time the Job started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the Job finished.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Succeeded is true if the snapshot has been stored successfully.
+	Succeeded bool `json:"succeeded"`
+	// Message describes why the run failed, if it did.
+	Message string `json:"message,omitempty"`
+}
+
+// EtcdBackupStatus defines the observed state of EtcdBackup.
+type EtcdBackupStatus struct {
+	// LastScheduleTime is the last time a snapshot was scheduled.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastSuccessfulTime is the last time a snapshot was stored successfully.
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// LastRun describes the most recently finished snapshot run.
+	LastRun *EtcdBackupRun `json:"lastRun,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.target.name"
+// +kubebuilder:printcolumn:name="Schedule",type="string",JSONPath=".spec.schedule"
+// +kubebuilder:printcolumn:name="Last Success",type="date",JSONPath=".status.lastSuccessfulTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// EtcdBackup is the Schema for the etcdbackups API
+type EtcdBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdBackupSpec   `json:"spec,omitempty"`
+	Status EtcdBackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EtcdBackupList contains a list of EtcdBackup
+type EtcdBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EtcdBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EtcdBackup{}, &EtcdBackupList{})
+}
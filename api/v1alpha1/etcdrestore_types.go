@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RestoreAnnotation is set by the operator on a RootShard, Shard or CacheServer while an EtcdRestore is
+// restoring its etcd. Its value is the name of the EtcdRestore. The component is scaled down to zero
+// replicas as long as the annotation is present.
+const RestoreAnnotation = "operator.kcp.io/restore-in-progress"
+
+// EtcdRestorePhase is the phase of a restore.
+type EtcdRestorePhase string
+
+const (
+	EtcdRestorePhaseScalingDown EtcdRestorePhase = "ScalingDown"
+	EtcdRestorePhaseRestoring   EtcdRestorePhase = "Restoring"
+	EtcdRestorePhaseScalingUp   EtcdRestorePhase = "ScalingUp"
+	EtcdRestorePhaseCompleted   EtcdRestorePhase = "Completed"
+	EtcdRestorePhaseFailed      EtcdRestorePhase = "Failed"
+)
+
+// EtcdRestoreSpec defines the desired state of EtcdRestore.
+type EtcdRestoreSpec struct {
+	// Target references the RootShard, Shard or CacheServer (in the same namespace) whose etcd should be
+	// restored. The snapshot is restored into the etcd cluster currently configured on the target, which
+	// can be a fresh, empty cluster. The operator does not provision etcd, so restoring into a new cluster
+	// requires creating it and pointing the target's spec.etcd at it beforehand.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="target is immutable"
+	Target EtcdBackupTarget `json:"target"`
+
+	// BackupName is the name of the EtcdBackup (in the same namespace) whose storage the snapshot is read from.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="backupName is immutable"
+	BackupName string `json:"backupName"`
+
+	// Optional: Snapshot is the file name of the snapshot to restore, e.g. "20250101T000000Z.db". Defaults
+	// to the latest snapshot of the backup's target.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="snapshot is immutable"
+	Snapshot string `json:"snapshot,omitempty"`
+
+	// Optional: Image is the container image used to restore the snapshot. It must provide a shell as well
+	// as the etcd, etcdctl and etcdutl binaries.
+	Image string `json:"image,omitempty"`
+}
+
+// EtcdRestoreStatus defines the observed state of EtcdRestore.
+type EtcdRestoreStatus struct {
+	// Phase is the current phase of the restore.
+	Phase EtcdRestorePhase `json:"phase,omitempty"`
+	// JobName is the name of the Job restoring the snapshot.
+	JobName string `json:"jobName,omitempty"`
+	// StartTime is the time the restore started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the restore completed or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.target.name"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// EtcdRestore is the Schema for the etcdrestores API
+type EtcdRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EtcdRestoreSpec   `json:"spec,omitempty"`
+	Status EtcdRestoreStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EtcdRestoreList contains a list of EtcdRestore
+type EtcdRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EtcdRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EtcdRestore{}, &EtcdRestoreList{})
+}
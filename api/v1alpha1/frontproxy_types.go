@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,6 +29,54 @@ type FrontProxySpec struct {
 	Replicas *int32 `json:"replicas,omitempty"`
 	// Optional: Auth configures various aspects of Authentication and Authorization for this front-proxy instance.
 	Auth *AuthSpec `json:"auth,omitempty"`
+	// Optional: Image overwrites the container image used to deploy the front-proxy.
+	Image *ImageSpec `json:"image,omitempty"`
+
+	// Optional: Service configures how the front-proxy Service is exposed. Defaults to a LoadBalancer Service.
+	Service *FrontProxyServiceSpec `json:"service,omitempty"`
+	// Optional: Ingress configures an Ingress for the front-proxy that passes TLS through to the front-proxy
+	// pods. The ingress controller must support TLS passthrough.
+	Ingress *FrontProxyIngressSpec `json:"ingress,omitempty"`
+	// Optional: Gateway configures a Gateway API TLSRoute in passthrough mode for the front-proxy.
+	// The Gateway API CRDs must be installed in the cluster.
+	Gateway *FrontProxyGatewaySpec `json:"gateway,omitempty"`
+}
+
+type FrontProxyServiceSpec struct {
+	// Type is the type of the front-proxy Service. Defaults to LoadBalancer.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	Type corev1.ServiceType `json:"type,omitempty"`
+	// Optional: Annotations are added to the front-proxy Service, e.g. to configure a cloud provider's load balancer.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Optional: LoadBalancerClass selects a specific load balancer implementation. Only valid for LoadBalancer Services.
+	LoadBalancerClass *string `json:"loadBalancerClass,omitempty"`
+	// Optional: LoadBalancerSourceRanges restricts traffic through the load balancer to the given client IP ranges.
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+	// Optional: NodePort pins the node port used for NodePort and LoadBalancer Services.
+	NodePort *int32 `json:"nodePort,omitempty"`
+}
+
+type FrontProxyIngressSpec struct {
+	// Optional: IngressClassName selects the ingress controller responsible for the Ingress.
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+	// Optional: Annotations are added to the Ingress. The operator sets the annotations required by ingress-nginx
+	// for TLS passthrough, other ingress controllers might require additional annotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type FrontProxyGatewaySpec struct {
+	// ParentRefs are the Gateways (and listeners) the TLSRoute attaches to.
+	// +kubebuilder:validation:MinItems=1
+	ParentRefs []GatewayParentReference `json:"parentRefs"`
+}
+
+type GatewayParentReference struct {
+	// Name is the name of the Gateway.
+	Name string `json:"name"`
+	// Optional: Namespace is the namespace of the Gateway. Defaults to the namespace of the FrontProxy.
+	Namespace string `json:"namespace,omitempty"`
+	// Optional: SectionName is the name of the Gateway listener to attach to. The listener must use TLS passthrough.
+	SectionName string `json:"sectionName,omitempty"`
 }
 
 type AuthSpec struct {
@@ -37,12 +86,32 @@ type AuthSpec struct {
 
 // FrontProxyStatus defines the observed state of FrontProxy
 type FrontProxyStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Replicas is the desired number of front-proxy replicas.
+	Replicas int32 `json:"replicas,omitempty"`
+	// ReadyReplicas is the number of front-proxy replicas that report ready.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Version is the kcp version (i.e. image tag) that the front-proxy is configured to run.
+	Version string `json:"version,omitempty"`
+	// ResolvedImage is the image digest the front-proxy has been pinned to, if digest resolution is enabled.
+	ResolvedImage *ResolvedImage `json:"resolvedImage,omitempty"`
+	// ExternalAddress is the IP address or hostname the front-proxy is reachable at from outside the
+	// cluster, as reported by the load balancer or ingress controller. The DNS record for the RootShard's
+	// hostname should point to this address.
+	ExternalAddress string `json:"externalAddress,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Address",type="string",JSONPath=".status.externalAddress"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // FrontProxy is the Schema for the frontproxies API
 type FrontProxy struct {
@@ -22,6 +22,8 @@ import (
 )
 
 // KubeconfigSpec defines the desired state of Kubeconfig.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.renewBefore) || duration(self.renewBefore) < duration(self.validity)",message="renewBefore must be shorter than validity"
 type KubeconfigSpec struct {
 	// Target configures which kcp-operator object this kubeconfig should be generated for (shard or front-proxy).
 	Target KubeconfigTarget `json:"target"`
@@ -31,27 +33,238 @@ type KubeconfigSpec struct {
 	// Username defines the groups embedded in the TLS certificate generated for this kubeconfig.
 	Groups []string `json:"groups,omitempty"`
 
-	// Validity configures the lifetime of the embedded TLS certificate. The kubeconfig secret will be automatically regenerated when the certificate expires.
+	// Validity configures the lifetime of the embedded TLS certificate. The kubeconfig secret will be automatically regenerated before the certificate expires.
 	Validity metav1.Duration `json:"validity"`
 
+	// Optional: RenewBefore configures how long before its expiry the embedded TLS certificate is renewed.
+	// Must be shorter than validity. Defaults to a third of validity.
+	//
+	// +kubebuilder:validation:XValidation:rule="duration(self) > duration('0s')",message="renewBefore must be positive"
+	// +optional
+	RenewBefore *metav1.Duration `json:"renewBefore,omitempty"`
+
 	// SecretRef defines the v1.Secret object that the resulting kubeconfig should be written to.
 	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// Optional: Output configures the layout of the Secret that the kubeconfig is written to. By default, only
+	// the kubeconfig with embedded credentials is written to the key "kubeconfig".
+	//
+	// +optional
+	Output *KubeconfigOutput `json:"output,omitempty"`
+
+	// Optional: Mirror configures further namespaces that the kubeconfig Secret is copied into, so that
+	// consumers outside of the Kubeconfig's namespace can mount it. Copies are kept in sync and removed when
+	// their namespace is no longer selected or the Kubeconfig is deleted.
+	//
+	// +optional
+	Mirror *KubeconfigMirror `json:"mirror,omitempty"`
+
+	// Optional: Contexts configures additional contexts in the kubeconfig, each pointing at another workspace
+	// of the target. All contexts share the same client certificate. The default context is always named
+	// "default" and points at target.clusterPath.
+	//
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Contexts []KubeconfigContext `json:"contexts,omitempty"`
+
+	// Optional: Permissions configures ClusterRoleBindings that the operator creates inside kcp for the
+	// kubeconfig's user and groups. They are removed again when the Kubeconfig is deleted.
+	//
+	// +optional
+	Permissions *KubeconfigPermissions `json:"permissions,omitempty"`
+
+	// Optional: Revoked revokes the kubeconfig: the CA that signed its client certificate is no longer trusted
+	// by kcp and the kubeconfig Secret is deleted. Setting it back to false issues a new kubeconfig, signed by
+	// a new CA.
+	//
+	// +optional
+	Revoked bool `json:"revoked,omitempty"`
 }
 
 type KubeconfigTarget struct {
 	RootShardRef  *corev1.LocalObjectReference `json:"rootShardRef,omitempty"`
 	ShardRef      *corev1.LocalObjectReference `json:"shardRef,omitempty"`
 	FrontProxyRef *corev1.LocalObjectReference `json:"frontProxyRef,omitempty"`
+
+	// Optional: ClusterPath is the logical cluster path of the workspace (e.g. "root:org:team") that the default
+	// context of the kubeconfig points at. If empty, the server URL points at the base URL of the target.
+	//
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(:[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`
+	// +optional
+	ClusterPath string `json:"clusterPath,omitempty"`
+}
+
+// KubeconfigMirror selects the namespaces that a kubeconfig Secret is copied into. Copies have the same name as
+// the Secret. The Kubeconfig's own namespace is always skipped.
+type KubeconfigMirror struct {
+	// Optional: Namespaces lists the namespaces to copy the Secret into. Namespaces that do not exist yet are
+	// skipped until they are created.
+	//
+	// +listType=set
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Optional: NamespaceSelector selects further namespaces by their labels. An empty selector selects all
+	// namespaces.
+	//
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// KubeconfigOutput configures the layout of a kubeconfig Secret. All keys must be distinct.
+//
+// +kubebuilder:validation:XValidation:rule="!has(self.credentialsMountPath) || (has(self.includeCertificate) && self.includeCertificate && has(self.includeCABundle) && self.includeCABundle)",message="credentialsMountPath requires includeCertificate and includeCABundle"
+type KubeconfigOutput struct {
+	// Optional: KubeconfigKey is the key that the kubeconfig is written to. Defaults to "kubeconfig".
+	//
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	// +optional
+	KubeconfigKey string `json:"kubeconfigKey,omitempty"`
+
+	// Optional: IncludeCertificate additionally writes the PEM encoded client certificate and private key to
+	// separate keys.
+	//
+	// +optional
+	IncludeCertificate bool `json:"includeCertificate,omitempty"`
+	// Optional: CertificateKey is the key that the client certificate is written to. Defaults to "tls.crt".
+	//
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	// +optional
+	CertificateKey string `json:"certificateKey,omitempty"`
+	// Optional: PrivateKeyKey is the key that the private key is written to. Defaults to "tls.key".
+	//
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	// +optional
+	PrivateKeyKey string `json:"privateKeyKey,omitempty"`
+
+	// Optional: IncludeCABundle additionally writes the PEM encoded CA bundle verifying kcp's serving
+	// certificates to a separate key.
+	//
+	// +optional
+	IncludeCABundle bool `json:"includeCABundle,omitempty"`
+	// Optional: CABundleKey is the key that the CA bundle is written to. Defaults to "ca.crt".
+	//
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	// +optional
+	CABundleKey string `json:"caBundleKey,omitempty"`
+
+	// Optional: CredentialsMountPath makes the kubeconfig reference the client certificate, private key and CA
+	// bundle as files below this path, where the Secret is expected to be mounted, instead of embedding them.
+	// Clients watching these files pick up renewed certificates without reloading the kubeconfig. Requires
+	// includeCertificate and includeCABundle.
+	//
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	CredentialsMountPath string `json:"credentialsMountPath,omitempty"`
+}
+
+// KubeconfigContext is an additional context in a generated kubeconfig.
+type KubeconfigContext struct {
+	// Name is the name of the context (and its cluster) in the kubeconfig.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self != 'default'",message="the context name \"default\" is reserved"
+	Name string `json:"name"`
+
+	// ClusterPath is the logical cluster path of the workspace (e.g. "root:org:team") this context points at.
+	//
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(:[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`
+	ClusterPath string `json:"clusterPath"`
+}
+
+// KubeconfigPermissions grants the identity of a generated kubeconfig permissions inside a kcp workspace.
+type KubeconfigPermissions struct {
+	// Optional: ClusterPath is the logical cluster path of the workspace that the ClusterRoleBindings are created
+	// in. Defaults to target.clusterPath, or to the root workspace if that is empty.
+	//
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(:[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`
+	// +optional
+	ClusterPath string `json:"clusterPath,omitempty"`
+
+	// ClusterRoles are the names of the ClusterRoles in the workspace that are bound to the kubeconfig's user and
+	// groups, one ClusterRoleBinding each.
+	//
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	ClusterRoles []string `json:"clusterRoles"`
 }
 
 // KubeconfigStatus defines the observed state of Kubeconfig
 type KubeconfigStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ServerURL is the server URL of the kubeconfig's default context.
+	//
+	// +optional
+	ServerURL string `json:"serverURL,omitempty"`
+
+	// Certificate describes the client certificate currently in the kubeconfig.
+	//
+	// +optional
+	Certificate *KubeconfigCertificateStatus `json:"certificate,omitempty"`
+
+	// LastRenewalTime is when the client certificate was last issued.
+	//
+	// +optional
+	LastRenewalTime *metav1.Time `json:"lastRenewalTime,omitempty"`
+
+	// BoundClusterPath is the logical cluster path of the workspace that ClusterRoleBindings for spec.permissions
+	// have been created in.
+	//
+	// +optional
+	BoundClusterPath string `json:"boundClusterPath,omitempty"`
+
+	// MirroredNamespaces lists the namespaces that the kubeconfig Secret has been copied into.
+	//
+	// +optional
+	MirroredNamespaces []string `json:"mirroredNamespaces,omitempty"`
+
+	// CASerialNumber is the serial number (in hex) of the CA currently signing the kubeconfig's client
+	// certificates.
+	//
+	// +optional
+	CASerialNumber string `json:"caSerialNumber,omitempty"`
+
+	// Revocations lists the most recent revocations of this Kubeconfig, oldest first.
+	//
+	// +optional
+	Revocations []KubeconfigRevocation `json:"revocations,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// KubeconfigCertificateStatus describes a client certificate issued for a Kubeconfig.
+type KubeconfigCertificateStatus struct {
+	// NotBefore is the start of the certificate's validity.
+	NotBefore metav1.Time `json:"notBefore"`
+	// NotAfter is when the certificate expires.
+	NotAfter metav1.Time `json:"notAfter"`
+	// RenewalTime is when the operator will replace the certificate with a new one.
+	RenewalTime metav1.Time `json:"renewalTime"`
+	// SerialNumber is the certificate's serial number (in hex).
+	SerialNumber string `json:"serialNumber"`
+	// SHA256Fingerprint is the SHA-256 hash (in hex) of the DER encoded certificate.
+	SHA256Fingerprint string `json:"sha256Fingerprint"`
+}
+
+// KubeconfigRevocation records the revocation of the CA of a Kubeconfig, and thereby of all client
+// certificates it signed.
+type KubeconfigRevocation struct {
+	// Time is when the CA was revoked.
+	Time metav1.Time `json:"time"`
+	// CASerialNumber is the serial number (in hex) of the revoked CA.
+	CASerialNumber string `json:"caSerialNumber"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Secret",type="string",JSONPath=".spec.secretRef.name"
+// +kubebuilder:printcolumn:name="Server",type="string",JSONPath=".status.serverURL",priority=1
+// +kubebuilder:printcolumn:name="Expires",type="date",JSONPath=".status.certificate.notAfter"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Kubeconfig is the Schema for the kubeconfigs API
 type Kubeconfig struct {
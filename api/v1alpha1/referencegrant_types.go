@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReferenceGrantSpec defines which objects in other namespaces may reference objects in the
+// namespace of the ReferenceGrant.
+type ReferenceGrantSpec struct {
+	// From lists the kinds and namespaces of objects that are allowed to reference objects in this namespace.
+	// +kubebuilder:validation:MinItems=1
+	From []ReferenceGrantFrom `json:"from"`
+	// To lists the objects in this namespace that may be referenced.
+	// +kubebuilder:validation:MinItems=1
+	To []ReferenceGrantTo `json:"to"`
+}
+
+type ReferenceGrantFrom struct {
+	// Kind is the kind of the referencing object.
+	// +kubebuilder:validation:Enum=Shard;FrontProxy;Kubeconfig
+	Kind string `json:"kind"`
+	// Namespace is the namespace of the referencing object.
+	Namespace string `json:"namespace"`
+}
+
+type ReferenceGrantTo struct {
+	// Kind is the kind of the referenced object.
+	// +kubebuilder:validation:Enum=RootShard;Secret
+	Kind string `json:"kind"`
+	// Optional: Name restricts the grant to a single object. If empty, all objects of the given kind
+	// in this namespace may be referenced.
+	Name string `json:"name,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReferenceGrant permits objects in other namespaces to reference objects (e.g. a RootShard) in the
+// namespace of the ReferenceGrant.
+type ReferenceGrant struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ReferenceGrantSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ReferenceGrantList contains a list of ReferenceGrant
+type ReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReferenceGrant `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReferenceGrant{}, &ReferenceGrantList{})
+}
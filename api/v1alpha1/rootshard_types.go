@@ -21,12 +21,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// BreakGlassAnnotation grants the Kubernetes users listed in its value (comma-separated) read access to the
+// admin kubeconfig Secret of a RootShard, via a Role and RoleBinding managed by the operator. Removing the
+// annotation revokes the access. It is meant for emergencies in which kcp cannot be reached otherwise.
+const BreakGlassAnnotation = "operator.kcp.io/break-glass"
+
 // RootShardSpec defines the desired state of RootShard.
 type RootShardSpec struct {
 	CommonShardSpec `json:",inline"`
 
 	// Hostname is the external name of the KCP instance. This should be matched by a DNS
-	// record pointing to the kcp-front-proxy Service's external IP address.
+	// record pointing to the kcp-front-proxy Service's external IP address, which is published
+	// in the FrontProxy's status.
 	Hostname string `json:"hostname"`
 
 	// Cache configures the cache server (with a Kubernetes-like API) used by a sharded kcp instance.
@@ -35,11 +41,26 @@ type RootShardSpec struct {
 	// CARef is an optional reference to a cert-manager Certificate resources
 	// which can be used as CA for the kcp instance.
 	CARef *corev1.LocalObjectReference `json:"caRef,omitempty"`
+
+	// Optional: Version is the kcp version (e.g. "v0.26.0") to run for this kcp setup. It is inherited by
+	// the referenced CacheServer and by all Shards and FrontProxies referencing this RootShard, unless they
+	// overwrite the image tag. Changing the version triggers an ordered upgrade of the cache server, the
+	// root shard, all secondary shards and finally all front-proxies. Defaults to the latest kcp release
+	// that the operator supports.
+	Version string `json:"version,omitempty"`
+
+	// Optional: UpgradeTimeout is the maximum time a single step of an upgrade may take until all
+	// components of that step are rolled out and ready. If exceeded, the upgrade is halted. Defaults to 15m.
+	UpgradeTimeout *metav1.Duration `json:"upgradeTimeout,omitempty"`
 }
 
 type CacheConfig struct {
 	// Embedded configures settings for starting the cache server embedded in the root shard.
 	Embedded *EmbeddedCacheConfiguration `json:"embedded,omitempty"`
+
+	// Reference references a CacheServer object in the same namespace. The cache server inherits the kcp
+	// version of this root shard and is upgraded before it.
+	Reference *corev1.LocalObjectReference `json:"ref,omitempty"`
 }
 
 type EmbeddedCacheConfiguration struct {
@@ -72,14 +93,73 @@ type OIDCConfiguration struct {
 	UsernamePrefix string `json:"usernamePrefix,omitempty"`
 }
 
+// UpgradePhase is a step of an upgrade. Components are upgraded in the order of the phases below.
+type UpgradePhase string
+
+const (
+	UpgradePhaseCacheServer  UpgradePhase = "CacheServer"
+	UpgradePhaseRootShard    UpgradePhase = "RootShard"
+	UpgradePhaseShards       UpgradePhase = "Shards"
+	UpgradePhaseFrontProxies UpgradePhase = "FrontProxies"
+	UpgradePhaseCompleted    UpgradePhase = "Completed"
+)
+
+// UpgradeStatus describes the progress of rolling out a kcp version.
+type UpgradeStatus struct {
+	// FromVersion is the kcp version that components which have not been upgraded yet are running.
+	FromVersion string `json:"fromVersion,omitempty"`
+	// TargetVersion is the kcp version that is being rolled out.
+	TargetVersion string `json:"targetVersion"`
+	// Phase is the current step of the upgrade. All components of earlier phases have been upgraded.
+	Phase UpgradePhase `json:"phase"`
+	// PhaseStartTime is the time the current phase was started.
+	PhaseStartTime *metav1.Time `json:"phaseStartTime,omitempty"`
+	// Failed is set if the current phase did not complete in time. A failed upgrade is not resumed
+	// automatically; changing the version restarts the rollout.
+	Failed bool `json:"failed,omitempty"`
+	// Message gives details about the current phase.
+	Message string `json:"message,omitempty"`
+}
+
 // RootShardStatus defines the observed state of RootShard
 type RootShardStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Replicas is the desired number of replicas for the root shard.
+	Replicas int32 `json:"replicas,omitempty"`
+	// ReadyReplicas is the number of root shard replicas that report ready via kcp's /readyz endpoint.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Version is the kcp version (i.e. image tag) that the root shard is configured to run.
+	Version string `json:"version,omitempty"`
+	// ResolvedImage is the image digest the root shard has been pinned to, if digest resolution is enabled.
+	ResolvedImage *ResolvedImage `json:"resolvedImage,omitempty"`
+
+	// URLs are the URLs under which this root shard is reachable. They match the URLs the shard has been
+	// configured with and can be consumed by other controllers.
+	URLs ShardURLs `json:"urls,omitempty"`
+	// ExternalPort is the port the kcp setup is reachable on from outside the cluster. It is derived from
+	// how the FrontProxies of this RootShard are exposed and used for the external URLs of all shards.
+	ExternalPort int32 `json:"externalPort,omitempty"`
+
+	// Inventory reports what the shard is hosting. It is collected periodically from kcp, which requires
+	// the RootShard to have a CA configured.
+	Inventory *ShardInventory `json:"inventory,omitempty"`
+
+	// Upgrade tracks the rollout of the kcp version across all components of this kcp setup.
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Hostname",type="string",JSONPath=".spec.hostname"
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version"
+// +kubebuilder:printcolumn:name="Upgrade",type="string",JSONPath=".status.upgrade.phase"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // RootShard is the Schema for the kcpinstances API
 type RootShard struct {
@@ -28,6 +28,15 @@ type ShardSpec struct {
 	CommonShardSpec `json:",inline"`
 
 	RootShard RootShardConfig `json:"rootShard"`
+
+	// Optional: DeletionProtection blocks the deletion of this Shard as long as logical clusters (i.e.
+	// workspaces) are still stored on it. The shard is cordoned in kcp regardless, so that no new
+	// workspaces are scheduled onto it.
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// Optional: Unschedulable cordons the shard in kcp, so that no new workspaces are scheduled onto it.
+	// Existing workspaces keep being served. Requires the RootShard to have a CA configured.
+	Unschedulable bool `json:"unschedulable,omitempty"`
 }
 
 type CommonShardSpec struct {
@@ -35,16 +44,74 @@ type CommonShardSpec struct {
 	Etcd EtcdConfig `json:"etcd"`
 
 	Image *ImageSpec `json:"image,omitempty"`
+
+	// Optional: Replicas configures the replica count for the shard Deployment. All replicas share
+	// the same etcd cluster and serve traffic; readiness is reported per replica, there is no leader
+	// election. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Optional: VirtualWorkspaces configures how virtual workspaces are served for this shard.
+	// Defaults to running them embedded in the kcp process.
+	VirtualWorkspaces *VirtualWorkspacesSpec `json:"virtualWorkspaces,omitempty"`
+}
+
+type VirtualWorkspacesMode string
+
+const (
+	// VirtualWorkspacesModeEmbedded runs the virtual workspaces in-process with kcp.
+	VirtualWorkspacesModeEmbedded VirtualWorkspacesMode = "embedded"
+	// VirtualWorkspacesModeExternal runs the virtual workspaces in a separate Deployment.
+	VirtualWorkspacesModeExternal VirtualWorkspacesMode = "external"
+)
+
+type VirtualWorkspacesSpec struct {
+	// Mode selects whether virtual workspaces are served by the shard itself ("embedded") or by a
+	// standalone virtual-workspaces server ("external"). External mode requires the RootShard to have
+	// a CA configured, since the server authenticates against the shard with a client certificate.
+	// +kubebuilder:validation:Enum=embedded;external
+	// +kubebuilder:default=embedded
+	Mode VirtualWorkspacesMode `json:"mode,omitempty"`
+	// Optional: Replicas configures the replica count for the virtual-workspaces Deployment. Only used in
+	// external mode. Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
 }
 
 // ShardStatus defines the observed state of Shard
 type ShardStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Replicas is the desired number of replicas for this shard.
+	Replicas int32 `json:"replicas,omitempty"`
+	// ReadyReplicas is the number of replicas for this shard that report ready via kcp's /readyz endpoint.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Version is the kcp version (i.e. image tag) that the shard is configured to run.
+	Version string `json:"version,omitempty"`
+	// Unschedulable reflects whether the shard is currently cordoned in kcp.
+	Unschedulable bool `json:"unschedulable,omitempty"`
+	// ResolvedImage is the image digest the shard has been pinned to, if digest resolution is enabled.
+	ResolvedImage *ResolvedImage `json:"resolvedImage,omitempty"`
+
+	// URLs are the URLs under which this shard is reachable. They match the URLs the shard has been
+	// configured with and can be consumed by other controllers.
+	URLs ShardURLs `json:"urls,omitempty"`
+
+	// Inventory reports what the shard is hosting. It is collected periodically from kcp, which requires
+	// the RootShard to have a CA configured.
+	Inventory *ShardInventory `json:"inventory,omitempty"`
+
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.version"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Unschedulable",type="boolean",JSONPath=".status.unschedulable",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Shard is the Schema for the shards API
 type Shard struct {
@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -34,6 +35,9 @@ import (
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
 	"github.com/kcp-dev/kcp-operator/internal/controller"
+	"github.com/kcp-dev/kcp-operator/internal/etcd"
+	"github.com/kcp-dev/kcp-operator/internal/registry"
+	webhookoperatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -54,6 +58,7 @@ func main() {
 		metricsAddr, probeAddr                           string
 		enableLeaderElection, secureMetrics, enableHTTP2 bool
 		tlsOpts                                          []func(*tls.Config)
+		insecureRegistries                               string
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
@@ -66,6 +71,8 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&insecureRegistries, "insecure-registries", "",
+		"Comma-separated list of registry hosts (e.g. a local registry) that are queried via plain HTTP when resolving image digests.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -141,30 +148,44 @@ func main() {
 		os.Exit(1)
 	}
 
+	var insecureRegistryHosts []string
+	if insecureRegistries != "" {
+		insecureRegistryHosts = strings.Split(insecureRegistries, ",")
+	}
+	imageResolver := registry.NewResolver(insecureRegistryHosts)
+	etcdHealthChecker := etcd.NewHealthChecker()
+
 	if err = (&controller.RootShardReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ImageResolver:     imageResolver,
+		EtcdHealthChecker: etcdHealthChecker,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "KCPInstance")
 		os.Exit(1)
 	}
 	if err = (&controller.FrontProxyReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		ImageResolver: imageResolver,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FrontProxy")
 		os.Exit(1)
 	}
 	if err = (&controller.ShardReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ImageResolver:     imageResolver,
+		EtcdHealthChecker: etcdHealthChecker,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Shard")
 		os.Exit(1)
 	}
 	if err = (&controller.CacheServerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		ImageResolver:     imageResolver,
+		EtcdHealthChecker: etcdHealthChecker,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CacheServer")
 		os.Exit(1)
@@ -176,6 +197,39 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Kubeconfig")
 		os.Exit(1)
 	}
+	if err = (&controller.EtcdBackupReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EtcdBackup")
+		os.Exit(1)
+	}
+	if err = (&controller.EtcdRestoreReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "EtcdRestore")
+		os.Exit(1)
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookoperatorkcpiov1alpha1.SetupRootShardWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "RootShard")
+			os.Exit(1)
+		}
+		if err = webhookoperatorkcpiov1alpha1.SetupShardWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Shard")
+			os.Exit(1)
+		}
+		if err = webhookoperatorkcpiov1alpha1.SetupFrontProxyWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "FrontProxy")
+			os.Exit(1)
+		}
+		if err = webhookoperatorkcpiov1alpha1.SetupCacheServerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CacheServer")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
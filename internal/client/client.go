@@ -0,0 +1,340 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client provides clients for talking to the kcp shards managed by the operator. The operator
+// authenticates with short-lived admin client certificates signed by the RootShard's CA, which every
+// shard of the setup trusts as client CA.
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/certificates"
+	"github.com/kcp-dev/kcp-operator/internal/resources/kubeconfig"
+)
+
+const (
+	// RootCluster is the logical cluster path of kcp's root workspace.
+	RootCluster = "root"
+	// WildcardCluster is the logical cluster path for cross-cluster list and watch requests against a
+	// single shard.
+	WildcardCluster = "*"
+
+	// clientCertificateValidity is the lifetime of the client certificates the operator issues for itself.
+	clientCertificateValidity = time.Hour
+	clientCommonName          = "system:kcp-operator"
+
+	// UnschedulableAnnotation marks a kcp Shard as cordoned, i.e. no new workspaces are scheduled onto it.
+	UnschedulableAnnotation = "experimental.core.kcp.io/unschedulable"
+)
+
+var (
+	// ErrNoCA is returned if a RootShard has no CA configured, in which case the operator cannot authenticate against kcp.
+	ErrNoCA = errors.New("RootShard has no CA configured")
+
+	// ShardGVK is kcp's Shard kind, living in the root workspace.
+	ShardGVK = schema.GroupVersionKind{Group: "core.kcp.io", Version: "v1alpha1", Kind: "Shard"}
+	// LogicalClusterGVK is kcp's LogicalCluster kind, of which there is one per workspace.
+	LogicalClusterGVK = schema.GroupVersionKind{Group: "core.kcp.io", Version: "v1alpha1", Kind: "LogicalCluster"}
+	// WorkspaceGVK is kcp's Workspace kind.
+	WorkspaceGVK = schema.GroupVersionKind{Group: "tenancy.kcp.io", Version: "v1alpha1", Kind: "Workspace"}
+)
+
+// restMapper knows the kcp kinds the operator works with. It is static because discovery is not available
+// for wildcard requests.
+var restMapper = func() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(ShardGVK, meta.RESTScopeRoot)
+	mapper.Add(LogicalClusterGVK, meta.RESTScopeRoot)
+	mapper.Add(WorkspaceGVK, meta.RESTScopeRoot)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRoleBinding"), meta.RESTScopeRoot)
+
+	return mapper
+}()
+
+// NewRootShardClient returns a client for the root workspace of the given RootShard.
+func NewRootShardClient(ctx context.Context, c ctrlruntimeclient.Client, rootShard *operatorkcpiov1alpha1.RootShard, scheme *runtime.Scheme) (ctrlruntimeclient.Client, error) {
+	return NewShardClient(ctx, c, rootShard, resources.GetRootShardURLs(rootShard).Internal, RootCluster, scheme)
+}
+
+// NewShardClient returns a client for the given logical cluster on the shard reachable at shardURL. The
+// shard must belong to the kcp setup formed around rootShard.
+func NewShardClient(ctx context.Context, c ctrlruntimeclient.Client, rootShard *operatorkcpiov1alpha1.RootShard, shardURL, cluster string, scheme *runtime.Scheme) (ctrlruntimeclient.Client, error) {
+	config, err := newRestConfig(ctx, c, rootShard)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Host, err = url.JoinPath(shardURL, "clusters", cluster)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shard URL %q: %w", shardURL, err)
+	}
+
+	return ctrlruntimeclient.New(config, ctrlruntimeclient.Options{Scheme: scheme, Mapper: restMapper})
+}
+
+// GetCASecretName returns the name of the Secret that cert-manager writes the root shard's CA to.
+func GetCASecretName(ctx context.Context, c ctrlruntimeclient.Client, rootShard *operatorkcpiov1alpha1.RootShard) (string, error) {
+	if rootShard.Spec.CARef == nil {
+		return "", ErrNoCA
+	}
+
+	cert := certificates.New(certificates.CertificateGVK, rootShard.Spec.CARef.Name, rootShard.Namespace)
+	if err := c.Get(ctx, ctrlruntimeclient.ObjectKeyFromObject(cert), cert); err != nil {
+		return "", fmt.Errorf("failed to get CA Certificate: %w", err)
+	}
+
+	secretName, _, err := unstructured.NestedString(cert.Object, "spec", "secretName")
+	if err != nil || secretName == "" {
+		return "", fmt.Errorf("CA Certificate %s has no spec.secretName", rootShard.Spec.CARef.Name)
+	}
+
+	return secretName, nil
+}
+
+// CA is the certificate authority of a RootShard. Every shard of the kcp setup trusts it as client CA.
+type CA struct {
+	// CertPEM is the PEM encoded CA certificate, suitable as CA bundle for clients.
+	CertPEM []byte
+
+	cert *x509.Certificate
+	key  any
+}
+
+// GetCA loads the CA of the given RootShard from the Secret cert-manager writes it to.
+func GetCA(ctx context.Context, c ctrlruntimeclient.Client, rootShard *operatorkcpiov1alpha1.RootShard) (*CA, error) {
+	secretName, err := GetCASecretName(ctx, c, rootShard)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: rootShard.Namespace, Name: secretName}, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get CA Secret: %w", err)
+	}
+
+	ca, err := NewCA(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA Secret %s: %w", secretName, err)
+	}
+
+	return ca, nil
+}
+
+// NewCA returns a CA for the given PEM encoded certificate and private key.
+func NewCA(certPEM, keyPEM []byte) (*CA, error) {
+	cert, key, err := parseKeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{CertPEM: certPEM, cert: cert, key: key}, nil
+}
+
+// GenerateCA generates a self-signed CA valid for validity and returns its PEM encoded certificate and
+// private key.
+func GenerateCA(commonName string, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(validity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encodeKeyPair(der, key)
+}
+
+// Certificate returns the parsed CA certificate.
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// newRestConfig returns a rest config (without host) authenticating as system:masters. It uses the RootShard's
+// admin kubeconfig, unless that is missing or not usable (e.g. before the RootShard has been reconciled or
+// after its CA was rotated), in which case a client certificate signed by the RootShard's CA is issued.
+func newRestConfig(ctx context.Context, c ctrlruntimeclient.Client, rootShard *operatorkcpiov1alpha1.RootShard) (*rest.Config, error) {
+	ca, err := GetCA(ctx, c, rootShard)
+	if err != nil {
+		return nil, err
+	}
+
+	if config := getAdminRestConfig(ctx, c, rootShard, ca, time.Now()); config != nil {
+		return config, nil
+	}
+
+	certPEM, keyPEM, err := ca.IssueClientCertificate(clientCommonName, []string{"system:masters"}, clientCertificateValidity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue client certificate: %w", err)
+	}
+
+	return &rest.Config{
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData:   ca.CertPEM,
+			CertData: certPEM,
+			KeyData:  keyPEM,
+		},
+		UserAgent: "kcp-operator",
+	}, nil
+}
+
+// getAdminRestConfig returns a rest config (without host) for the admin kubeconfig of the given RootShard, or
+// nil if it does not exist, cannot be parsed, is not signed by ca or has expired.
+func getAdminRestConfig(ctx context.Context, c ctrlruntimeclient.Client, rootShard *operatorkcpiov1alpha1.RootShard, ca *CA, now time.Time) *rest.Config {
+	var secret corev1.Secret
+	if err := c.Get(ctx, ctrlruntimeclient.ObjectKey{Namespace: rootShard.Namespace, Name: resources.GetRootShardAdminKubeconfigName(rootShard)}, &secret); err != nil {
+		return nil
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(secret.Data[kubeconfig.DefaultKubeconfigKey])
+	if err != nil {
+		return nil
+	}
+
+	cert, _, err := parseKeyPair(config.CertData, config.KeyData)
+	if err != nil || cert.CheckSignatureFrom(ca.cert) != nil || !now.Before(cert.NotAfter) {
+		return nil
+	}
+
+	return &rest.Config{
+		TLSClientConfig: config.TLSClientConfig,
+		UserAgent:       "kcp-operator",
+	}
+}
+
+func parseKeyPair(certPEM, keyPEM []byte) (*x509.Certificate, any, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, errors.New("no PEM encoded certificate found")
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, errors.New("no PEM encoded private key found")
+	}
+
+	var key any
+	switch keyBlock.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	return cert, key, nil
+}
+
+// IssueClientCertificate issues a client certificate for the given user and groups, valid for validity. It
+// returns the PEM encoded certificate and private key.
+func (ca *CA) IssueClientCertificate(commonName string, groups []string, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: groups,
+		},
+		// allow for some clock skew between the operator and the shards
+		NotBefore:   now.Add(-5 * time.Minute),
+		NotAfter:    now.Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encodeKeyPair(der, key)
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encodeKeyPair(certDER []byte, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/kubeconfig"
+)
+
+func TestIssueClientCertificate(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kcp-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	caKeyDER, err := x509.MarshalECPrivateKey(caKey)
+	if err != nil {
+		t.Fatalf("failed to marshal CA key: %v", err)
+	}
+
+	caCert, parsedKey, err := parseKeyPair(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: caKeyDER}),
+	)
+	if err != nil {
+		t.Fatalf("failed to parse CA key pair: %v", err)
+	}
+
+	ca := &CA{cert: caCert, key: parsedKey}
+	certPEM, _, err := ca.IssueClientCertificate(clientCommonName, []string{"system:masters"}, clientCertificateValidity)
+	if err != nil {
+		t.Fatalf("failed to issue client certificate: %v", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse client certificate: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("client certificate is not signed by the CA: %v", err)
+	}
+
+	if len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != "system:masters" {
+		t.Errorf("expected client certificate for system:masters, got %v", cert.Subject.Organization)
+	}
+}
+
+func TestGenerateCA(t *testing.T) {
+	certPEM, keyPEM, err := GenerateCA("kubeconfig-ca", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+
+	ca, err := NewCA(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to parse generated CA: %v", err)
+	}
+
+	if !ca.Certificate().IsCA {
+		t.Error("expected generated certificate to be a CA")
+	}
+
+	clientCertPEM, _, err := ca.IssueClientCertificate("alice", []string{"team"}, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to issue client certificate: %v", err)
+	}
+
+	block, _ := pem.Decode(clientCertPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse client certificate: %v", err)
+	}
+
+	if err := cert.CheckSignatureFrom(ca.Certificate()); err != nil {
+		t.Errorf("client certificate is not signed by the generated CA: %v", err)
+	}
+}
+
+func TestGetAdminRestConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	rootShard := &operatorkcpiov1alpha1.RootShard{ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "kcp"}}
+	now := time.Now()
+
+	newCA := func(t *testing.T) *CA {
+		certPEM, keyPEM, err := GenerateCA("kcp-ca", time.Hour)
+		if err != nil {
+			t.Fatalf("failed to generate CA: %v", err)
+		}
+		ca, err := NewCA(certPEM, keyPEM)
+		if err != nil {
+			t.Fatalf("failed to parse CA: %v", err)
+		}
+		return ca
+	}
+	ca := newCA(t)
+
+	testcases := []struct {
+		name     string
+		signer   *CA
+		now      time.Time
+		expected bool
+	}{
+		{
+			name: "no admin kubeconfig",
+			now:  now,
+		},
+		{
+			name:     "valid admin kubeconfig",
+			signer:   ca,
+			now:      now,
+			expected: true,
+		},
+		{
+			name:   "signed by another CA",
+			signer: newCA(t),
+			now:    now,
+		},
+		{
+			name:   "expired",
+			signer: ca,
+			now:    now.Add(2 * time.Hour),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+
+			if tc.signer != nil {
+				certPEM, keyPEM, err := tc.signer.IssueClientCertificate(kubeconfig.AdminUsername, []string{"system:masters"}, time.Hour)
+				if err != nil {
+					t.Fatalf("failed to issue client certificate: %v", err)
+				}
+
+				data, err := kubeconfig.NewSecretData(kubeconfig.NewRootShardAdmin(rootShard), kubeconfig.Layout{KubeconfigKey: kubeconfig.DefaultKubeconfigKey},
+					"https://root-kcp.kcp.svc.cluster.local:6443", kubeconfig.Credentials{CertPEM: certPEM, KeyPEM: keyPEM, CAPEM: ca.CertPEM})
+				if err != nil {
+					t.Fatalf("failed to render admin kubeconfig: %v", err)
+				}
+
+				builder = builder.WithObjects(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: resources.GetRootShardAdminKubeconfigName(rootShard), Namespace: rootShard.Namespace},
+					Data:       data,
+				})
+			}
+
+			config := getAdminRestConfig(context.Background(), builder.Build(), rootShard, ca, tc.now)
+			if (config != nil) != tc.expected {
+				t.Fatalf("expected admin kubeconfig to be used: %v", tc.expected)
+			}
+			if config != nil && (config.Host != "" || len(config.CertData) == 0 || string(config.CAData) != string(ca.CertPEM)) {
+				t.Errorf("unexpected rest config: %+v", config)
+			}
+		})
+	}
+}
@@ -18,45 +18,220 @@ package controller
 
 import (
 	"context"
+	"fmt"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/etcd"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/registry"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/cacheserver"
 )
 
 // CacheServerReconciler reconciles a CacheServer object
 type CacheServerReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// ImageResolver resolves image tags to digests if digest resolution is enabled. Defaults to
+	// querying the image registry.
+	ImageResolver registry.Resolver
+	// EtcdHealthChecker checks the configured etcd endpoints before rolling out. Defaults to querying
+	// etcd's health endpoint.
+	EtcdHealthChecker etcd.HealthChecker
 }
 
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=cacheservers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=cacheservers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=cacheservers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the CacheServer object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
 func (r *CacheServerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+	logger.V(4).Info("Reconciling CacheServer object")
 
-	// TODO(user): your logic here
+	var cacheServer operatorkcpiov1alpha1.CacheServer
+	if err := r.Get(ctx, req.NamespacedName, &cacheServer); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
 
-	return ctrl.Result{}, nil
+	if cacheServer.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	oldCacheServer := cacheServer.DeepCopy()
+
+	if isPaused(&cacheServer.Status.Conditions, &cacheServer) {
+		logger.V(4).Info("Reconciliation is paused, only updating status")
+
+		dep, err := getDeployment(ctx, r.Client, client.ObjectKey{Namespace: cacheServer.Namespace, Name: resources.GetCacheServerDeploymentName(&cacheServer)})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if dep != nil {
+			cacheServer.Status.ReadyReplicas = dep.Status.ReadyReplicas
+			setAvailableCondition(&cacheServer.Status.Conditions, cacheServer.Generation, dep)
+		}
+
+		if err := r.Status().Patch(ctx, &cacheServer, client.MergeFrom(oldCacheServer)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	rootShard, err := r.getRootShard(ctx, &cacheServer)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var version, rootShardVersion string
+	if rootShard != nil {
+		version = resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseCacheServer)
+		rootShardVersion = resources.GetRootShardVersion(rootShard)
+	}
+
+	cacheServer.Status.Version = resources.GetEffectiveVersion(cacheServer.Spec.Image, version)
+	if !checkVersionCompatibility(&cacheServer.Status.Conditions, cacheServer.Generation, reference.KindCacheServer, cacheServer.Status.Version, rootShardVersion) {
+		if err := r.Status().Patch(ctx, &cacheServer, client.MergeFrom(oldCacheServer)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	resolvedImage, err := resolveImageDigest(ctx, r.Client, r.ImageResolver, cacheServer.Namespace, cacheServer.Generation, &cacheServer.Status.Conditions, cacheServer.Spec.Image, version, cacheServer.Status.ResolvedImage)
+	cacheServer.Status.ResolvedImage = resolvedImage
+	if err != nil {
+		if patchErr := r.Status().Patch(ctx, &cacheServer, client.MergeFrom(oldCacheServer)); patchErr != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", patchErr)
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	etcdCertHash, etcdCertRecheck, err := reconcileEtcdCertificate(ctx, r.Client, r.Scheme, &cacheServer, &cacheServer.Status.Conditions, cacheServer.Spec.Etcd, resources.GetCacheServerResourceLabels(&cacheServer))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reachable, err := checkEtcdHealth(ctx, r.Client, r.EtcdHealthChecker, cacheServer.Namespace, cacheServer.Generation, &cacheServer.Status.Conditions, cacheServer.Spec.Etcd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !reachable {
+		if err := r.Status().Patch(ctx, &cacheServer, client.MergeFrom(oldCacheServer)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{RequeueAfter: etcdUnreachableRetryInterval}, nil
+	}
+
+	objMeta := metav1.ObjectMeta{Name: resources.GetCacheServerDeploymentName(&cacheServer), Namespace: cacheServer.Namespace}
+
+	dep := &appsv1.Deployment{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		cacheserver.MutateDeployment(dep, &cacheServer, version)
+		resources.SetEtcdCertificateHash(dep, etcdCertHash)
+		scaleDownForRestore(dep, &cacheServer)
+		return controllerutil.SetControllerReference(&cacheServer, dep, r.Scheme)
+	}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile Deployment: %w", err)
+	}
+
+	svc := &corev1.Service{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		cacheserver.MutateService(svc, &cacheServer)
+		return controllerutil.SetControllerReference(&cacheServer, svc, r.Scheme)
+	}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile Service: %w", err)
+	}
+
+	cacheServer.Status.Replicas = *dep.Spec.Replicas
+	cacheServer.Status.ReadyReplicas = dep.Status.ReadyReplicas
+	setAvailableCondition(&cacheServer.Status.Conditions, cacheServer.Generation, dep)
+
+	if err := r.Status().Patch(ctx, &cacheServer, client.MergeFrom(oldCacheServer)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	return requeueWithin(ctrl.Result{}, etcdCertRecheck), nil
+}
+
+// getRootShard returns the RootShard referencing the given cache server, if any. The cache server
+// inherits its kcp version from it.
+func (r *CacheServerReconciler) getRootShard(ctx context.Context, cacheServer *operatorkcpiov1alpha1.CacheServer) (*operatorkcpiov1alpha1.RootShard, error) {
+	var rootShards operatorkcpiov1alpha1.RootShardList
+	if err := r.List(ctx, &rootShards, client.InNamespace(cacheServer.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list RootShards: %w", err)
+	}
+
+	for _, rs := range rootShards.Items {
+		if ref := rs.Spec.Cache.Reference; ref != nil && ref.Name == cacheServer.Name {
+			return &rs, nil
+		}
+	}
+
+	return nil, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *CacheServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorkcpiov1alpha1.CacheServer{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Watches(&operatorkcpiov1alpha1.RootShard{}, handler.EnqueueRequestsFromMapFunc(r.cacheServerForRootShard)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.cacheServersForSecret)).
 		Complete(r)
 }
+
+// cacheServersForSecret enqueues all CacheServers using the given Secret as etcd client certificate.
+func (r *CacheServerReconciler) cacheServersForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	var cacheServers operatorkcpiov1alpha1.CacheServerList
+	if err := r.List(ctx, &cacheServers, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list CacheServers")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, cacheServer := range cacheServers.Items {
+		if cacheServer.Spec.Etcd.ClientCert.SecretRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cacheServer)})
+		}
+	}
+
+	return requests
+}
+
+// cacheServerForRootShard enqueues the CacheServer referenced by the given RootShard, so that
+// version changes are propagated.
+func (r *CacheServerReconciler) cacheServerForRootShard(_ context.Context, obj client.Object) []reconcile.Request {
+	rootShard, ok := obj.(*operatorkcpiov1alpha1.RootShard)
+	if !ok || rootShard.Spec.Cache.Reference == nil {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{
+		Namespace: rootShard.Namespace,
+		Name:      rootShard.Spec.Cache.Reference.Name,
+	}}}
+}
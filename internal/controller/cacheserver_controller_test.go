@@ -22,7 +22,10 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -82,8 +85,84 @@ var _ = Describe("CacheServer Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Checking the cache server Deployment")
+			dep := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-cache-server", Namespace: "default"}, dep)).To(Succeed())
+			Expect(dep.Spec.Template.Spec.Containers[0].Image).To(Equal("ghcr.io/kcp-dev/kcp:v0.26.0"))
+		})
+
+		It("should report a missing etcd client certificate", func() {
+			certName := types.NamespacedName{Name: "missing-etcd-cert", Namespace: "default"}
+
+			By("creating a CacheServer referencing a missing Secret")
+			cs := &operatorkcpiov1alpha1.CacheServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      certName.Name,
+					Namespace: certName.Namespace,
+				},
+				Spec: operatorkcpiov1alpha1.CacheServerSpec{
+					Etcd: operatorkcpiov1alpha1.EtcdConfig{
+						Endpoints: []string{"https://localhost:2379"},
+						ClientCert: operatorkcpiov1alpha1.EtcdCertificate{
+							SecretRef: corev1.LocalObjectReference{Name: "missing-etcd-client-cert"},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, cs)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, cs)
+
+			controllerReconciler := &CacheServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: certName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the EtcdCertificateValid condition")
+			Expect(k8sClient.Get(ctx, certName, cs)).To(Succeed())
+			cond := meta.FindStatusCondition(cs.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonSecretNotFound)))
+		})
+
+		It("should not create any objects while paused", func() {
+			pausedName := types.NamespacedName{Name: "paused-resource", Namespace: "default"}
+
+			By("creating a paused CacheServer")
+			paused := &operatorkcpiov1alpha1.CacheServer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        pausedName.Name,
+					Namespace:   pausedName.Namespace,
+					Annotations: map[string]string{operatorkcpiov1alpha1.PausedAnnotation: "true"},
+				},
+				Spec: operatorkcpiov1alpha1.CacheServerSpec{
+					Etcd: operatorkcpiov1alpha1.EtcdConfig{
+						Endpoints: []string{"https://localhost:2379"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, paused)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, paused)
+
+			controllerReconciler := &CacheServerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: pausedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking that no Deployment has been created")
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: pausedName.Name + "-cache-server", Namespace: "default"}, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("Checking the Paused condition")
+			Expect(k8sClient.Get(ctx, pausedName, paused)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(paused.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypePaused))).To(BeTrue())
 		})
 	})
 })
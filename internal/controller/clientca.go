@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp-operator/internal/client"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/kubeconfig"
+)
+
+// reconcileClientCABundle writes the Secret holding the client CAs trusted by the Deployment with the given
+// name: the root shard's CA, which signs the operator's own client certificates, and the CAs of all kubeconfigs
+// generated for the kcp setup (see getKubeconfigCAs). Revoking a kubeconfig deletes its CA, which removes it
+// from the bundle.
+func reconcileClientCABundle(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, rootShard *operatorkcpiov1alpha1.RootShard, deploymentName string, labels map[string]string) error {
+	if rootShard.Spec.CARef == nil {
+		return nil
+	}
+
+	ca, err := kcpclient.GetCA(ctx, c, rootShard)
+	if err != nil {
+		return err
+	}
+
+	kubeconfigCAs, err := getKubeconfigCAs(ctx, c, rootShard)
+	if err != nil {
+		return err
+	}
+
+	bundle := appendPEM(nil, ca.CertPEM)
+	for _, kubeconfigCA := range kubeconfigCAs {
+		bundle = appendPEM(bundle, kubeconfigCA)
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: resources.GetClientCABundleName(deploymentName), Namespace: owner.GetNamespace()}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		secret.Labels = labels
+		secret.Data = map[string][]byte{resources.ClientCABundleKey: bundle}
+		return controllerutil.SetControllerReference(owner, secret, scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile client CA bundle: %w", err)
+	}
+
+	return nil
+}
+
+// getKubeconfigCAs returns the PEM encoded CAs of all Kubeconfigs generated for the given RootShard, sorted by
+// the namespace and name of their Secrets. As anyone able to create Secrets can set the client CA label and
+// annotation, they only preselect candidates: a Secret is only trusted if it is the CA Secret of an existing
+// Kubeconfig controlling it, and that Kubeconfig's target resolves to rootShard, within its namespace or as
+// permitted by a ReferenceGrant.
+func getKubeconfigCAs(ctx context.Context, c client.Client, rootShard *operatorkcpiov1alpha1.RootShard) ([][]byte, error) {
+	var secrets corev1.SecretList
+	if err := c.List(ctx, &secrets, client.MatchingLabels{resources.ClientCALabel: "true"}); err != nil {
+		return nil, fmt.Errorf("failed to list client CA Secrets: %w", err)
+	}
+
+	// keep the bundle stable, so that kcp only reloads it when the set of CAs changes
+	slices.SortFunc(secrets.Items, func(a, b corev1.Secret) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+
+	var cas [][]byte
+	for _, secret := range secrets.Items {
+		key, ok := resources.GetClientCARootShard(&secret)
+		if !ok || key != client.ObjectKeyFromObject(rootShard) || secret.DeletionTimestamp != nil {
+			continue
+		}
+
+		trusted, err := isKubeconfigCA(ctx, c, &secret, rootShard)
+		if err != nil {
+			return nil, err
+		}
+		if trusted {
+			cas = append(cas, secret.Data[corev1.TLSCertKey])
+		}
+	}
+
+	return cas, nil
+}
+
+// isKubeconfigCA returns true if the given Secret is the CA Secret of an existing Kubeconfig targeting rootShard.
+func isKubeconfigCA(ctx context.Context, c client.Client, secret *corev1.Secret, rootShard *operatorkcpiov1alpha1.RootShard) (bool, error) {
+	owner := metav1.GetControllerOf(secret)
+	if owner == nil || owner.Kind != "Kubeconfig" || owner.APIVersion != operatorkcpiov1alpha1.GroupVersion.String() {
+		return false, nil
+	}
+
+	var kc operatorkcpiov1alpha1.Kubeconfig
+	if err := c.Get(ctx, client.ObjectKey{Namespace: secret.Namespace, Name: owner.Name}, &kc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get Kubeconfig: %w", err)
+	}
+
+	if kc.UID != owner.UID || kc.DeletionTimestamp != nil || secret.Name != kubeconfig.GetCASecretName(&kc) {
+		return false, nil
+	}
+
+	target, _, _, err := resolveKubeconfigTarget(ctx, c, &kc)
+	if err != nil {
+		var kcErr *kubeconfigError
+		if errors.As(err, &kcErr) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return client.ObjectKeyFromObject(target) == client.ObjectKeyFromObject(rootShard), nil
+}
+
+// appendPEM appends PEM data to a bundle, making sure blocks are separated by a newline.
+func appendPEM(bundle, data []byte) []byte {
+	if len(data) == 0 {
+		return bundle
+	}
+
+	bundle = append(bundle, data...)
+	if data[len(data)-1] != '\n' {
+		bundle = append(bundle, '\n')
+	}
+
+	return bundle
+}
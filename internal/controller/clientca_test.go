@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/kubeconfig"
+)
+
+var _ = Describe("Client CA bundle", func() {
+	ctx := context.Background()
+
+	It("should only trust CAs of Kubeconfigs targeting the RootShard", func() {
+		rootShard := &operatorkcpiov1alpha1.RootShard{
+			ObjectMeta: metav1.ObjectMeta{Name: "client-ca-root", Namespace: "default"},
+			Spec: operatorkcpiov1alpha1.RootShardSpec{
+				Hostname: "example.kcp.io",
+				CommonShardSpec: operatorkcpiov1alpha1.CommonShardSpec{
+					Etcd: operatorkcpiov1alpha1.EtcdConfig{Endpoints: []string{"https://localhost:2379"}},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, rootShard)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, rootShard)
+
+		kc := &operatorkcpiov1alpha1.Kubeconfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "client-ca", Namespace: "default"},
+			Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+				Target:    operatorkcpiov1alpha1.KubeconfigTarget{RootShardRef: &corev1.LocalObjectReference{Name: rootShard.Name}},
+				Username:  "alice",
+				Validity:  metav1.Duration{Duration: 24 * time.Hour},
+				SecretRef: corev1.LocalObjectReference{Name: "client-ca-kubeconfig"},
+			},
+		}
+		Expect(k8sClient.Create(ctx, kc)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, kc)
+
+		newCASecret := func(name string) *corev1.Secret {
+			return &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "default",
+					Labels:      map[string]string{resources.ClientCALabel: "true"},
+					Annotations: map[string]string{resources.ClientCARootShardAnnotation: "default/" + rootShard.Name},
+				},
+				Data: map[string][]byte{corev1.TLSCertKey: []byte(name)},
+			}
+		}
+
+		By("creating the CA Secret of the Kubeconfig")
+		caSecret := newCASecret(kubeconfig.GetCASecretName(kc))
+		Expect(controllerutil.SetControllerReference(kc, caSecret, k8sClient.Scheme())).To(Succeed())
+		Expect(k8sClient.Create(ctx, caSecret)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, caSecret)
+
+		By("creating a hand-made labelled Secret")
+		forged := newCASecret("forged-ca")
+		Expect(k8sClient.Create(ctx, forged)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, forged)
+
+		By("creating a Secret claiming to be controlled by the Kubeconfig under another name")
+		renamed := newCASecret("renamed-ca")
+		Expect(controllerutil.SetControllerReference(kc, renamed, k8sClient.Scheme())).To(Succeed())
+		Expect(k8sClient.Create(ctx, renamed)).To(Succeed())
+		DeferCleanup(k8sClient.Delete, ctx, renamed)
+
+		cas, err := getKubeconfigCAs(ctx, k8sClient, rootShard)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cas).To(Equal([][]byte{[]byte(caSecret.Name)}))
+	})
+})
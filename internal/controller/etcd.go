@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/etcd"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/certificates"
+)
+
+const (
+	// etcdCertificateExpiryWarning is the remaining validity below which an etcd client certificate is
+	// reported as expiring soon.
+	etcdCertificateExpiryWarning = 7 * 24 * time.Hour
+
+	// etcdUnreachableRetryInterval is the interval at which the etcd health check is retried while no
+	// etcd endpoint is reachable.
+	etcdUnreachableRetryInterval = 30 * time.Second
+
+	// etcdHealthCheckTimeout bounds the health check of all etcd endpoints of a component.
+	etcdHealthCheckTimeout = 10 * time.Second
+)
+
+// reconcileEtcdCertificate has the etcd client certificate issued by cert-manager if an issuer is
+// configured, validates the certificate Secret and updates the EtcdCertificateValid condition. It returns
+// the hash of the Secret's certificate data, which is empty if the Secret is missing or invalid, and the
+// duration after which the condition needs to be updated because the certificate expires.
+func reconcileEtcdCertificate(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, conditions *[]metav1.Condition, config operatorkcpiov1alpha1.EtcdConfig, labels map[string]string) (string, time.Duration, error) {
+	secretName := config.ClientCert.SecretRef.Name
+	if secretName == "" {
+		meta.RemoveStatusCondition(conditions, string(operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid))
+		return "", 0, nil
+	}
+
+	if ref := config.ClientCert.IssuerRef; ref != nil {
+		cert := certificates.New(certificates.CertificateGVK, secretName, owner.GetNamespace())
+		if err := reconcileUnstructured(ctx, c, scheme, owner, cert, func() error {
+			return certificates.MutateCertificate(cert, labels, certificates.Options{
+				SecretName:  secretName,
+				IssuerName:  ref.Name,
+				IssuerKind:  ref.Kind,
+				IssuerGroup: ref.Group,
+				CommonName:  owner.GetName(),
+				Usages:      []string{"client auth"},
+			})
+		}); err != nil {
+			return "", 0, fmt.Errorf("failed to reconcile etcd client Certificate: %w", err)
+		}
+	}
+
+	generation := owner.GetGeneration()
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: owner.GetNamespace(), Name: secretName}, &secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return "", 0, fmt.Errorf("failed to get etcd client certificate Secret: %w", err)
+		}
+
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonSecretNotFound, fmt.Sprintf("Secret %s not found", secretName))
+		return "", 0, nil
+	}
+
+	cert, err := resources.ParseEtcdCertificateSecret(&secret)
+	if err != nil {
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonCertificateInvalid, err.Error())
+		return "", 0, nil
+	}
+
+	remaining := time.Until(cert.NotAfter)
+	expiry := cert.NotAfter.UTC().Format(time.RFC3339)
+
+	switch {
+	case remaining <= 0:
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonCertificateExpired, fmt.Sprintf("certificate in Secret %s expired at %s", secretName, expiry))
+		return cert.Hash, 0, nil
+
+	case remaining <= etcdCertificateExpiryWarning:
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonCertificateExpiry, fmt.Sprintf("certificate in Secret %s expires at %s", secretName, expiry))
+		return cert.Hash, remaining, nil
+
+	default:
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonCertificateValid, fmt.Sprintf("certificate in Secret %s is valid until %s", secretName, expiry))
+		return cert.Hash, remaining - etcdCertificateExpiryWarning, nil
+	}
+}
+
+// checkEtcdHealth runs a health check against all configured etcd endpoints using the client certificate
+// and updates the EtcdReachable condition. It returns false if none of the endpoints is healthy, in which
+// case the component must not be rolled out, as kcp would not be able to start. The check is skipped if
+// the client certificate is not valid, as this is already reported by the EtcdCertificateValid condition.
+func checkEtcdHealth(ctx context.Context, c client.Reader, checker etcd.HealthChecker, namespace string, generation int64, conditions *[]metav1.Condition, config operatorkcpiov1alpha1.EtcdConfig) (bool, error) {
+	if !meta.IsStatusConditionTrue(*conditions, string(operatorkcpiov1alpha1.ConditionTypeEtcdCertificateValid)) {
+		meta.RemoveStatusCondition(conditions, string(operatorkcpiov1alpha1.ConditionTypeEtcdReachable))
+		return true, nil
+	}
+
+	var secret corev1.Secret
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: config.ClientCert.SecretRef.Name}, &secret); err != nil {
+		return false, fmt.Errorf("failed to get etcd client certificate Secret: %w", err)
+	}
+
+	tlsConfig, err := etcd.TLSConfigFromSecret(&secret)
+	if err != nil {
+		return false, err
+	}
+
+	if checker == nil {
+		checker = etcd.NewHealthChecker()
+	}
+
+	// endpoints are checked in parallel and bounded as a whole, so that unreachable endpoints do not add up
+	// and block reconciliation
+	ctx, cancel := context.WithTimeout(ctx, etcdHealthCheckTimeout)
+	defer cancel()
+
+	errs := make([]error, len(config.Endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range config.Endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = checker.CheckHealth(ctx, endpoint, tlsConfig)
+		}()
+	}
+	wg.Wait()
+
+	var failures []string
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", config.Endpoints[i], err))
+		}
+	}
+
+	switch {
+	case len(failures) == 0:
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdReachable, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonEndpointsHealthy, fmt.Sprintf("all %d etcd endpoints are healthy", len(config.Endpoints)))
+		return true, nil
+
+	case len(failures) < len(config.Endpoints):
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdReachable, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonEndpointsUnhealthy, strings.Join(failures, "; "))
+		return true, nil
+
+	default:
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeEtcdReachable, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonEtcdUnreachable, strings.Join(failures, "; "))
+		return false, nil
+	}
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/etcdbackup"
+)
+
+// EtcdBackupReconciler reconciles an EtcdBackup object
+type EtcdBackupReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=etcdbackups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=etcdbackups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=etcdbackups/finalizers,verbs=update
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=rootshards;shards;cacheservers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
+func (r *EtcdBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(4).Info("Reconciling EtcdBackup object")
+
+	var backup operatorkcpiov1alpha1.EtcdBackup
+	if err := r.Get(ctx, req.NamespacedName, &backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if backup.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	oldBackup := backup.DeepCopy()
+
+	if !isPaused(&backup.Status.Conditions, &backup) {
+		etcd, err := r.getTargetEtcd(ctx, &backup)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+
+			setCondition(&backup.Status.Conditions, backup.Generation, operatorkcpiov1alpha1.ConditionTypeScheduled, metav1.ConditionFalse,
+				operatorkcpiov1alpha1.ConditionReasonTargetNotFound, fmt.Sprintf("%s %s not found", backup.Spec.Target.Kind, backup.Spec.Target.Name))
+			if err := r.Status().Patch(ctx, &backup, client.MergeFrom(oldBackup)); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+			}
+
+			return ctrl.Result{}, nil
+		}
+
+		cj := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: resources.GetEtcdBackupCronJobName(&backup), Namespace: backup.Namespace}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cj, func() error {
+			etcdbackup.MutateCronJob(cj, &backup, *etcd)
+			return controllerutil.SetControllerReference(&backup, cj, r.Scheme)
+		}); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile CronJob: %w", err)
+		}
+
+		if backup.Spec.Suspend {
+			setCondition(&backup.Status.Conditions, backup.Generation, operatorkcpiov1alpha1.ConditionTypeScheduled, metav1.ConditionFalse,
+				operatorkcpiov1alpha1.ConditionReasonBackupSuspended, "snapshots are suspended")
+		} else {
+			setCondition(&backup.Status.Conditions, backup.Generation, operatorkcpiov1alpha1.ConditionTypeScheduled, metav1.ConditionTrue,
+				operatorkcpiov1alpha1.ConditionReasonBackupScheduled, fmt.Sprintf("snapshots are taken on schedule %q", backup.Spec.Schedule))
+		}
+	}
+
+	if err := r.updateRunStatus(ctx, &backup); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Status().Patch(ctx, &backup, client.MergeFrom(oldBackup)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getTargetEtcd returns the etcd configuration of the object the given EtcdBackup targets.
+func (r *EtcdBackupReconciler) getTargetEtcd(ctx context.Context, backup *operatorkcpiov1alpha1.EtcdBackup) (*operatorkcpiov1alpha1.EtcdConfig, error) {
+	key := client.ObjectKey{Namespace: backup.Namespace, Name: backup.Spec.Target.Name}
+
+	switch backup.Spec.Target.Kind {
+	case operatorkcpiov1alpha1.EtcdBackupTargetRootShard:
+		var rootShard operatorkcpiov1alpha1.RootShard
+		if err := r.Get(ctx, key, &rootShard); err != nil {
+			return nil, err
+		}
+		return &rootShard.Spec.Etcd, nil
+
+	case operatorkcpiov1alpha1.EtcdBackupTargetShard:
+		var shard operatorkcpiov1alpha1.Shard
+		if err := r.Get(ctx, key, &shard); err != nil {
+			return nil, err
+		}
+		return &shard.Spec.Etcd, nil
+
+	case operatorkcpiov1alpha1.EtcdBackupTargetCacheServer:
+		var cacheServer operatorkcpiov1alpha1.CacheServer
+		if err := r.Get(ctx, key, &cacheServer); err != nil {
+			return nil, err
+		}
+		return &cacheServer.Spec.Etcd, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported target kind %q", backup.Spec.Target.Kind)
+	}
+}
+
+// updateRunStatus publishes the schedule times of the CronJob and the outcome of the most recently
+// finished Job.
+func (r *EtcdBackupReconciler) updateRunStatus(ctx context.Context, backup *operatorkcpiov1alpha1.EtcdBackup) error {
+	var cj batchv1.CronJob
+	if err := r.Get(ctx, client.ObjectKey{Namespace: backup.Namespace, Name: resources.GetEtcdBackupCronJobName(backup)}, &cj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	backup.Status.LastScheduleTime = cj.Status.LastScheduleTime
+	backup.Status.LastSuccessfulTime = cj.Status.LastSuccessfulTime
+
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(backup.Namespace), client.MatchingLabels(resources.GetEtcdBackupResourceLabels(backup))); err != nil {
+		return fmt.Errorf("failed to list Jobs: %w", err)
+	}
+
+	var lastRun *operatorkcpiov1alpha1.EtcdBackupRun
+	for _, job := range jobs.Items {
+		run := getBackupRun(&job)
+		if run == nil || run.CompletionTime == nil {
+			continue
+		}
+
+		if lastRun == nil || lastRun.CompletionTime.Before(run.CompletionTime) {
+			lastRun = run
+		}
+	}
+
+	if lastRun != nil {
+		backup.Status.LastRun = lastRun
+	}
+
+	return nil
+}
+
+// getBackupRun returns the run described by the given Job, or nil if the Job has not finished yet.
+func getBackupRun(job *batchv1.Job) *operatorkcpiov1alpha1.EtcdBackupRun {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return &operatorkcpiov1alpha1.EtcdBackupRun{
+				JobName:        job.Name,
+				StartTime:      job.Status.StartTime,
+				CompletionTime: job.Status.CompletionTime,
+				Succeeded:      true,
+			}
+
+		case batchv1.JobFailed:
+			return &operatorkcpiov1alpha1.EtcdBackupRun{
+				JobName:        job.Name,
+				StartTime:      job.Status.StartTime,
+				CompletionTime: &cond.LastTransitionTime,
+				Message:        cond.Message,
+			}
+		}
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EtcdBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&operatorkcpiov1alpha1.EtcdBackup{}).
+		Owns(&batchv1.CronJob{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(r.backupForJob)).
+		Watches(&operatorkcpiov1alpha1.RootShard{}, handler.EnqueueRequestsFromMapFunc(r.backupsForTarget(operatorkcpiov1alpha1.EtcdBackupTargetRootShard))).
+		Watches(&operatorkcpiov1alpha1.Shard{}, handler.EnqueueRequestsFromMapFunc(r.backupsForTarget(operatorkcpiov1alpha1.EtcdBackupTargetShard))).
+		Watches(&operatorkcpiov1alpha1.CacheServer{}, handler.EnqueueRequestsFromMapFunc(r.backupsForTarget(operatorkcpiov1alpha1.EtcdBackupTargetCacheServer))).
+		Complete(r)
+}
+
+// backupForJob enqueues the EtcdBackup a snapshot Job belongs to. Jobs are owned by the CronJob, not by
+// the EtcdBackup itself, so they are matched by their labels.
+func (r *EtcdBackupReconciler) backupForJob(_ context.Context, obj client.Object) []reconcile.Request {
+	name, ok := resources.GetEtcdBackupNameFromLabels(obj.GetLabels())
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+// backupsForTarget returns a map function enqueueing all EtcdBackups targeting the given object, so that
+// changes to its etcd configuration are propagated.
+func (r *EtcdBackupReconciler) backupsForTarget(kind operatorkcpiov1alpha1.EtcdBackupTargetKind) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var backups operatorkcpiov1alpha1.EtcdBackupList
+		if err := r.List(ctx, &backups, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list EtcdBackups")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, backup := range backups.Items {
+			if backup.Spec.Target.Kind == kind && backup.Spec.Target.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&backup)})
+			}
+		}
+
+		return requests
+	}
+}
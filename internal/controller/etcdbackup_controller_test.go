@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+var _ = Describe("EtcdBackup Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		cacheServer := &operatorkcpiov1alpha1.CacheServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "etcdbackup-test-cache",
+				Namespace: "default",
+			},
+			Spec: operatorkcpiov1alpha1.CacheServerSpec{
+				Etcd: operatorkcpiov1alpha1.EtcdConfig{
+					Endpoints: []string{"https://etcd-0:2379", "https://etcd-1:2379"},
+					ClientCert: operatorkcpiov1alpha1.EtcdCertificate{
+						SecretRef: corev1.LocalObjectReference{Name: "etcd-client-cert"},
+					},
+				},
+			},
+		}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind EtcdBackup")
+			err := k8sClient.Get(ctx, typeNamespacedName, &operatorkcpiov1alpha1.EtcdBackup{})
+			if err != nil && errors.IsNotFound(err) {
+				resource := &operatorkcpiov1alpha1.EtcdBackup{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: operatorkcpiov1alpha1.EtcdBackupSpec{
+						Target: operatorkcpiov1alpha1.EtcdBackupTarget{
+							Kind: operatorkcpiov1alpha1.EtcdBackupTargetCacheServer,
+							Name: cacheServer.Name,
+						},
+						Schedule: "0 */6 * * *",
+						Storage: operatorkcpiov1alpha1.EtcdBackupStorage{
+							PVC: &operatorkcpiov1alpha1.PVCBackupStorage{ClaimName: "backups"},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &operatorkcpiov1alpha1.EtcdBackup{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance EtcdBackup")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should report a missing target", func() {
+			controllerReconciler := &EtcdBackupReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			backup := &operatorkcpiov1alpha1.EtcdBackup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, backup)).To(Succeed())
+			cond := meta.FindStatusCondition(backup.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeScheduled))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonTargetNotFound)))
+		})
+
+		It("should successfully reconcile the resource", func() {
+			By("creating the target CacheServer")
+			Expect(k8sClient.Create(ctx, cacheServer.DeepCopy())).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, cacheServer.DeepCopy())
+
+			By("Reconciling the created resource")
+			controllerReconciler := &EtcdBackupReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the CronJob")
+			cj := &batchv1.CronJob{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: resourceName + "-etcd-backup", Namespace: "default"}, cj)).To(Succeed())
+			Expect(cj.Spec.Schedule).To(Equal("0 */6 * * *"))
+
+			podSpec := cj.Spec.JobTemplate.Spec.Template.Spec
+			Expect(podSpec.InitContainers[0].Args).To(ContainElement("--endpoints=https://etcd-0:2379"))
+			Expect(podSpec.Volumes).To(ContainElement(HaveField("Secret.SecretName", "etcd-client-cert")))
+			Expect(podSpec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "backups")))
+
+			By("Checking the EtcdBackup status")
+			backup := &operatorkcpiov1alpha1.EtcdBackup{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, backup)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(backup.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeScheduled))).To(BeTrue())
+		})
+	})
+})
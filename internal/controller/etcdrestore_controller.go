@@ -0,0 +1,415 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/etcdrestore"
+)
+
+const (
+	// etcdRestoreFinalizer makes sure the target is released when an EtcdRestore is deleted.
+	etcdRestoreFinalizer = "operator.kcp.io/etcd-restore"
+
+	// etcdRestorePollInterval is the interval at which the target's Deployment is checked while it is
+	// being scaled down or up. The Deployment is not owned by the EtcdRestore and hence not watched.
+	etcdRestorePollInterval = 5 * time.Second
+
+	// etcdRestoreScaleDownTimeout is how long a restore waits for the replicas of its target to terminate.
+	etcdRestoreScaleDownTimeout = 10 * time.Minute
+)
+
+// EtcdRestoreReconciler reconciles an EtcdRestore object
+type EtcdRestoreReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// restoreTarget is the object whose etcd is restored.
+type restoreTarget struct {
+	obj            client.Object
+	etcd           operatorkcpiov1alpha1.EtcdConfig
+	deploymentName string
+}
+
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=etcdrestores,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=etcdrestores/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=etcdrestores/finalizers,verbs=update
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=etcdbackups,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=rootshards;shards;cacheservers,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
+func (r *EtcdRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(4).Info("Reconciling EtcdRestore object")
+
+	var restore operatorkcpiov1alpha1.EtcdRestore
+	if err := r.Get(ctx, req.NamespacedName, &restore); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if restore.DeletionTimestamp != nil {
+		return ctrl.Result{}, r.reconcileDeletion(ctx, &restore)
+	}
+
+	oldRestore := restore.DeepCopy()
+
+	if isPaused(&restore.Status.Conditions, &restore) {
+		logger.V(4).Info("Reconciliation is paused, only updating status")
+
+		if err := r.Status().Patch(ctx, &restore, client.MergeFrom(oldRestore)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(&restore, etcdRestoreFinalizer) {
+		controllerutil.AddFinalizer(&restore, etcdRestoreFinalizer)
+		if err := r.Update(ctx, &restore); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add finalizer: %w", err)
+		}
+	}
+
+	var (
+		result ctrl.Result
+		err    error
+	)
+
+	switch restore.Status.Phase {
+	case "", operatorkcpiov1alpha1.EtcdRestorePhaseScalingDown:
+		result, err = r.reconcileScaleDown(ctx, &restore)
+	case operatorkcpiov1alpha1.EtcdRestorePhaseRestoring:
+		result, err = r.reconcileRestoring(ctx, &restore)
+	case operatorkcpiov1alpha1.EtcdRestorePhaseScalingUp:
+		result, err = r.reconcileScaleUp(ctx, &restore)
+	default:
+		// completed and failed restores are never picked up again
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.Status().Patch(ctx, &restore, client.MergeFrom(oldRestore)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	return result, err
+}
+
+// reconcileScaleDown claims the target via the RestoreAnnotation, which keeps its reconciler from scaling it
+// up again, scales its Deployment down and starts the restore Job once no replicas are left. The Deployment is
+// scaled down here rather than by the target's reconciler, as that does not touch it while the target is paused
+// or its etcd is unreachable, which is when a restore is needed most.
+func (r *EtcdRestoreReconciler) reconcileScaleDown(ctx context.Context, restore *operatorkcpiov1alpha1.EtcdRestore) (ctrl.Result, error) {
+	var backup operatorkcpiov1alpha1.EtcdBackup
+	if err := r.Get(ctx, client.ObjectKey{Namespace: restore.Namespace, Name: restore.Spec.BackupName}, &backup); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("failed to get EtcdBackup: %w", err)
+		}
+
+		setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledDown, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonBackupNotFound, fmt.Sprintf("EtcdBackup %s not found", restore.Spec.BackupName))
+		return ctrl.Result{}, nil
+	}
+
+	target, err := r.getTarget(ctx, restore)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledDown, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonTargetNotFound, fmt.Sprintf("%s %s not found", restore.Spec.Target.Kind, restore.Spec.Target.Name))
+		return ctrl.Result{}, nil
+	}
+
+	switch holder := target.obj.GetAnnotations()[operatorkcpiov1alpha1.RestoreAnnotation]; holder {
+	case restore.Name:
+		// already claimed by this restore
+
+	case "":
+		if err := r.setRestoreAnnotation(ctx, target.obj, restore.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+
+	default:
+		setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledDown, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonRestoreConflict, fmt.Sprintf("EtcdRestore %s is already restoring %s %s", holder, restore.Spec.Target.Kind, restore.Spec.Target.Name))
+		return ctrl.Result{RequeueAfter: etcdRestorePollInterval}, nil
+	}
+
+	if restore.Status.Phase == "" {
+		restore.Status.Phase = operatorkcpiov1alpha1.EtcdRestorePhaseScalingDown
+		restore.Status.StartTime = ptr.To(metav1.Now())
+	}
+
+	dep, err := getDeployment(ctx, r.Client, client.ObjectKey{Namespace: restore.Namespace, Name: target.deploymentName})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if dep != nil && ptr.Deref(dep.Spec.Replicas, 1) > 0 {
+		patch := client.MergeFrom(dep.DeepCopy())
+		dep.Spec.Replicas = ptr.To[int32](0)
+		if err := r.Patch(ctx, dep, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to scale down Deployment: %w", err)
+		}
+	}
+
+	if dep != nil && dep.Status.Replicas > 0 {
+		if time.Since(restore.Status.StartTime.Time) > etcdRestoreScaleDownTimeout {
+			return ctrl.Result{}, r.failScaleDown(ctx, restore, target, fmt.Sprintf("%d replicas of Deployment %s did not terminate within %v", dep.Status.Replicas, dep.Name, etcdRestoreScaleDownTimeout))
+		}
+
+		setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledDown, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonInProgress, fmt.Sprintf("waiting for %d replicas of Deployment %s to terminate", dep.Status.Replicas, dep.Name))
+		return ctrl.Result{RequeueAfter: etcdRestorePollInterval}, nil
+	}
+
+	setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledDown, metav1.ConditionTrue,
+		operatorkcpiov1alpha1.ConditionReasonSucceeded, fmt.Sprintf("%s %s has been scaled down", restore.Spec.Target.Kind, restore.Spec.Target.Name))
+
+	job := etcdrestore.NewJob(restore, &backup, target.etcd)
+	if err := controllerutil.SetControllerReference(restore, job, r.Scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, fmt.Errorf("failed to create Job: %w", err)
+	}
+
+	restore.Status.Phase = operatorkcpiov1alpha1.EtcdRestorePhaseRestoring
+	restore.Status.JobName = job.Name
+	setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeSnapshotRestored, metav1.ConditionFalse,
+		operatorkcpiov1alpha1.ConditionReasonInProgress, fmt.Sprintf("Job %s is restoring the snapshot", job.Name))
+
+	return ctrl.Result{}, nil
+}
+
+// failScaleDown fails the given restore while scaling down. As the target's etcd has not been touched yet, the
+// target is released again.
+func (r *EtcdRestoreReconciler) failScaleDown(ctx context.Context, restore *operatorkcpiov1alpha1.EtcdRestore, target *restoreTarget, message string) error {
+	if err := r.setRestoreAnnotation(ctx, target.obj, ""); err != nil {
+		return err
+	}
+
+	restore.Status.Phase = operatorkcpiov1alpha1.EtcdRestorePhaseFailed
+	restore.Status.CompletionTime = ptr.To(metav1.Now())
+	setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledDown, metav1.ConditionFalse,
+		operatorkcpiov1alpha1.ConditionReasonFailed, message+"; the target has been released")
+
+	return nil
+}
+
+// reconcileRestoring waits for the restore Job to finish. On success the target is released and scales
+// back up. On failure it is kept scaled down, as its etcd is in an unknown state, until the EtcdRestore
+// is deleted.
+func (r *EtcdRestoreReconciler) reconcileRestoring(ctx context.Context, restore *operatorkcpiov1alpha1.EtcdRestore) (ctrl.Result, error) {
+	var job batchv1.Job
+	if err := r.Get(ctx, client.ObjectKey{Namespace: restore.Namespace, Name: restore.Status.JobName}, &job); err != nil {
+		if apierrors.IsNotFound(err) {
+			// the Job might not have made it into the cache yet
+			return ctrl.Result{RequeueAfter: etcdRestorePollInterval}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get Job: %w", err)
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch cond.Type {
+		case batchv1.JobComplete:
+			target, err := r.getTarget(ctx, restore)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			if target != nil {
+				if err := r.setRestoreAnnotation(ctx, target.obj, ""); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+
+			restore.Status.Phase = operatorkcpiov1alpha1.EtcdRestorePhaseScalingUp
+			setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeSnapshotRestored, metav1.ConditionTrue,
+				operatorkcpiov1alpha1.ConditionReasonSucceeded, "snapshot has been restored")
+			setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledUp, metav1.ConditionFalse,
+				operatorkcpiov1alpha1.ConditionReasonInProgress, fmt.Sprintf("waiting for %s %s to become available", restore.Spec.Target.Kind, restore.Spec.Target.Name))
+
+			return ctrl.Result{RequeueAfter: etcdRestorePollInterval}, nil
+
+		case batchv1.JobFailed:
+			restore.Status.Phase = operatorkcpiov1alpha1.EtcdRestorePhaseFailed
+			restore.Status.CompletionTime = ptr.To(metav1.Now())
+			setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeSnapshotRestored, metav1.ConditionFalse,
+				operatorkcpiov1alpha1.ConditionReasonFailed, fmt.Sprintf("Job %s failed: %s; the target stays scaled down until this EtcdRestore is deleted", job.Name, cond.Message))
+
+			return ctrl.Result{}, nil
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileScaleUp waits for the target's Deployment to become available again.
+func (r *EtcdRestoreReconciler) reconcileScaleUp(ctx context.Context, restore *operatorkcpiov1alpha1.EtcdRestore) (ctrl.Result, error) {
+	target, err := r.getTarget(ctx, restore)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+
+		setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledUp, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonTargetNotFound, fmt.Sprintf("%s %s not found", restore.Spec.Target.Kind, restore.Spec.Target.Name))
+		return ctrl.Result{}, nil
+	}
+
+	dep, err := getDeployment(ctx, r.Client, client.ObjectKey{Namespace: restore.Namespace, Name: target.deploymentName})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !isDeploymentAvailable(dep) {
+		return ctrl.Result{RequeueAfter: etcdRestorePollInterval}, nil
+	}
+
+	restore.Status.Phase = operatorkcpiov1alpha1.EtcdRestorePhaseCompleted
+	restore.Status.CompletionTime = ptr.To(metav1.Now())
+	setCondition(&restore.Status.Conditions, restore.Generation, operatorkcpiov1alpha1.ConditionTypeTargetScaledUp, metav1.ConditionTrue,
+		operatorkcpiov1alpha1.ConditionReasonSucceeded, fmt.Sprintf("%s %s is available again", restore.Spec.Target.Kind, restore.Spec.Target.Name))
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileDeletion releases the target if it is still claimed by the given restore.
+func (r *EtcdRestoreReconciler) reconcileDeletion(ctx context.Context, restore *operatorkcpiov1alpha1.EtcdRestore) error {
+	if !controllerutil.ContainsFinalizer(restore, etcdRestoreFinalizer) {
+		return nil
+	}
+
+	target, err := r.getTarget(ctx, restore)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if target != nil && target.obj.GetAnnotations()[operatorkcpiov1alpha1.RestoreAnnotation] == restore.Name {
+		if err := r.setRestoreAnnotation(ctx, target.obj, ""); err != nil {
+			return err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(restore, etcdRestoreFinalizer)
+	if err := r.Update(ctx, restore); err != nil {
+		return fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// setRestoreAnnotation sets the RestoreAnnotation on the given object, or removes it if value is empty.
+func (r *EtcdRestoreReconciler) setRestoreAnnotation(ctx context.Context, obj client.Object, value string) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	annotations := obj.GetAnnotations()
+	if value == "" {
+		delete(annotations, operatorkcpiov1alpha1.RestoreAnnotation)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[operatorkcpiov1alpha1.RestoreAnnotation] = value
+	}
+	obj.SetAnnotations(annotations)
+
+	if err := r.Patch(ctx, obj, patch); err != nil {
+		return fmt.Errorf("failed to update %s annotation: %w", operatorkcpiov1alpha1.RestoreAnnotation, err)
+	}
+
+	return nil
+}
+
+// getTarget returns the object whose etcd the given EtcdRestore restores.
+func (r *EtcdRestoreReconciler) getTarget(ctx context.Context, restore *operatorkcpiov1alpha1.EtcdRestore) (*restoreTarget, error) {
+	key := client.ObjectKey{Namespace: restore.Namespace, Name: restore.Spec.Target.Name}
+
+	switch restore.Spec.Target.Kind {
+	case operatorkcpiov1alpha1.EtcdBackupTargetRootShard:
+		var rootShard operatorkcpiov1alpha1.RootShard
+		if err := r.Get(ctx, key, &rootShard); err != nil {
+			return nil, err
+		}
+		return &restoreTarget{obj: &rootShard, etcd: rootShard.Spec.Etcd, deploymentName: resources.GetRootShardDeploymentName(&rootShard)}, nil
+
+	case operatorkcpiov1alpha1.EtcdBackupTargetShard:
+		var shard operatorkcpiov1alpha1.Shard
+		if err := r.Get(ctx, key, &shard); err != nil {
+			return nil, err
+		}
+		return &restoreTarget{obj: &shard, etcd: shard.Spec.Etcd, deploymentName: resources.GetShardDeploymentName(&shard)}, nil
+
+	case operatorkcpiov1alpha1.EtcdBackupTargetCacheServer:
+		var cacheServer operatorkcpiov1alpha1.CacheServer
+		if err := r.Get(ctx, key, &cacheServer); err != nil {
+			return nil, err
+		}
+		return &restoreTarget{obj: &cacheServer, etcd: cacheServer.Spec.Etcd, deploymentName: resources.GetCacheServerDeploymentName(&cacheServer)}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported target kind %q", restore.Spec.Target.Kind)
+	}
+}
+
+// isDeploymentAvailable returns true if all desired replicas of the given Deployment are ready.
+func isDeploymentAvailable(dep *appsv1.Deployment) bool {
+	if dep == nil || dep.Status.ObservedGeneration < dep.Generation {
+		return false
+	}
+
+	replicas := ptr.Deref(dep.Spec.Replicas, 1)
+
+	return replicas > 0 && dep.Status.ReadyReplicas == replicas
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *EtcdRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&operatorkcpiov1alpha1.EtcdRestore{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
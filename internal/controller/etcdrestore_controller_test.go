@@ -0,0 +1,270 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+var _ = Describe("EtcdRestore Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		cacheServer := &operatorkcpiov1alpha1.CacheServer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "etcdrestore-test-cache",
+				Namespace: "default",
+			},
+			Spec: operatorkcpiov1alpha1.CacheServerSpec{
+				Etcd: operatorkcpiov1alpha1.EtcdConfig{
+					Endpoints: []string{"https://etcd-0:2379"},
+					ClientCert: operatorkcpiov1alpha1.EtcdCertificate{
+						SecretRef: corev1.LocalObjectReference{Name: "etcd-client-cert"},
+					},
+				},
+			},
+		}
+
+		target := operatorkcpiov1alpha1.EtcdBackupTarget{
+			Kind: operatorkcpiov1alpha1.EtcdBackupTargetCacheServer,
+			Name: cacheServer.Name,
+		}
+
+		backup := &operatorkcpiov1alpha1.EtcdBackup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "etcdrestore-test-backup",
+				Namespace: "default",
+			},
+			Spec: operatorkcpiov1alpha1.EtcdBackupSpec{
+				Target:   target,
+				Schedule: "0 */6 * * *",
+				Storage: operatorkcpiov1alpha1.EtcdBackupStorage{
+					PVC: &operatorkcpiov1alpha1.PVCBackupStorage{ClaimName: "backups"},
+				},
+			},
+		}
+
+		BeforeEach(func() {
+			By("creating the custom resource for the Kind EtcdRestore")
+			err := k8sClient.Get(ctx, typeNamespacedName, &operatorkcpiov1alpha1.EtcdRestore{})
+			if err != nil && errors.IsNotFound(err) {
+				resource := &operatorkcpiov1alpha1.EtcdRestore{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: operatorkcpiov1alpha1.EtcdRestoreSpec{
+						Target:     target,
+						BackupName: backup.Name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &operatorkcpiov1alpha1.EtcdRestore{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			if errors.IsNotFound(err) {
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance EtcdRestore")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			By("Releasing the finalizer")
+			controllerReconciler := &EtcdRestoreReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, resource))).To(BeTrue())
+		})
+
+		It("should report a missing backup", func() {
+			controllerReconciler := &EtcdRestoreReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			restore := &operatorkcpiov1alpha1.EtcdRestore{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, restore)).To(Succeed())
+			cond := meta.FindStatusCondition(restore.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeTargetScaledDown))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonBackupNotFound)))
+		})
+
+		It("should not touch anything while paused", func() {
+			restore := &operatorkcpiov1alpha1.EtcdRestore{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, restore)).To(Succeed())
+			restore.Annotations = map[string]string{operatorkcpiov1alpha1.PausedAnnotation: "true"}
+			Expect(k8sClient.Update(ctx, restore)).To(Succeed())
+
+			controllerReconciler := &EtcdRestoreReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, restore)).To(Succeed())
+			Expect(restore.Finalizers).To(BeEmpty())
+			Expect(restore.Status.Phase).To(BeEmpty())
+			Expect(meta.IsStatusConditionTrue(restore.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypePaused))).To(BeTrue())
+		})
+
+		It("should successfully reconcile the resource", func() {
+			By("creating the target CacheServer and the EtcdBackup")
+			Expect(k8sClient.Create(ctx, cacheServer.DeepCopy())).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, cacheServer.DeepCopy())
+			Expect(k8sClient.Create(ctx, backup.DeepCopy())).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, backup.DeepCopy())
+
+			By("Reconciling the created resource")
+			controllerReconciler := &EtcdRestoreReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking that the target has been claimed")
+			cs := &operatorkcpiov1alpha1.CacheServer{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cacheServer), cs)).To(Succeed())
+			Expect(cs.Annotations).To(HaveKeyWithValue(operatorkcpiov1alpha1.RestoreAnnotation, resourceName))
+
+			By("Checking the Job")
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: resourceName + "-etcd-restore", Namespace: "default"}, job)).To(Succeed())
+			Expect(job.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("PersistentVolumeClaim.ClaimName", "backups")))
+
+			By("Checking the EtcdRestore status")
+			restore := &operatorkcpiov1alpha1.EtcdRestore{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, restore)).To(Succeed())
+			Expect(restore.Status.Phase).To(Equal(operatorkcpiov1alpha1.EtcdRestorePhaseRestoring))
+			Expect(meta.IsStatusConditionTrue(restore.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeTargetScaledDown))).To(BeTrue())
+
+			By("Deleting the EtcdRestore releases the target")
+			Expect(k8sClient.Delete(ctx, restore)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cacheServer), cs)).To(Succeed())
+			Expect(cs.Annotations).NotTo(HaveKey(operatorkcpiov1alpha1.RestoreAnnotation))
+		})
+
+		It("should scale down the target and fail if it does not terminate in time", func() {
+			By("creating the target CacheServer, its Deployment and the EtcdBackup")
+			Expect(k8sClient.Create(ctx, cacheServer.DeepCopy())).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, cacheServer.DeepCopy())
+			Expect(k8sClient.Create(ctx, backup.DeepCopy())).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, backup.DeepCopy())
+
+			labels := map[string]string{"app": "etcdrestore-test"}
+			dep := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resources.GetCacheServerDeploymentName(cacheServer),
+					Namespace: "default",
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: ptr.To[int32](2),
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "cache-server", Image: "cache-server"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, dep)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, dep)
+			dep.Status.Replicas = 2
+			Expect(k8sClient.Status().Update(ctx, dep)).To(Succeed())
+
+			By("Reconciling the created resource")
+			controllerReconciler := &EtcdRestoreReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking that the Deployment has been scaled down")
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(dep), dep)).To(Succeed())
+			Expect(dep.Spec.Replicas).To(HaveValue(BeEquivalentTo(0)))
+
+			restore := &operatorkcpiov1alpha1.EtcdRestore{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, restore)).To(Succeed())
+			Expect(restore.Status.Phase).To(Equal(operatorkcpiov1alpha1.EtcdRestorePhaseScalingDown))
+
+			By("Exceeding the scale down deadline")
+			restore.Status.StartTime = ptr.To(metav1.NewTime(time.Now().Add(-etcdRestoreScaleDownTimeout - time.Minute)))
+			Expect(k8sClient.Status().Update(ctx, restore)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, restore)).To(Succeed())
+			Expect(restore.Status.Phase).To(Equal(operatorkcpiov1alpha1.EtcdRestorePhaseFailed))
+			cond := meta.FindStatusCondition(restore.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeTargetScaledDown))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonFailed)))
+
+			cs := &operatorkcpiov1alpha1.CacheServer{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cacheServer), cs)).To(Succeed())
+			Expect(cs.Annotations).NotTo(HaveKey(operatorkcpiov1alpha1.RestoreAnnotation))
+		})
+	})
+})
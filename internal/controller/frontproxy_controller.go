@@ -17,46 +17,486 @@ limitations under the License.
 package controller
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/registry"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/frontproxy"
+)
+
+const (
+	// gatewayAddressPollInterval is how often a FrontProxy exposed through a Gateway is reconciled while the
+	// Gateway has not reported an address yet. Gateways are only watched if the Gateway API CRDs are installed
+	// when the operator starts.
+	gatewayAddressPollInterval = 30 * time.Second
 )
 
 // FrontProxyReconciler reconciles a FrontProxy object
 type FrontProxyReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// ImageResolver resolves image tags to digests if digest resolution is enabled. Defaults to
+	// querying the image registry.
+	ImageResolver registry.Resolver
 }
 
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=frontproxies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=frontproxies/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=frontproxies/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=referencegrants,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=shards,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services;configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=tlsroutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the FrontProxy object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
 func (r *FrontProxyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+	logger.V(4).Info("Reconciling FrontProxy object")
+
+	var frontProxy operatorkcpiov1alpha1.FrontProxy
+	if err := r.Get(ctx, req.NamespacedName, &frontProxy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if frontProxy.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	oldFrontProxy := frontProxy.DeepCopy()
+
+	if isPaused(&frontProxy.Status.Conditions, &frontProxy) {
+		logger.V(4).Info("Reconciliation is paused, only updating status")
+
+		dep, err := getDeployment(ctx, r.Client, client.ObjectKey{Namespace: frontProxy.Namespace, Name: resources.GetFrontProxyDeploymentName(&frontProxy)})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if dep != nil {
+			frontProxy.Status.ReadyReplicas = dep.Status.ReadyReplicas
+			setAvailableCondition(&frontProxy.Status.Conditions, frontProxy.Generation, dep)
+		}
+
+		if err := r.Status().Patch(ctx, &frontProxy, client.MergeFrom(oldFrontProxy)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	rootShard, err := getRootShard(ctx, r.Client, reference.KindFrontProxy, frontProxy.Namespace, frontProxy.Spec.RootShard)
+	if err != nil {
+		setRootShardCondition(&frontProxy.Status.Conditions, frontProxy.Generation, err)
+
+		if patchErr := r.Status().Patch(ctx, &frontProxy, client.MergeFrom(oldFrontProxy)); patchErr != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", patchErr)
+		}
+
+		return ctrl.Result{}, fmt.Errorf("failed to get RootShard: %w", err)
+	}
+
+	frontProxy.Status.Version = resources.GetEffectiveVersion(frontProxy.Spec.Image, resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseFrontProxies))
+	if !checkVersionCompatibility(&frontProxy.Status.Conditions, frontProxy.Generation, reference.KindFrontProxy, frontProxy.Status.Version, resources.GetRootShardVersion(rootShard)) {
+		if err := r.Status().Patch(ctx, &frontProxy, client.MergeFrom(oldFrontProxy)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	resolvedImage, err := resolveImageDigest(ctx, r.Client, r.ImageResolver, frontProxy.Namespace, frontProxy.Generation, &frontProxy.Status.Conditions, frontProxy.Spec.Image, resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseFrontProxies), frontProxy.Status.ResolvedImage)
+	frontProxy.Status.ResolvedImage = resolvedImage
+	if err != nil {
+		if patchErr := r.Status().Patch(ctx, &frontProxy, client.MergeFrom(oldFrontProxy)); patchErr != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", patchErr)
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	dep, svc, err := r.reconcileWorkloads(ctx, &frontProxy, rootShard)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileIngress(ctx, &frontProxy, rootShard); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileTLSRoute(ctx, &frontProxy, rootShard); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	frontProxy.Status.Replicas = ptr.Deref(dep.Spec.Replicas, 1)
+	frontProxy.Status.ReadyReplicas = dep.Status.ReadyReplicas
+	setAvailableCondition(&frontProxy.Status.Conditions, frontProxy.Generation, dep)
+
+	address, err := r.getExternalAddress(ctx, &frontProxy, svc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
-	// TODO(user): your logic here
+	frontProxy.Status.ExternalAddress = address
+	if address != "" {
+		setCondition(&frontProxy.Status.Conditions, frontProxy.Generation, operatorkcpiov1alpha1.ConditionTypeExposed, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonAddressAssigned, fmt.Sprintf("front-proxy is reachable at %s", address))
+	} else {
+		setCondition(&frontProxy.Status.Conditions, frontProxy.Generation, operatorkcpiov1alpha1.ConditionTypeExposed, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonAddressPending, "no external address has been assigned yet")
+	}
+
+	if err := r.Status().Patch(ctx, &frontProxy, client.MergeFrom(oldFrontProxy)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	if address == "" && frontProxy.Spec.Gateway != nil {
+		return ctrl.Result{RequeueAfter: gatewayAddressPollInterval}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
+func (r *FrontProxyReconciler) reconcileWorkloads(ctx context.Context, frontProxy *operatorkcpiov1alpha1.FrontProxy, rootShard *operatorkcpiov1alpha1.RootShard) (*appsv1.Deployment, *corev1.Service, error) {
+	name := resources.GetFrontProxyDeploymentName(frontProxy)
+	objMeta := metav1.ObjectMeta{Name: name, Namespace: frontProxy.Namespace}
+
+	shards, err := r.getShards(ctx, rootShard)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: resources.GetFrontProxyConfigName(frontProxy), Namespace: frontProxy.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+		if err := frontproxy.MutateConfigMap(cm, frontProxy, frontproxy.GetPathMappings(rootShard, shards)); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(frontProxy, cm, r.Scheme)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to reconcile ConfigMap: %w", err)
+	}
+
+	if err := reconcileClientCABundle(ctx, r.Client, r.Scheme, frontProxy, rootShard, name, resources.GetFrontProxyResourceLabels(frontProxy)); err != nil {
+		return nil, nil, err
+	}
+
+	dep := &appsv1.Deployment{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		frontproxy.MutateDeployment(dep, frontProxy, rootShard)
+		return controllerutil.SetControllerReference(frontProxy, dep, r.Scheme)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to reconcile Deployment: %w", err)
+	}
+
+	svc := &corev1.Service{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		frontproxy.MutateService(svc, frontProxy)
+		return controllerutil.SetControllerReference(frontProxy, svc, r.Scheme)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to reconcile Service: %w", err)
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, pdb, func() error {
+		resources.MutatePodDisruptionBudget(pdb, resources.GetFrontProxyResourceLabels(frontProxy))
+		return controllerutil.SetControllerReference(frontProxy, pdb, r.Scheme)
+	}); err != nil {
+		return nil, nil, fmt.Errorf("failed to reconcile PodDisruptionBudget: %w", err)
+	}
+
+	return dep, svc, nil
+}
+
+func (r *FrontProxyReconciler) reconcileIngress(ctx context.Context, frontProxy *operatorkcpiov1alpha1.FrontProxy, rootShard *operatorkcpiov1alpha1.RootShard) error {
+	ing := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: resources.GetFrontProxyDeploymentName(frontProxy), Namespace: frontProxy.Namespace}}
+
+	if frontProxy.Spec.Ingress == nil {
+		if err := r.Delete(ctx, ing); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Ingress: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, ing, func() error {
+		frontproxy.MutateIngress(ing, frontProxy, rootShard)
+		return controllerutil.SetControllerReference(frontProxy, ing, r.Scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile Ingress: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FrontProxyReconciler) reconcileTLSRoute(ctx context.Context, frontProxy *operatorkcpiov1alpha1.FrontProxy, rootShard *operatorkcpiov1alpha1.RootShard) error {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(frontproxy.TLSRouteGVK)
+	route.SetName(resources.GetFrontProxyDeploymentName(frontProxy))
+	route.SetNamespace(frontProxy.Namespace)
+
+	if frontProxy.Spec.Gateway == nil {
+		// the Gateway API CRDs are optional, so a missing kind is not an error.
+		if err := r.Delete(ctx, route); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete TLSRoute: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, route, func() error {
+		if err := frontproxy.MutateTLSRoute(route, frontProxy, rootShard); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(frontProxy, route, r.Scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile TLSRoute: %w", err)
+	}
+
+	return nil
+}
+
+// getExternalAddress determines the address that the front-proxy can be reached at from outside
+// the cluster. Gateways and Ingresses take precedence over the Service, since they are the entry
+// point for traffic when configured.
+func (r *FrontProxyReconciler) getExternalAddress(ctx context.Context, frontProxy *operatorkcpiov1alpha1.FrontProxy, svc *corev1.Service) (string, error) {
+	if frontProxy.Spec.Gateway != nil {
+		ref := frontProxy.Spec.Gateway.ParentRefs[0]
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = frontProxy.Namespace
+		}
+
+		gateway := &unstructured.Unstructured{}
+		gateway.SetGroupVersionKind(frontproxy.GatewayGVK)
+		if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, gateway); err != nil {
+			if apierrors.IsNotFound(err) {
+				return "", nil
+			}
+			return "", fmt.Errorf("failed to get Gateway: %w", err)
+		}
+
+		addresses, _, _ := unstructured.NestedSlice(gateway.Object, "status", "addresses")
+		for _, addr := range addresses {
+			if m, ok := addr.(map[string]interface{}); ok {
+				if value, ok := m["value"].(string); ok && value != "" {
+					return value, nil
+				}
+			}
+		}
+
+		return "", nil
+	}
+
+	if frontProxy.Spec.Ingress != nil {
+		var ing networkingv1.Ingress
+		if err := r.Get(ctx, client.ObjectKey{Namespace: frontProxy.Namespace, Name: resources.GetFrontProxyDeploymentName(frontProxy)}, &ing); err != nil {
+			return "", client.IgnoreNotFound(err)
+		}
+
+		for _, lb := range ing.Status.LoadBalancer.Ingress {
+			if lb.Hostname != "" {
+				return lb.Hostname, nil
+			}
+			if lb.IP != "" {
+				return lb.IP, nil
+			}
+		}
+
+		return "", nil
+	}
+
+	for _, lb := range svc.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			return lb.Hostname, nil
+		}
+		if lb.IP != "" {
+			return lb.IP, nil
+		}
+	}
+
+	return "", nil
+}
+
+// getShards returns the Shards of the kcp setup formed around the given RootShard, sorted by name. Shards
+// in other namespaces are only returned if a ReferenceGrant permits them to reference the RootShard, so
+// that no one can route front-proxy traffic to their own backends by creating a Shard.
+func (r *FrontProxyReconciler) getShards(ctx context.Context, rootShard *operatorkcpiov1alpha1.RootShard) ([]operatorkcpiov1alpha1.Shard, error) {
+	var list operatorkcpiov1alpha1.ShardList
+	if err := r.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("failed to list Shards: %w", err)
+	}
+
+	var shards []operatorkcpiov1alpha1.Shard
+	for _, s := range list.Items {
+		if s.DeletionTimestamp != nil || !referencesRootShard(s.Namespace, s.Spec.RootShard, rootShard) {
+			continue
+		}
+
+		if _, err := reference.CheckRootShardReference(ctx, r.Client, reference.KindShard, s.Namespace, s.Spec.RootShard); err != nil {
+			if errors.Is(err, reference.ErrNotPermitted) {
+				continue
+			}
+			return nil, err
+		}
+
+		shards = append(shards, s)
+	}
+
+	slices.SortFunc(shards, func(a, b operatorkcpiov1alpha1.Shard) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	return shards, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *FrontProxyReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	bldr := ctrl.NewControllerManagedBy(mgr).
 		For(&operatorkcpiov1alpha1.FrontProxy{}).
-		Complete(r)
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.Secret{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&networkingv1.Ingress{}).
+		Watches(&operatorkcpiov1alpha1.RootShard{}, handler.EnqueueRequestsFromMapFunc(r.frontProxiesForRootShard)).
+		Watches(&operatorkcpiov1alpha1.Shard{}, handler.EnqueueRequestsFromMapFunc(r.frontProxiesForShard)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.frontProxiesForClientCA)).
+		Watches(&operatorkcpiov1alpha1.ReferenceGrant{}, handler.EnqueueRequestsFromMapFunc(r.frontProxiesForReferenceGrant))
+
+	// the Gateway API CRDs are optional, so Gateways are only watched if they are installed.
+	if _, err := mgr.GetRESTMapper().RESTMapping(frontproxy.GatewayGVK.GroupKind(), frontproxy.GatewayGVK.Version); err == nil {
+		gateway := &unstructured.Unstructured{}
+		gateway.SetGroupVersionKind(frontproxy.GatewayGVK)
+		bldr = bldr.Watches(gateway, handler.EnqueueRequestsFromMapFunc(r.frontProxiesForGateway))
+	} else if !meta.IsNoMatchError(err) {
+		return fmt.Errorf("failed to check for the Gateway API: %w", err)
+	}
+
+	return bldr.Complete(r)
+}
+
+// frontProxiesForGateway enqueues all FrontProxies attached to the given Gateway via spec.gateway.parentRefs,
+// so that the address assigned to the Gateway is reflected in their status.
+func (r *FrontProxyReconciler) frontProxiesForGateway(ctx context.Context, obj client.Object) []reconcile.Request {
+	var frontProxies operatorkcpiov1alpha1.FrontProxyList
+	if err := r.List(ctx, &frontProxies); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list FrontProxies")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, fp := range frontProxies.Items {
+		if fp.Spec.Gateway == nil {
+			continue
+		}
+
+		for _, ref := range fp.Spec.Gateway.ParentRefs {
+			namespace := ref.Namespace
+			if namespace == "" {
+				namespace = fp.Namespace
+			}
+
+			if namespace == obj.GetNamespace() && ref.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&fp)})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// frontProxiesForRootShard enqueues all FrontProxies referencing the given RootShard.
+func (r *FrontProxyReconciler) frontProxiesForRootShard(ctx context.Context, obj client.Object) []reconcile.Request {
+	var frontProxies operatorkcpiov1alpha1.FrontProxyList
+	if err := r.List(ctx, &frontProxies); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list FrontProxies")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, fp := range frontProxies.Items {
+		if referencesRootShard(fp.Namespace, fp.Spec.RootShard, obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&fp)})
+		}
+	}
+
+	return requests
+}
+
+// frontProxiesForClientCA enqueues all FrontProxies of the kcp setup trusting the client CA in the given Secret,
+// so that new and revoked kubeconfigs are reflected in their client CA bundles.
+func (r *FrontProxyReconciler) frontProxiesForClientCA(ctx context.Context, obj client.Object) []reconcile.Request {
+	key, ok := resources.GetClientCARootShard(obj)
+	if !ok {
+		return nil
+	}
+
+	return r.frontProxiesForRootShard(ctx, &operatorkcpiov1alpha1.RootShard{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}})
+}
+
+// frontProxiesForShard enqueues all FrontProxies in front of the RootShard referenced by the given Shard,
+// so that its virtual workspaces are routed.
+func (r *FrontProxyReconciler) frontProxiesForShard(ctx context.Context, obj client.Object) []reconcile.Request {
+	shard, ok := obj.(*operatorkcpiov1alpha1.Shard)
+	if !ok {
+		return nil
+	}
+
+	key, err := reference.GetRootShardKey(shard.Namespace, shard.Spec.RootShard)
+	if err != nil {
+		return nil
+	}
+
+	return r.frontProxiesForRootShard(ctx, &operatorkcpiov1alpha1.RootShard{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}})
+}
+
+// frontProxiesForReferenceGrant enqueues all FrontProxies referencing a RootShard in the namespace of the given ReferenceGrant
+// from another namespace, so that granting or revoking access takes effect immediately.
+func (r *FrontProxyReconciler) frontProxiesForReferenceGrant(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list operatorkcpiov1alpha1.FrontProxyList
+	if err := r.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list FrontProxies")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, fp := range list.Items {
+		key, err := reference.GetRootShardKey(fp.Namespace, fp.Spec.RootShard)
+		if err == nil && key.Namespace == obj.GetNamespace() && key.Namespace != fp.Namespace {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&fp)})
+		}
+	}
+
+	return requests
 }
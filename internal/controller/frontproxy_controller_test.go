@@ -22,9 +22,13 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
@@ -42,16 +46,47 @@ var _ = Describe("FrontProxy Controller", func() {
 		}
 		frontproxy := &operatorkcpiov1alpha1.FrontProxy{}
 
+		rootShard := &operatorkcpiov1alpha1.RootShard{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "frontproxy-test-root",
+				Namespace: "default",
+			},
+			Spec: operatorkcpiov1alpha1.RootShardSpec{
+				Hostname: "example.kcp.io",
+				CommonShardSpec: operatorkcpiov1alpha1.CommonShardSpec{
+					Etcd: operatorkcpiov1alpha1.EtcdConfig{
+						Endpoints: []string{"https://localhost:2379"},
+					},
+				},
+			},
+		}
+
 		BeforeEach(func() {
+			By("creating the RootShard referenced by the FrontProxy")
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(rootShard), &operatorkcpiov1alpha1.RootShard{})
+			if err != nil && errors.IsNotFound(err) {
+				Expect(k8sClient.Create(ctx, rootShard.DeepCopy())).To(Succeed())
+			}
+
 			By("creating the custom resource for the Kind FrontProxy")
-			err := k8sClient.Get(ctx, typeNamespacedName, frontproxy)
+			err = k8sClient.Get(ctx, typeNamespacedName, frontproxy)
 			if err != nil && errors.IsNotFound(err) {
 				resource := &operatorkcpiov1alpha1.FrontProxy{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: operatorkcpiov1alpha1.FrontProxySpec{
+						RootShard: operatorkcpiov1alpha1.RootShardConfig{
+							Reference: &corev1.ObjectReference{Name: rootShard.Name},
+						},
+						Service: &operatorkcpiov1alpha1.FrontProxyServiceSpec{
+							Type: corev1.ServiceTypeNodePort,
+						},
+						Ingress: &operatorkcpiov1alpha1.FrontProxyIngressSpec{
+							IngressClassName: ptr.To("nginx"),
+						},
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -65,6 +100,7 @@ var _ = Describe("FrontProxy Controller", func() {
 
 			By("Cleanup the specific resource instance FrontProxy")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, rootShard.DeepCopy())).To(Succeed())
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
@@ -77,8 +113,18 @@ var _ = Describe("FrontProxy Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Checking the front-proxy Service")
+			svc := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-front-proxy", Namespace: "default"}, svc)).To(Succeed())
+			Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+
+			By("Checking the passthrough Ingress")
+			ing := &networkingv1.Ingress{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-front-proxy", Namespace: "default"}, ing)).To(Succeed())
+			Expect(ing.Spec.Rules).To(HaveLen(1))
+			Expect(ing.Spec.Rules[0].Host).To(Equal("example.kcp.io"))
+			Expect(ing.Annotations).To(HaveKeyWithValue("nginx.ingress.kubernetes.io/ssl-passthrough", "true"))
 		})
 	})
 })
@@ -0,0 +1,179 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/version"
+)
+
+// getRootShard resolves the RootShard referenced by a Shard or FrontProxy (identified by fromKind) living in
+// the given namespace. Cross-namespace references are only resolved if permitted by a ReferenceGrant.
+func getRootShard(ctx context.Context, c client.Client, fromKind, namespace string, ref operatorkcpiov1alpha1.RootShardConfig) (*operatorkcpiov1alpha1.RootShard, error) {
+	return reference.ResolveRootShard(ctx, c, fromKind, namespace, ref)
+}
+
+// setRootShardCondition records why the referenced RootShard could not be resolved.
+func setRootShardCondition(conditions *[]metav1.Condition, generation int64, err error) {
+	reason := operatorkcpiov1alpha1.ConditionReasonRootShardNotFound
+	if errors.Is(err, reference.ErrNotPermitted) {
+		reason = operatorkcpiov1alpha1.ConditionReasonRefNotPermitted
+	}
+
+	setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeAvailable, metav1.ConditionFalse, reason, err.Error())
+}
+
+// referencesRootShard returns true if the given reference, made from an object in namespace, points to rootShard.
+func referencesRootShard(namespace string, ref operatorkcpiov1alpha1.RootShardConfig, rootShard client.Object) bool {
+	key, err := reference.GetRootShardKey(namespace, ref)
+	if err != nil {
+		return false
+	}
+
+	return key == client.ObjectKeyFromObject(rootShard)
+}
+
+// checkVersionCompatibility validates the kcp version of a component of the given kind against the compatibility
+// matrix and, if rootShardVersion is set, against the version skew policy. It updates the VersionCompatible
+// condition and returns false if the component must not be updated. Versions that cannot be parsed (e.g.
+// custom image tags) are not checked.
+func checkVersionCompatibility(conditions *[]metav1.Condition, generation int64, kind, componentVersion, rootShardVersion string) bool {
+	err := version.IsSupported(componentVersion)
+	reason := operatorkcpiov1alpha1.ConditionReasonUnsupportedVersion
+
+	if err == nil && rootShardVersion != "" {
+		err = version.CheckSkew(kind, componentVersion, rootShardVersion)
+		reason = operatorkcpiov1alpha1.ConditionReasonUnsupportedSkew
+	}
+
+	switch {
+	case err == nil:
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeVersionCompatible, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonVersionSupported, fmt.Sprintf("kcp %s is supported", componentVersion))
+		return true
+
+	case errors.Is(err, version.ErrUnknownVersion):
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeVersionCompatible, metav1.ConditionUnknown,
+			operatorkcpiov1alpha1.ConditionReasonVersionUnknown, fmt.Sprintf("compatibility cannot be verified: %v", err))
+		return true
+
+	default:
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeVersionCompatible, metav1.ConditionFalse, reason, err.Error())
+		return false
+	}
+}
+
+// isPaused updates the Paused condition of obj and returns true if its reconciliation has been paused via
+// the PausedAnnotation. Reconcilers must not mutate any objects while paused, but keep publishing status.
+func isPaused(conditions *[]metav1.Condition, obj client.Object) bool {
+	if !hasPausedAnnotation(obj) {
+		meta.RemoveStatusCondition(conditions, string(operatorkcpiov1alpha1.ConditionTypePaused))
+		return false
+	}
+
+	setCondition(conditions, obj.GetGeneration(), operatorkcpiov1alpha1.ConditionTypePaused, metav1.ConditionTrue, operatorkcpiov1alpha1.ConditionReasonPaused,
+		fmt.Sprintf("reconciliation is paused via the %s annotation", operatorkcpiov1alpha1.PausedAnnotation))
+
+	return true
+}
+
+// hasPausedAnnotation returns true if the reconciliation of obj has been paused via the PausedAnnotation.
+func hasPausedAnnotation(obj client.Object) bool {
+	return obj.GetAnnotations()[operatorkcpiov1alpha1.PausedAnnotation] == "true"
+}
+
+// scaleDownForRestore scales the given Deployment down to zero replicas while an EtcdRestore is restoring
+// the etcd of obj, so that no kcp process writes to etcd during the restore.
+func scaleDownForRestore(dep *appsv1.Deployment, obj client.Object) {
+	if _, ok := obj.GetAnnotations()[operatorkcpiov1alpha1.RestoreAnnotation]; ok {
+		dep.Spec.Replicas = ptr.To[int32](0)
+	}
+}
+
+// requeueWithin shortens the RequeueAfter of the given result to d, unless it is already shorter. A zero
+// duration leaves the result unchanged.
+func requeueWithin(result ctrl.Result, d time.Duration) ctrl.Result {
+	if d > 0 && (result.RequeueAfter == 0 || d < result.RequeueAfter) {
+		result.RequeueAfter = d
+	}
+
+	return result
+}
+
+// getDeployment returns the Deployment with the given key, or nil if it does not exist.
+func getDeployment(ctx context.Context, c client.Client, key client.ObjectKey) (*appsv1.Deployment, error) {
+	var dep appsv1.Deployment
+	if err := c.Get(ctx, key, &dep); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Deployment: %w", err)
+	}
+
+	return &dep, nil
+}
+
+// setAvailableCondition updates the Available condition based on the observed state of a component's Deployment.
+func setAvailableCondition(conditions *[]metav1.Condition, generation int64, dep *appsv1.Deployment) {
+	cond := metav1.Condition{
+		Type:               string(operatorkcpiov1alpha1.ConditionTypeAvailable),
+		ObservedGeneration: generation,
+	}
+
+	desired := int32(1)
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+
+	switch {
+	case dep.Status.ReadyReplicas > 0:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = string(operatorkcpiov1alpha1.ConditionReasonReplicasReady)
+		cond.Message = fmt.Sprintf("%d/%d replicas are ready", dep.Status.ReadyReplicas, desired)
+	default:
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = string(operatorkcpiov1alpha1.ConditionReasonReplicasNotReady)
+		cond.Message = fmt.Sprintf("0/%d replicas are ready", desired)
+	}
+
+	meta.SetStatusCondition(conditions, cond)
+}
+
+// setCondition is a small wrapper around meta.SetStatusCondition for conditions that are not derived from a Deployment.
+func setCondition(conditions *[]metav1.Condition, generation int64, condType operatorkcpiov1alpha1.ConditionType, status metav1.ConditionStatus, reason operatorkcpiov1alpha1.ConditionReason, message string) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               string(condType),
+		Status:             status,
+		ObservedGeneration: generation,
+		Reason:             string(reason),
+		Message:            message,
+	})
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/registry"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// resolveImageDigest returns the digest the component's image is pinned to, if digest resolution is
+// enabled in its ImageSpec. The image tag is only resolved if it has not been resolved before, so that
+// restarts keep using the same image even if the tag is moved in the registry. The ImageResolved
+// condition is updated accordingly.
+func resolveImageDigest(ctx context.Context, c client.Reader, resolver registry.Resolver, namespace string, generation int64, conditions *[]metav1.Condition,
+	imageSpec *operatorkcpiov1alpha1.ImageSpec, version string, current *operatorkcpiov1alpha1.ResolvedImage) (*operatorkcpiov1alpha1.ResolvedImage, error) {
+	if imageSpec == nil || !imageSpec.ResolveDigest || imageSpec.Digest != "" {
+		meta.RemoveStatusCondition(conditions, string(operatorkcpiov1alpha1.ConditionTypeImageResolved))
+		return nil, nil
+	}
+
+	image := resources.GetImageReference(imageSpec, version)
+	if current != nil && current.Image == image && current.Digest != "" {
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeImageResolved, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonDigestResolved, fmt.Sprintf("%s is pinned to %s", image, current.Digest))
+		return current, nil
+	}
+
+	if resolver == nil {
+		resolver = registry.NewResolver(nil)
+	}
+
+	digest, err := resolveDigest(ctx, c, resolver, namespace, imageSpec, image)
+	if err != nil {
+		setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeImageResolved, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonDigestFailed, err.Error())
+		return current, fmt.Errorf("failed to resolve digest for %s: %w", image, err)
+	}
+
+	setCondition(conditions, generation, operatorkcpiov1alpha1.ConditionTypeImageResolved, metav1.ConditionTrue,
+		operatorkcpiov1alpha1.ConditionReasonDigestResolved, fmt.Sprintf("%s is pinned to %s", image, digest))
+
+	return &operatorkcpiov1alpha1.ResolvedImage{
+		Image:      image,
+		Digest:     digest,
+		ResolvedAt: metav1.Now(),
+	}, nil
+}
+
+func resolveDigest(ctx context.Context, c client.Reader, resolver registry.Resolver, namespace string, imageSpec *operatorkcpiov1alpha1.ImageSpec, image string) (string, error) {
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	var creds *registry.Credentials
+	for _, pullSecret := range imageSpec.ImagePullSecrets {
+		var secret corev1.Secret
+		if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: pullSecret.Name}, &secret); err != nil {
+			return "", fmt.Errorf("failed to get image pull Secret %s: %w", pullSecret.Name, err)
+		}
+
+		data, ok := secret.Data[corev1.DockerConfigJsonKey]
+		if !ok {
+			continue
+		}
+
+		if creds, err = registry.CredentialsFromDockerConfig(data, ref.Registry); err != nil {
+			return "", fmt.Errorf("invalid image pull Secret %s: %w", pullSecret.Name, err)
+		}
+		if creds != nil {
+			break
+		}
+	}
+
+	return resolver.Resolve(ctx, image, creds)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp-operator/internal/client"
+)
+
+const (
+	// inventoryInterval is how often the inventory of a (root) shard is collected from kcp.
+	inventoryInterval = 5 * time.Minute
+
+	// inventoryPageSize is how many objects are listed at once when counting them.
+	inventoryPageSize = 500
+
+	// rootShardName is the name the root shard registers itself with in kcp.
+	rootShardName = "root"
+)
+
+// isInventoryDue returns true if the given inventory is missing or old enough to be collected again. Status
+// updates trigger reconciliations, so the inventory must not be collected on every reconciliation.
+func isInventoryDue(inventory *operatorkcpiov1alpha1.ShardInventory) bool {
+	// allow for some jitter in when periodic reconciliations happen
+	return inventory == nil || time.Since(inventory.LastUpdated.Time) >= inventoryInterval-inventoryInterval/10
+}
+
+// getShardInventory collects the inventory of the kcp shard with the given name, reachable at shardURL.
+// Logical clusters and workspaces are listed on the shard itself, while the URLs are taken from the kcp
+// Shard object in the root workspace.
+func getShardInventory(ctx context.Context, c client.Client, scheme *runtime.Scheme, rootShard *operatorkcpiov1alpha1.RootShard, shardName, shardURL string) (*operatorkcpiov1alpha1.ShardInventory, error) {
+	shardClient, err := kcpclient.NewShardClient(ctx, c, rootShard, shardURL, kcpclient.WildcardCluster, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	logicalClusters, err := countObjects(ctx, shardClient, kcpclient.LogicalClusterGVK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list logical clusters: %w", err)
+	}
+
+	workspaces, err := countObjects(ctx, shardClient, kcpclient.WorkspaceGVK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspaces: %w", err)
+	}
+
+	rootClient, err := kcpclient.NewRootShardClient(ctx, c, rootShard, scheme)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	kcpShard := &unstructured.Unstructured{}
+	kcpShard.SetGroupVersionKind(kcpclient.ShardGVK)
+	if err := rootClient.Get(ctx, client.ObjectKey{Name: shardName}, kcpShard); err != nil {
+		return nil, fmt.Errorf("failed to get kcp Shard: %w", err)
+	}
+
+	inventory := &operatorkcpiov1alpha1.ShardInventory{
+		LogicalClusters: logicalClusters,
+		Workspaces:      workspaces,
+		LastUpdated:     metav1.Now(),
+	}
+	inventory.URLs.Internal, _, _ = unstructured.NestedString(kcpShard.Object, "spec", "baseURL")
+	inventory.URLs.External, _, _ = unstructured.NestedString(kcpShard.Object, "spec", "externalURL")
+	inventory.URLs.VirtualWorkspaces, _, _ = unstructured.NestedString(kcpShard.Object, "spec", "virtualWorkspaceURL")
+
+	return inventory, nil
+}
+
+// countObjects counts the objects of the given kind. Only their metadata is listed, in pages, so that large
+// shards are not loaded into memory at once.
+func countObjects(ctx context.Context, c client.Client, gvk schema.GroupVersionKind) (int32, error) {
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+
+	var count int32
+	for {
+		if err := c.List(ctx, list, client.Limit(inventoryPageSize), client.Continue(list.Continue)); err != nil {
+			return 0, err
+		}
+
+		count += int32(len(list.Items))
+		if list.Continue == "" {
+			return count, nil
+		}
+	}
+}
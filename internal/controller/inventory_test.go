@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp-operator/internal/client"
+)
+
+func TestIsInventoryDue(t *testing.T) {
+	testcases := []struct {
+		name      string
+		inventory *operatorkcpiov1alpha1.ShardInventory
+		expected  bool
+	}{
+		{
+			name:     "no inventory",
+			expected: true,
+		},
+		{
+			name:      "fresh inventory",
+			inventory: &operatorkcpiov1alpha1.ShardInventory{LastUpdated: metav1.NewTime(time.Now().Add(-time.Minute))},
+			expected:  false,
+		},
+		{
+			name:      "inventory within jitter",
+			inventory: &operatorkcpiov1alpha1.ShardInventory{LastUpdated: metav1.NewTime(time.Now().Add(-inventoryInterval + 10*time.Second))},
+			expected:  true,
+		},
+		{
+			name:      "outdated inventory",
+			inventory: &operatorkcpiov1alpha1.ShardInventory{LastUpdated: metav1.NewTime(time.Now().Add(-2 * inventoryInterval))},
+			expected:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if due := isInventoryDue(tc.inventory); due != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, due)
+			}
+		})
+	}
+}
+
+func TestCountObjects(t *testing.T) {
+	const total = inventoryPageSize*2 + 3
+
+	var objects []client.Object
+	for i := range total {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(kcpclient.LogicalClusterGVK)
+		obj.SetName(fmt.Sprintf("cluster-%d", i))
+		objects = append(objects, obj)
+	}
+
+	// the fake client neither paginates nor lists kcp's types as metadata, so pages are cut out of the full list here
+	var calls int
+	c := fake.NewClientBuilder().WithObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			calls++
+
+			listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+			if listOpts.Limit == 0 {
+				t.Fatal("expected objects to be listed in pages")
+			}
+
+			partial, ok := list.(*metav1.PartialObjectMetadataList)
+			if !ok {
+				t.Fatalf("expected only metadata to be listed, got %T", list)
+			}
+
+			all := &unstructured.UnstructuredList{}
+			all.SetGroupVersionKind(partial.GroupVersionKind())
+			if err := c.List(ctx, all); err != nil {
+				return err
+			}
+
+			partial.Items = nil
+			for _, obj := range all.Items {
+				partial.Items = append(partial.Items, metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: obj.GetName()}})
+			}
+
+			start := 0
+			if listOpts.Continue != "" {
+				start, _ = strconv.Atoi(listOpts.Continue)
+			}
+			end := min(start+int(listOpts.Limit), len(partial.Items))
+
+			partial.Items = partial.Items[start:end]
+			partial.Continue = ""
+			if end < total {
+				partial.Continue = strconv.Itoa(end)
+			}
+
+			return nil
+		},
+	}).Build()
+
+	count, err := countObjects(context.Background(), c, kcpclient.LogicalClusterGVK)
+	if err != nil {
+		t.Fatalf("failed to count objects: %v", err)
+	}
+
+	if count != total {
+		t.Errorf("expected %d objects, got %d", total, count)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 pages to be listed, got %d", calls)
+	}
+}
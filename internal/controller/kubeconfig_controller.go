@@ -18,13 +18,48 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp-operator/internal/client"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/kubeconfig"
+)
+
+// kubeconfigFinalizer makes sure the ClusterRoleBindings created in kcp for a Kubeconfig are removed before
+// the Kubeconfig is deleted.
+const kubeconfigFinalizer = "operator.kcp.io/kubeconfig-permissions"
+
+// kubeconfigMirrorFinalizer makes sure the copies of a kubeconfig Secret in other namespaces, which cannot be
+// owned by the Kubeconfig, are removed before the Kubeconfig is deleted.
+const kubeconfigMirrorFinalizer = "operator.kcp.io/kubeconfig-mirror"
+
+const (
+	// kubeconfigCAValidity is the lifetime of the CA signing the client certificates of a Kubeconfig. The CA
+	// is only replaced when the Kubeconfig is revoked.
+	kubeconfigCAValidity = 10 * 365 * 24 * time.Hour
+	// maxKubeconfigRevocations is the number of revocations kept in the status of a Kubeconfig.
+	maxKubeconfigRevocations = 10
 )
 
 // KubeconfigReconciler reconciles a Kubeconfig object
@@ -33,30 +68,696 @@ type KubeconfigReconciler struct {
 	Scheme *runtime.Scheme
 }
 
+// kubeconfigError is returned if a Kubeconfig cannot be generated, because its target cannot be
+// resolved or its configuration is invalid. It carries the reason reported in the Ready condition.
+type kubeconfigError struct {
+	reason operatorkcpiov1alpha1.ConditionReason
+	err    error
+}
+
+func (e *kubeconfigError) Error() string {
+	return e.err.Error()
+}
+
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=kubeconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=kubeconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=kubeconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=rootshards;shards;frontproxies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=referencegrants,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Kubeconfig object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
 func (r *KubeconfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+	logger.V(4).Info("Reconciling Kubeconfig object")
+
+	var kc operatorkcpiov1alpha1.Kubeconfig
+	if err := r.Get(ctx, req.NamespacedName, &kc); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if kc.DeletionTimestamp != nil {
+		return ctrl.Result{}, r.reconcileDeletion(ctx, &kc)
+	}
+
+	oldKubeconfig := kc.DeepCopy()
+
+	if isPaused(&kc.Status.Conditions, &kc) {
+		if err := r.Status().Patch(ctx, &kc, client.MergeFrom(oldKubeconfig)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	var addedFinalizer bool
+	if kc.Spec.Permissions != nil && controllerutil.AddFinalizer(&kc, kubeconfigFinalizer) {
+		addedFinalizer = true
+	}
+	if kc.Spec.Mirror != nil && controllerutil.AddFinalizer(&kc, kubeconfigMirrorFinalizer) {
+		addedFinalizer = true
+	}
+	if addedFinalizer {
+		if err := r.Update(ctx, &kc); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add finalizer: %w", err)
+		}
+	}
+
+	var (
+		result ctrl.Result
+		err    error
+	)
+	switch {
+	case kc.Spec.Revoked:
+		err = r.revoke(ctx, &kc)
+	default:
+		result, err = r.reconcileKubeconfig(ctx, &kc)
+
+		var targetErr *kubeconfigError
+		if errors.As(err, &targetErr) {
+			setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypeKubeconfigReady, metav1.ConditionFalse, targetErr.reason, targetErr.Error())
+			err = nil
+		}
+	}
+
+	// the status is updated even if reconciling failed, so that neither bindings created in kcp nor
+	// revocations are forgotten
+	if err := r.Status().Patch(ctx, &kc, client.MergeFrom(oldKubeconfig)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	return result, err
+}
+
+// reconcileKubeconfig writes the kubeconfig to the Kubeconfig's Secret if it is missing, outdated or its client
+// certificate is due for renewal, and requeues for the next renewal.
+func (r *KubeconfigReconciler) reconcileKubeconfig(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig) (ctrl.Result, error) {
+	layout, err := kubeconfig.GetLayout(kc)
+	if err != nil {
+		return ctrl.Result{}, &kubeconfigError{reason: operatorkcpiov1alpha1.ConditionReasonInvalidOutput, err: err}
+	}
+
+	rootShard, baseURL, internalURL, err := resolveKubeconfigTarget(ctx, r.Client, kc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	rootCA, err := kcpclient.GetCA(ctx, r.Client, rootShard)
+	if err != nil {
+		if errors.Is(err, kcpclient.ErrNoCA) {
+			return ctrl.Result{}, &kubeconfigError{reason: operatorkcpiov1alpha1.ConditionReasonCANotConfigured, err: err}
+		}
+		return ctrl.Result{}, err
+	}
+
+	ca, err := r.reconcileCA(ctx, kc, rootShard)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	data, err := r.getSecretData(ctx, kc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if data == nil || kubeconfig.NeedsUpdate(data, kc, layout, baseURL, rootCA.CertPEM, ca.Certificate(), time.Now()) {
+		certPEM, keyPEM, err := ca.IssueClientCertificate(kc.Spec.Username, kc.Spec.Groups, kc.Spec.Validity.Duration)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to issue client certificate: %w", err)
+		}
+
+		data, err = kubeconfig.NewSecretData(kc, layout, baseURL, kubeconfig.Credentials{CertPEM: certPEM, KeyPEM: keyPEM, CAPEM: rootCA.CertPEM})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// the whole data is replaced, so that keys of a previous layout do not linger
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kc.Spec.SecretRef.Name, Namespace: kc.Namespace}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+			secret.Data = data
+			return controllerutil.SetControllerReference(kc, secret, r.Scheme)
+		}); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reconcile Secret: %w", err)
+		}
+
+		kc.Status.LastRenewalTime = ptr.To(metav1.Now())
+	}
+
+	cert, err := kubeconfig.ClientCertificate(layout, data)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	serverURL, err := kubeconfig.GetServerURL(baseURL, kc.Spec.Target.ClusterPath)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	kc.Status.ServerURL = serverURL
+	kc.Status.Certificate = kubeconfig.GetCertificateStatus(kc, cert)
+
+	setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypeKubeconfigReady, metav1.ConditionTrue,
+		operatorkcpiov1alpha1.ConditionReasonKubeconfigWritten, fmt.Sprintf("kubeconfig has been written to Secret %s", kc.Spec.SecretRef.Name))
 
-	// TODO(user): your logic here
+	if err := r.reconcileMirror(ctx, kc, data); err != nil {
+		setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypeSecretMirrored, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonMirrorFailed, err.Error())
+		return ctrl.Result{}, err
+	}
 
-	return ctrl.Result{}, nil
+	if err := r.reconcilePermissions(ctx, kc, rootShard, internalURL); err != nil {
+		setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypePermissionsBound, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonBindingFailed, err.Error())
+		return ctrl.Result{}, err
+	}
+
+	// requeue exactly when the certificate is due; the threshold may just have passed since checking it, in
+	// which case a non-positive delay would not requeue at all
+	return ctrl.Result{RequeueAfter: max(time.Until(kc.Status.Certificate.RenewalTime.Time), time.Second)}, nil
+}
+
+// reconcileCA returns the CA signing the client certificates of the given Kubeconfig, generating it if it does
+// not exist yet. Its Secret is labelled, so that the shards of the kcp setup around rootShard trust the CA
+// for as long as the Secret exists.
+func (r *KubeconfigReconciler) reconcileCA(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig, rootShard *operatorkcpiov1alpha1.RootShard) (*kcpclient.CA, error) {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kubeconfig.GetCASecretName(kc), Namespace: kc.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		if len(secret.Data[corev1.TLSCertKey]) == 0 {
+			certPEM, keyPEM, err := kcpclient.GenerateCA(fmt.Sprintf("kubeconfig:%s:%s", kc.Namespace, kc.Name), kubeconfigCAValidity)
+			if err != nil {
+				return fmt.Errorf("failed to generate CA: %w", err)
+			}
+			secret.Data = map[string][]byte{
+				corev1.TLSCertKey:       certPEM,
+				corev1.TLSPrivateKeyKey: keyPEM,
+			}
+		}
+
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[resources.ClientCALabel] = "true"
+
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[resources.ClientCARootShardAnnotation] = rootShard.Namespace + "/" + rootShard.Name
+
+		return controllerutil.SetControllerReference(kc, secret, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile CA Secret: %w", err)
+	}
+
+	ca, err := kcpclient.NewCA(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA Secret %s: %w", secret.Name, err)
+	}
+
+	kc.Status.CASerialNumber = ca.Certificate().SerialNumber.Text(16)
+
+	return ca, nil
+}
+
+// revoke deletes the CA of the given Kubeconfig, so that kcp no longer trusts any client certificate it signed,
+// as well as the kubeconfig Secret, and records the revocation in the status.
+func (r *KubeconfigReconciler) revoke(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig) error {
+	var caSecret corev1.Secret
+	err := r.Get(ctx, client.ObjectKey{Namespace: kc.Namespace, Name: kubeconfig.GetCASecretName(kc)}, &caSecret)
+	switch {
+	case apierrors.IsNotFound(err):
+		// already revoked
+	case err != nil:
+		return fmt.Errorf("failed to get CA Secret: %w", err)
+	default:
+		serial := kc.Status.CASerialNumber
+		if ca, err := kcpclient.NewCA(caSecret.Data[corev1.TLSCertKey], caSecret.Data[corev1.TLSPrivateKeyKey]); err == nil {
+			serial = ca.Certificate().SerialNumber.Text(16)
+		}
+
+		if err := r.Delete(ctx, &caSecret); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete CA Secret: %w", err)
+		}
+
+		kc.Status.Revocations = append(kc.Status.Revocations, operatorkcpiov1alpha1.KubeconfigRevocation{
+			Time:           metav1.Now(),
+			CASerialNumber: serial,
+		})
+		if len(kc.Status.Revocations) > maxKubeconfigRevocations {
+			kc.Status.Revocations = kc.Status.Revocations[len(kc.Status.Revocations)-maxKubeconfigRevocations:]
+		}
+	}
+
+	kc.Status.CASerialNumber = ""
+	kc.Status.Certificate = nil
+
+	if err := r.deleteMirroredSecrets(ctx, kc, nil); err != nil {
+		return err
+	}
+	kc.Status.MirroredNamespaces = nil
+	meta.RemoveStatusCondition(&kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeSecretMirrored))
+
+	var secret corev1.Secret
+	err = r.Get(ctx, client.ObjectKey{Namespace: kc.Namespace, Name: kc.Spec.SecretRef.Name}, &secret)
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return fmt.Errorf("failed to get Secret: %w", err)
+	case metav1.IsControlledBy(&secret, kc):
+		if err := r.Delete(ctx, &secret); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Secret: %w", err)
+		}
+	}
+
+	setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypeKubeconfigReady, metav1.ConditionFalse,
+		operatorkcpiov1alpha1.ConditionReasonRevoked, "the kubeconfig has been revoked")
+
+	return nil
+}
+
+// reconcilePermissions creates a ClusterRoleBinding for each ClusterRole in spec.permissions in the configured
+// workspace, and removes bindings that are no longer configured, including all bindings in a previously
+// configured workspace. kcp is reached through the in-cluster URL of the kubeconfig's target.
+func (r *KubeconfigReconciler) reconcilePermissions(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig, rootShard *operatorkcpiov1alpha1.RootShard, internalURL string) error {
+	var clusterPath string
+	if kc.Spec.Permissions != nil {
+		clusterPath = kubeconfig.GetPermissionsClusterPath(kc)
+	}
+
+	if kc.Status.BoundClusterPath != "" && kc.Status.BoundClusterPath != clusterPath {
+		if err := r.deleteClusterRoleBindings(ctx, kc, rootShard, internalURL, kc.Status.BoundClusterPath, nil); err != nil {
+			return err
+		}
+		kc.Status.BoundClusterPath = ""
+	}
+
+	if kc.Spec.Permissions == nil {
+		meta.RemoveStatusCondition(&kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypePermissionsBound))
+		return nil
+	}
+
+	kcpClient, err := kcpclient.NewShardClient(ctx, r.Client, rootShard, internalURL, clusterPath, r.Scheme)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	// record the workspace before creating anything, so that bindings are cleaned up even if creating some fails
+	kc.Status.BoundClusterPath = clusterPath
+
+	desired := sets.New[string]()
+	for _, clusterRole := range kc.Spec.Permissions.ClusterRoles {
+		crb := &rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: kubeconfig.GetClusterRoleBindingName(kc, clusterRole)}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, kcpClient, crb, func() error {
+			kubeconfig.MutateClusterRoleBinding(crb, kc, clusterRole)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile ClusterRoleBinding %s in %s: %w", crb.Name, clusterPath, err)
+		}
+		desired.Insert(crb.Name)
+	}
+
+	if err := r.deleteClusterRoleBindings(ctx, kc, rootShard, internalURL, clusterPath, desired); err != nil {
+		return err
+	}
+
+	setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypePermissionsBound, metav1.ConditionTrue,
+		operatorkcpiov1alpha1.ConditionReasonClusterRolesBound, fmt.Sprintf("ClusterRoles %s are bound in %s", strings.Join(kc.Spec.Permissions.ClusterRoles, ", "), clusterPath))
+
+	return nil
+}
+
+// deleteClusterRoleBindings deletes all ClusterRoleBindings of the given Kubeconfig in the workspace at
+// clusterPath, except for those named in keep. A workspace that no longer exists has nothing to clean up.
+func (r *KubeconfigReconciler) deleteClusterRoleBindings(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig, rootShard *operatorkcpiov1alpha1.RootShard, internalURL, clusterPath string, keep sets.Set[string]) error {
+	kcpClient, err := kcpclient.NewShardClient(ctx, r.Client, rootShard, internalURL, clusterPath, r.Scheme)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	var bindings rbacv1.ClusterRoleBindingList
+	if err := kcpClient.List(ctx, &bindings, client.MatchingLabels{kubeconfig.KubeconfigUIDLabel: string(kc.UID)}); err != nil {
+		// kcp rejects requests to logical clusters that do not exist, so the bindings are gone with the workspace
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			log.FromContext(ctx).Info("Workspace is not accessible, assuming it has been deleted along with its ClusterRoleBindings", "workspace", clusterPath, "error", err)
+			return nil
+		}
+		return fmt.Errorf("failed to list ClusterRoleBindings in %s: %w", clusterPath, err)
+	}
+
+	for _, crb := range bindings.Items {
+		if keep.Has(crb.Name) {
+			continue
+		}
+		if err := kcpClient.Delete(ctx, &crb); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete ClusterRoleBinding %s in %s: %w", crb.Name, clusterPath, err)
+		}
+	}
+
+	return nil
+}
+
+// reconcileMirror copies the kubeconfig Secret data into all namespaces selected by spec.mirror and removes
+// copies from namespaces that are no longer selected. Namespaces holding an unrelated Secret of the same name
+// are reported, but do not prevent copying into the others. Like cross-namespace references to a RootShard,
+// copying requires consent by a ReferenceGrant in the destination namespace; namespaces without one are
+// reported and skipped.
+func (r *KubeconfigReconciler) reconcileMirror(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig, data map[string][]byte) error {
+	if kc.Spec.Mirror == nil {
+		if err := r.deleteMirroredSecrets(ctx, kc, nil); err != nil {
+			return err
+		}
+		kc.Status.MirroredNamespaces = nil
+		meta.RemoveStatusCondition(&kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeSecretMirrored))
+		return nil
+	}
+
+	namespaces, err := r.getMirrorNamespaces(ctx, kc)
+	if err != nil {
+		return err
+	}
+
+	var (
+		errs         []error
+		notPermitted []string
+	)
+	mirrored := sets.New[string]()
+	for _, namespace := range sets.List(namespaces) {
+		permitted, err := reference.IsPermitted(ctx, r.Client, reference.KindKubeconfig, kc.Namespace, reference.KindSecret, namespace, kc.Spec.SecretRef.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !permitted {
+			notPermitted = append(notPermitted, namespace)
+			namespaces.Delete(namespace)
+			continue
+		}
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kc.Spec.SecretRef.Name, Namespace: namespace}}
+		if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+			if secret.ResourceVersion != "" && secret.Labels[kubeconfig.KubeconfigUIDLabel] != string(kc.UID) {
+				return errors.New("an unrelated Secret of the same name exists")
+			}
+			kubeconfig.MutateMirroredSecret(secret, kc, data)
+			return nil
+		}); err != nil {
+			// listed namespaces that do not exist yet are picked up once they are created
+			if !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to copy Secret into namespace %s: %w", namespace, err))
+			}
+			continue
+		}
+		mirrored.Insert(namespace)
+	}
+
+	if err := r.deleteMirroredSecrets(ctx, kc, namespaces); err != nil {
+		errs = append(errs, err)
+	}
+
+	kc.Status.MirroredNamespaces = sets.List(mirrored)
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if len(notPermitted) > 0 {
+		setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypeSecretMirrored, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonRefNotPermitted, fmt.Sprintf("Secret %s has been copied into %d namespace(s), no ReferenceGrant permits copying it into %s",
+				kc.Spec.SecretRef.Name, mirrored.Len(), strings.Join(notPermitted, ", ")))
+		return nil
+	}
+
+	setCondition(&kc.Status.Conditions, kc.Generation, operatorkcpiov1alpha1.ConditionTypeSecretMirrored, metav1.ConditionTrue,
+		operatorkcpiov1alpha1.ConditionReasonSecretCopied, fmt.Sprintf("Secret %s has been copied into %d namespace(s)", kc.Spec.SecretRef.Name, mirrored.Len()))
+
+	return nil
+}
+
+// getMirrorNamespaces returns the namespaces selected by spec.mirror, except for the Kubeconfig's own and
+// terminating namespaces.
+func (r *KubeconfigReconciler) getMirrorNamespaces(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig) (sets.Set[string], error) {
+	namespaces := sets.New(kc.Spec.Mirror.Namespaces...)
+
+	if kc.Spec.Mirror.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(kc.Spec.Mirror.NamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector: %w", err)
+		}
+
+		var namespaceList corev1.NamespaceList
+		if err := r.List(ctx, &namespaceList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
+		}
+
+		for _, ns := range namespaceList.Items {
+			if ns.Status.Phase != corev1.NamespaceTerminating {
+				namespaces.Insert(ns.Name)
+			}
+		}
+	}
+
+	namespaces.Delete(kc.Namespace)
+
+	return namespaces, nil
+}
+
+// deleteMirroredSecrets deletes all copies of the given Kubeconfig's Secret, except for those in the
+// namespaces in keep.
+func (r *KubeconfigReconciler) deleteMirroredSecrets(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig, keep sets.Set[string]) error {
+	var secrets corev1.SecretList
+	if err := r.List(ctx, &secrets, client.MatchingLabels{kubeconfig.KubeconfigUIDLabel: string(kc.UID)}); err != nil {
+		return fmt.Errorf("failed to list copies of Secret: %w", err)
+	}
+
+	for _, secret := range secrets.Items {
+		if secret.Namespace == kc.Namespace || keep.Has(secret.Namespace) {
+			continue
+		}
+		if err := r.Delete(ctx, &secret); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete copy of Secret in namespace %s: %w", secret.Namespace, err)
+		}
+	}
+
+	return nil
+}
+
+// reconcileDeletion removes the copies of the kubeconfig Secret and the ClusterRoleBindings created in kcp
+// before releasing the respective finalizer. If the kcp setup is gone, there is nothing left to clean up.
+func (r *KubeconfigReconciler) reconcileDeletion(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig) error {
+	if controllerutil.ContainsFinalizer(kc, kubeconfigMirrorFinalizer) {
+		if err := r.deleteMirroredSecrets(ctx, kc, nil); err != nil {
+			return err
+		}
+
+		controllerutil.RemoveFinalizer(kc, kubeconfigMirrorFinalizer)
+		if err := r.Update(ctx, kc); err != nil {
+			return fmt.Errorf("failed to remove finalizer: %w", err)
+		}
+	}
+
+	if !controllerutil.ContainsFinalizer(kc, kubeconfigFinalizer) {
+		return nil
+	}
+
+	if kc.Status.BoundClusterPath != "" {
+		rootShard, _, internalURL, err := resolveKubeconfigTarget(ctx, r.Client, kc)
+
+		var targetErr *kubeconfigError
+		switch {
+		case errors.As(err, &targetErr):
+			log.FromContext(ctx).Info("Target is not available, skipping removal of ClusterRoleBindings", "error", err)
+		case err != nil:
+			return err
+		default:
+			err := r.deleteClusterRoleBindings(ctx, kc, rootShard, internalURL, kc.Status.BoundClusterPath, nil)
+			if errors.Is(err, kcpclient.ErrNoCA) {
+				log.FromContext(ctx).Info("RootShard has no CA configured, cannot remove ClusterRoleBindings from kcp")
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	controllerutil.RemoveFinalizer(kc, kubeconfigFinalizer)
+	if err := r.Update(ctx, kc); err != nil {
+		return fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// getSecretData returns the data of the Kubeconfig's Secret, or nil if it does not exist.
+func (r *KubeconfigReconciler) getSecretData(ctx context.Context, kc *operatorkcpiov1alpha1.Kubeconfig) (map[string][]byte, error) {
+	var secret corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: kc.Namespace, Name: kc.Spec.SecretRef.Name}, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Secret: %w", err)
+	}
+
+	return secret.Data, nil
+}
+
+// resolveKubeconfigTarget returns the RootShard whose CA signs the client certificate of the given Kubeconfig, the
+// base URL of its target to be used in the kubeconfig and the URL the operator reaches the target at from within the
+// cluster. RootShards and Shards are addressed via their internal URL, front-proxies via the external URL matching
+// how they are exposed.
+func resolveKubeconfigTarget(ctx context.Context, c client.Client, kc *operatorkcpiov1alpha1.Kubeconfig) (*operatorkcpiov1alpha1.RootShard, string, string, error) {
+	target := kc.Spec.Target
+
+	switch {
+	case target.RootShardRef != nil:
+		var rootShard operatorkcpiov1alpha1.RootShard
+		if err := c.Get(ctx, client.ObjectKey{Namespace: kc.Namespace, Name: target.RootShardRef.Name}, &rootShard); err != nil {
+			return nil, "", "", targetNotFound(err, reference.KindRootShard, target.RootShardRef.Name)
+		}
+		internalURL := resources.GetRootShardURLs(&rootShard).Internal
+		return &rootShard, internalURL, internalURL, nil
+
+	case target.ShardRef != nil:
+		var shard operatorkcpiov1alpha1.Shard
+		if err := c.Get(ctx, client.ObjectKey{Namespace: kc.Namespace, Name: target.ShardRef.Name}, &shard); err != nil {
+			return nil, "", "", targetNotFound(err, reference.KindShard, target.ShardRef.Name)
+		}
+
+		rootShard, err := getRootShard(ctx, c, reference.KindShard, shard.Namespace, shard.Spec.RootShard)
+		if err != nil {
+			return nil, "", "", rootShardNotFound(err)
+		}
+		internalURL := resources.GetShardURLs(&shard, rootShard).Internal
+		return rootShard, internalURL, internalURL, nil
+
+	case target.FrontProxyRef != nil:
+		var frontProxy operatorkcpiov1alpha1.FrontProxy
+		if err := c.Get(ctx, client.ObjectKey{Namespace: kc.Namespace, Name: target.FrontProxyRef.Name}, &frontProxy); err != nil {
+			return nil, "", "", targetNotFound(err, reference.KindFrontProxy, target.FrontProxyRef.Name)
+		}
+
+		rootShard, err := getRootShard(ctx, c, reference.KindFrontProxy, frontProxy.Namespace, frontProxy.Spec.RootShard)
+		if err != nil {
+			return nil, "", "", rootShardNotFound(err)
+		}
+		return rootShard, resources.GetFrontProxyExternalURL(&frontProxy, rootShard), resources.GetFrontProxyServiceURL(&frontProxy), nil
+	}
+
+	return nil, "", "", &kubeconfigError{reason: operatorkcpiov1alpha1.ConditionReasonTargetNotFound, err: errors.New("no target configured")}
+}
+
+func targetNotFound(err error, kind, name string) error {
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return &kubeconfigError{reason: operatorkcpiov1alpha1.ConditionReasonTargetNotFound, err: fmt.Errorf("%s %s not found", kind, name)}
+}
+
+func rootShardNotFound(err error) error {
+	switch {
+	case errors.Is(err, reference.ErrNotPermitted):
+		return &kubeconfigError{reason: operatorkcpiov1alpha1.ConditionReasonRefNotPermitted, err: err}
+	case apierrors.IsNotFound(err):
+		return &kubeconfigError{reason: operatorkcpiov1alpha1.ConditionReasonRootShardNotFound, err: err}
+	default:
+		return err
+	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *KubeconfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorkcpiov1alpha1.Kubeconfig{}).
+		Owns(&corev1.Secret{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(kubeconfigForMirroredSecret)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.kubeconfigsForNamespace)).
+		Watches(&operatorkcpiov1alpha1.ReferenceGrant{}, handler.EnqueueRequestsFromMapFunc(r.kubeconfigsForReferenceGrant)).
+		Watches(&operatorkcpiov1alpha1.RootShard{}, handler.EnqueueRequestsFromMapFunc(r.kubeconfigsForTarget(func(t operatorkcpiov1alpha1.KubeconfigTarget) *corev1.LocalObjectReference { return t.RootShardRef }))).
+		Watches(&operatorkcpiov1alpha1.Shard{}, handler.EnqueueRequestsFromMapFunc(r.kubeconfigsForTarget(func(t operatorkcpiov1alpha1.KubeconfigTarget) *corev1.LocalObjectReference { return t.ShardRef }))).
+		Watches(&operatorkcpiov1alpha1.FrontProxy{}, handler.EnqueueRequestsFromMapFunc(r.kubeconfigsForTarget(func(t operatorkcpiov1alpha1.KubeconfigTarget) *corev1.LocalObjectReference { return t.FrontProxyRef }))).
 		Complete(r)
 }
+
+// kubeconfigsForTarget returns a map function enqueueing all Kubeconfigs whose target, as returned by ref,
+// is the given object, so that changes to its URLs are propagated.
+func (r *KubeconfigReconciler) kubeconfigsForTarget(ref func(operatorkcpiov1alpha1.KubeconfigTarget) *corev1.LocalObjectReference) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var kubeconfigs operatorkcpiov1alpha1.KubeconfigList
+		if err := r.List(ctx, &kubeconfigs, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list Kubeconfigs")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, kc := range kubeconfigs.Items {
+			if r := ref(kc.Spec.Target); r != nil && r.Name == obj.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&kc)})
+			}
+		}
+
+		return requests
+	}
+}
+
+// kubeconfigForMirroredSecret enqueues the Kubeconfig that the given Secret is a copy of, so that modified or
+// deleted copies are restored.
+func kubeconfigForMirroredSecret(_ context.Context, obj client.Object) []reconcile.Request {
+	owner, ok := kubeconfig.GetMirroredKubeconfig(obj)
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: owner}}
+}
+
+// kubeconfigsForReferenceGrant enqueues all Kubeconfigs mirroring their Secret from a namespace listed in the
+// given ReferenceGrant, so that granting or revoking consent takes effect immediately.
+func (r *KubeconfigReconciler) kubeconfigsForReferenceGrant(ctx context.Context, obj client.Object) []reconcile.Request {
+	grant, ok := obj.(*operatorkcpiov1alpha1.ReferenceGrant)
+	if !ok {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, from := range grant.Spec.From {
+		if from.Kind != reference.KindKubeconfig {
+			continue
+		}
+
+		var kubeconfigs operatorkcpiov1alpha1.KubeconfigList
+		if err := r.List(ctx, &kubeconfigs, client.InNamespace(from.Namespace)); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list Kubeconfigs")
+			return nil
+		}
+
+		for _, kc := range kubeconfigs.Items {
+			if kc.Spec.Mirror != nil {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&kc)})
+			}
+		}
+	}
+
+	return requests
+}
+
+// kubeconfigsForNamespace enqueues all Kubeconfigs mirroring their Secret, so that created namespaces and
+// changed namespace labels are taken into account.
+func (r *KubeconfigReconciler) kubeconfigsForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	var kubeconfigs operatorkcpiov1alpha1.KubeconfigList
+	if err := r.List(ctx, &kubeconfigs); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Kubeconfigs")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, kc := range kubeconfigs.Items {
+		if kc.Spec.Mirror != nil && (kc.Spec.Mirror.NamespaceSelector != nil || slices.Contains(kc.Spec.Mirror.Namespaces, obj.GetName())) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&kc)})
+		}
+	}
+
+	return requests
+}
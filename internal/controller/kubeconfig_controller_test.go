@@ -23,12 +23,18 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp-operator/internal/client"
+	"github.com/kcp-dev/kcp-operator/internal/resources/kubeconfig"
 )
 
 var _ = Describe("Kubeconfig Controller", func() {
@@ -39,13 +45,27 @@ var _ = Describe("Kubeconfig Controller", func() {
 
 		typeNamespacedName := types.NamespacedName{
 			Name:      resourceName,
-			Namespace: "default", // TODO(user):Modify as needed
+			Namespace: "default",
+		}
+
+		rootShard := &operatorkcpiov1alpha1.RootShard{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kubeconfig-test-root",
+				Namespace: "default",
+			},
+			Spec: operatorkcpiov1alpha1.RootShardSpec{
+				Hostname: "example.kcp.io",
+				CommonShardSpec: operatorkcpiov1alpha1.CommonShardSpec{
+					Etcd: operatorkcpiov1alpha1.EtcdConfig{
+						Endpoints: []string{"https://localhost:2379"},
+					},
+				},
+			},
 		}
-		kubeconfig := &operatorkcpiov1alpha1.Kubeconfig{}
 
 		BeforeEach(func() {
 			By("creating the custom resource for the Kind Kubeconfig")
-			err := k8sClient.Get(ctx, typeNamespacedName, kubeconfig)
+			err := k8sClient.Get(ctx, typeNamespacedName, &operatorkcpiov1alpha1.Kubeconfig{})
 			if err != nil && errors.IsNotFound(err) {
 				resource := &operatorkcpiov1alpha1.Kubeconfig{
 					ObjectMeta: metav1.ObjectMeta{
@@ -53,7 +73,16 @@ var _ = Describe("Kubeconfig Controller", func() {
 						Namespace: "default",
 					},
 					Spec: operatorkcpiov1alpha1.KubeconfigSpec{
-						Validity: metav1.Duration{Duration: 24 * time.Hour},
+						Target: operatorkcpiov1alpha1.KubeconfigTarget{
+							RootShardRef: &corev1.LocalObjectReference{Name: rootShard.Name},
+							ClusterPath:  "root:org:team",
+						},
+						Username:  "alice",
+						Validity:  metav1.Duration{Duration: 24 * time.Hour},
+						SecretRef: corev1.LocalObjectReference{Name: "alice-kubeconfig"},
+						Contexts: []operatorkcpiov1alpha1.KubeconfigContext{
+							{Name: "root", ClusterPath: "root"},
+						},
 					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
@@ -61,7 +90,6 @@ var _ = Describe("Kubeconfig Controller", func() {
 		})
 
 		AfterEach(func() {
-			// TODO(user): Cleanup logic after each test, like removing the resource instance.
 			resource := &operatorkcpiov1alpha1.Kubeconfig{}
 			err := k8sClient.Get(ctx, typeNamespacedName, resource)
 			Expect(err).NotTo(HaveOccurred())
@@ -69,8 +97,30 @@ var _ = Describe("Kubeconfig Controller", func() {
 			By("Cleanup the specific resource instance Kubeconfig")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
 		})
-		It("should successfully reconcile the resource", func() {
-			By("Reconciling the created resource")
+
+		It("should report a missing target", func() {
+			controllerReconciler := &KubeconfigReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			kc := &operatorkcpiov1alpha1.Kubeconfig{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kc)).To(Succeed())
+			cond := meta.FindStatusCondition(kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeKubeconfigReady))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonTargetNotFound)))
+		})
+
+		It("should report a RootShard without CA", func() {
+			By("creating the target RootShard")
+			Expect(k8sClient.Create(ctx, rootShard.DeepCopy())).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, rootShard.DeepCopy())
+
 			controllerReconciler := &KubeconfigReconciler{
 				Client: k8sClient,
 				Scheme: k8sClient.Scheme(),
@@ -80,8 +130,205 @@ var _ = Describe("Kubeconfig Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			kc := &operatorkcpiov1alpha1.Kubeconfig{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kc)).To(Succeed())
+			cond := meta.FindStatusCondition(kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeKubeconfigReady))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonCANotConfigured)))
+		})
+
+		It("should revoke the kubeconfig", func() {
+			kc := &operatorkcpiov1alpha1.Kubeconfig{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kc)).To(Succeed())
+
+			By("creating the CA and kubeconfig Secrets")
+			certPEM, keyPEM, err := kcpclient.GenerateCA("test", time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+
+			caSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: kubeconfig.GetCASecretName(kc), Namespace: kc.Namespace},
+				Data:       map[string][]byte{corev1.TLSCertKey: certPEM, corev1.TLSPrivateKeyKey: keyPEM},
+			}
+			Expect(k8sClient.Create(ctx, caSecret)).To(Succeed())
+
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kc.Spec.SecretRef.Name, Namespace: kc.Namespace}}
+			Expect(controllerutil.SetControllerReference(kc, secret, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			By("revoking the Kubeconfig")
+			kc.Spec.Revoked = true
+			Expect(k8sClient.Update(ctx, kc)).To(Succeed())
+
+			controllerReconciler := &KubeconfigReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(caSecret), &corev1.Secret{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), &corev1.Secret{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kc)).To(Succeed())
+			Expect(kc.Status.Revocations).To(HaveLen(1))
+			Expect(kc.Status.CASerialNumber).To(BeEmpty())
+			cond := meta.FindStatusCondition(kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeKubeconfigReady))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonRevoked)))
+		})
+
+		It("should release the finalizer of a Kubeconfig with permissions if the target is gone", func() {
+			key := types.NamespacedName{Name: "test-permissions", Namespace: "default"}
+			resource := &operatorkcpiov1alpha1.Kubeconfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+					Target: operatorkcpiov1alpha1.KubeconfigTarget{
+						RootShardRef: &corev1.LocalObjectReference{Name: rootShard.Name},
+					},
+					Username:  "bob",
+					Validity:  metav1.Duration{Duration: 24 * time.Hour},
+					SecretRef: corev1.LocalObjectReference{Name: "bob-kubeconfig"},
+					Permissions: &operatorkcpiov1alpha1.KubeconfigPermissions{
+						ClusterRoles: []string{"cluster-admin"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &KubeconfigReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			kc := &operatorkcpiov1alpha1.Kubeconfig{}
+			Expect(k8sClient.Get(ctx, key, kc)).To(Succeed())
+			Expect(kc.Finalizers).To(ContainElement(kubeconfigFinalizer))
+
+			By("recording bindings in a workspace and deleting the Kubeconfig")
+			kc.Status.BoundClusterPath = "root"
+			Expect(k8sClient.Status().Update(ctx, kc)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, kc)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, key, &operatorkcpiov1alpha1.Kubeconfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should remove copies of the Secret when the Kubeconfig is deleted", func() {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig-mirror"}}
+			Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+			key := types.NamespacedName{Name: "test-mirror", Namespace: "default"}
+			resource := &operatorkcpiov1alpha1.Kubeconfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+					Target: operatorkcpiov1alpha1.KubeconfigTarget{
+						RootShardRef: &corev1.LocalObjectReference{Name: rootShard.Name},
+					},
+					Username:  "carol",
+					Validity:  metav1.Duration{Duration: 24 * time.Hour},
+					SecretRef: corev1.LocalObjectReference{Name: "carol-kubeconfig"},
+					Mirror: &operatorkcpiov1alpha1.KubeconfigMirror{
+						Namespaces: []string{namespace.Name},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+
+			controllerReconciler := &KubeconfigReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			kc := &operatorkcpiov1alpha1.Kubeconfig{}
+			Expect(k8sClient.Get(ctx, key, kc)).To(Succeed())
+			Expect(kc.Finalizers).To(ContainElement(kubeconfigMirrorFinalizer))
+
+			By("creating a copy of the Secret and deleting the Kubeconfig")
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kc.Spec.SecretRef.Name, Namespace: namespace.Name}}
+			kubeconfig.MutateMirroredSecret(secret, kc, map[string][]byte{kubeconfig.DefaultKubeconfigKey: []byte("config")})
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, kc)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), &corev1.Secret{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, key, &operatorkcpiov1alpha1.Kubeconfig{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should only copy the Secret into namespaces permitting it", func() {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig-mirror-consent"}}
+			Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+			kc := &operatorkcpiov1alpha1.Kubeconfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-mirror-consent",
+					Namespace: "default",
+					UID:       "mirror-consent",
+				},
+				Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+					SecretRef: corev1.LocalObjectReference{Name: "dave-kubeconfig"},
+					Mirror: &operatorkcpiov1alpha1.KubeconfigMirror{
+						Namespaces: []string{namespace.Name},
+					},
+				},
+			}
+			data := map[string][]byte{kubeconfig.DefaultKubeconfigKey: []byte("config")}
+
+			controllerReconciler := &KubeconfigReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			copyKey := types.NamespacedName{Name: kc.Spec.SecretRef.Name, Namespace: namespace.Name}
+
+			By("mirroring without a ReferenceGrant")
+			Expect(controllerReconciler.reconcileMirror(ctx, kc, data)).To(Succeed())
+
+			err := k8sClient.Get(ctx, copyKey, &corev1.Secret{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			cond := meta.FindStatusCondition(kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeSecretMirrored))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonRefNotPermitted)))
+
+			By("mirroring with a ReferenceGrant")
+			grant := &operatorkcpiov1alpha1.ReferenceGrant{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubeconfigs", Namespace: namespace.Name},
+				Spec: operatorkcpiov1alpha1.ReferenceGrantSpec{
+					From: []operatorkcpiov1alpha1.ReferenceGrantFrom{{Kind: "Kubeconfig", Namespace: kc.Namespace}},
+					To:   []operatorkcpiov1alpha1.ReferenceGrantTo{{Kind: "Secret", Name: kc.Spec.SecretRef.Name}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, grant)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, grant)
+
+			Expect(controllerReconciler.reconcileMirror(ctx, kc, data)).To(Succeed())
+
+			Expect(k8sClient.Get(ctx, copyKey, &corev1.Secret{})).To(Succeed())
+			Expect(kc.Status.MirroredNamespaces).To(ConsistOf(namespace.Name))
+			Expect(meta.IsStatusConditionTrue(kc.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeSecretMirrored))).To(BeTrue())
 		})
 	})
 })
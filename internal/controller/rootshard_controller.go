@@ -17,46 +17,451 @@ limitations under the License.
 package controller
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp-operator/internal/client"
+	"github.com/kcp-dev/kcp-operator/internal/etcd"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/registry"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/certificates"
+	"github.com/kcp-dev/kcp-operator/internal/resources/kubeconfig"
+	"github.com/kcp-dev/kcp-operator/internal/resources/rootshard"
+	"github.com/kcp-dev/kcp-operator/internal/resources/virtualworkspaces"
 )
 
 // RootShardReconciler reconciles a RootShard object
 type RootShardReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// ImageResolver resolves image tags to digests if digest resolution is enabled. Defaults to
+	// querying the image registry.
+	ImageResolver registry.Resolver
+	// EtcdHealthChecker checks the configured etcd endpoints before rolling out. Defaults to querying
+	// etcd's health endpoint.
+	EtcdHealthChecker etcd.HealthChecker
 }
 
-// +kubebuilder:rbac:groups=operator.kcp.io,resources=kcpinstances,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=operator.kcp.io,resources=kcpinstances/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=operator.kcp.io,resources=kcpinstances/finalizers,verbs=update
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=rootshards,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=rootshards/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=rootshards/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=shards;frontproxies;cacheservers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates;issuers,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the RootShard object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
 func (r *RootShardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+	logger.V(4).Info("Reconciling RootShard object")
 
-	// TODO(user): your logic here
+	var rootShard operatorkcpiov1alpha1.RootShard
+	if err := r.Get(ctx, req.NamespacedName, &rootShard); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
 
-	return ctrl.Result{}, nil
+	if rootShard.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	oldRootShard := rootShard.DeepCopy()
+
+	if isPaused(&rootShard.Status.Conditions, &rootShard) {
+		logger.V(4).Info("Reconciliation is paused, only updating status")
+
+		dep, err := getDeployment(ctx, r.Client, client.ObjectKey{Namespace: rootShard.Namespace, Name: resources.GetRootShardDeploymentName(&rootShard)})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if dep != nil {
+			rootShard.Status.ReadyReplicas = dep.Status.ReadyReplicas
+			setAvailableCondition(&rootShard.Status.Conditions, rootShard.Generation, dep)
+		}
+
+		if err := r.Status().Patch(ctx, &rootShard, client.MergeFrom(oldRootShard)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	desiredVersion := resources.GetEffectiveVersion(rootShard.Spec.Image, resources.GetDesiredVersion(&rootShard))
+	if !checkVersionCompatibility(&rootShard.Status.Conditions, rootShard.Generation, reference.KindRootShard, desiredVersion, "") {
+		if err := r.Status().Patch(ctx, &rootShard, client.MergeFrom(oldRootShard)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	upgrading, err := reconcileUpgrade(ctx, r.Client, &rootShard)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile upgrade: %w", err)
+	}
+
+	resolvedImage, err := resolveImageDigest(ctx, r.Client, r.ImageResolver, rootShard.Namespace, rootShard.Generation, &rootShard.Status.Conditions, rootShard.Spec.Image, resources.GetKCPVersion(&rootShard, operatorkcpiov1alpha1.UpgradePhaseRootShard), rootShard.Status.ResolvedImage)
+	rootShard.Status.ResolvedImage = resolvedImage
+	if err != nil {
+		if patchErr := r.Status().Patch(ctx, &rootShard, client.MergeFrom(oldRootShard)); patchErr != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", patchErr)
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	etcdCertHash, etcdCertRecheck, err := reconcileEtcdCertificate(ctx, r.Client, r.Scheme, &rootShard, &rootShard.Status.Conditions, rootShard.Spec.Etcd, resources.GetRootShardResourceLabels(&rootShard))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reachable, err := checkEtcdHealth(ctx, r.Client, r.EtcdHealthChecker, rootShard.Namespace, rootShard.Generation, &rootShard.Status.Conditions, rootShard.Spec.Etcd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !reachable {
+		if err := r.Status().Patch(ctx, &rootShard, client.MergeFrom(oldRootShard)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{RequeueAfter: etcdUnreachableRetryInterval}, nil
+	}
+
+	externalPort, err := r.getExternalPort(ctx, &rootShard)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	rootShard.Status.ExternalPort = externalPort
+
+	dep, err := r.reconcileWorkloads(ctx, &rootShard, etcdCertHash)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	rootShard.Status.Replicas = resources.GetReplicas(&rootShard.Spec.CommonShardSpec)
+	if err := reconcileVirtualWorkspaces(ctx, r.Client, r.Scheme, &rootShard, &rootShard.Status.Conditions, &rootShard.Spec.CommonShardSpec, &rootShard, virtualworkspaces.Options{
+		ShardDeploymentName: resources.GetRootShardDeploymentName(&rootShard),
+		Namespace:           rootShard.Namespace,
+		ShardURL:            resources.GetRootShardURLs(&rootShard).Internal,
+		Image:               rootShard.Spec.Image,
+		Version:             resources.GetKCPVersion(&rootShard, operatorkcpiov1alpha1.UpgradePhaseRootShard),
+		ResolvedImage:       rootShard.Status.ResolvedImage,
+		IssuerName:          getCAIssuerName(&rootShard),
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	adminRenewal, err := r.reconcileAdminKubeconfig(ctx, &rootShard)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcileBreakGlass(ctx, &rootShard); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	collectInventory := rootShard.Spec.CARef != nil && dep.Status.ReadyReplicas > 0
+	if collectInventory && isInventoryDue(rootShard.Status.Inventory) {
+		inventory, err := getShardInventory(ctx, r.Client, r.Scheme, &rootShard, rootShardName, resources.GetRootShardURLs(&rootShard).Internal)
+		if err != nil {
+			// the inventory is informational only, keep reporting the last one
+			logger.Error(err, "Failed to collect shard inventory")
+		} else {
+			rootShard.Status.Inventory = inventory
+		}
+	}
+
+	rootShard.Status.ReadyReplicas = dep.Status.ReadyReplicas
+	rootShard.Status.Version = resources.GetRootShardVersion(&rootShard)
+	rootShard.Status.URLs = resources.GetRootShardURLs(&rootShard)
+	setAvailableCondition(&rootShard.Status.Conditions, rootShard.Generation, dep)
+
+	if err := r.Status().Patch(ctx, &rootShard, client.MergeFrom(oldRootShard)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	var result ctrl.Result
+	switch {
+	case upgrading:
+		result.RequeueAfter = upgradePollInterval
+	case collectInventory:
+		result.RequeueAfter = inventoryInterval
+	}
+
+	return requeueWithin(requeueWithin(result, etcdCertRecheck), adminRenewal), nil
+}
+
+// reconcileAdminKubeconfig writes the admin kubeconfig of the given RootShard, a system:masters credential
+// signed by its CA that the operator uses for its own clients, and returns the time until it is due for
+// renewal. RootShards without CA get no admin kubeconfig.
+func (r *RootShardReconciler) reconcileAdminKubeconfig(ctx context.Context, rootShard *operatorkcpiov1alpha1.RootShard) (time.Duration, error) {
+	if rootShard.Spec.CARef == nil {
+		return 0, nil
+	}
+
+	ca, err := kcpclient.GetCA(ctx, r.Client, rootShard)
+	if err != nil {
+		return 0, err
+	}
+
+	kc := kubeconfig.NewRootShardAdmin(rootShard)
+	layout, err := kubeconfig.GetLayout(kc)
+	if err != nil {
+		return 0, err
+	}
+	baseURL := resources.GetRootShardURLs(rootShard).Internal
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: kc.Spec.SecretRef.Name, Namespace: rootShard.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = resources.GetRootShardResourceLabels(rootShard)
+
+		if kubeconfig.NeedsUpdate(secret.Data, kc, layout, baseURL, ca.CertPEM, ca.Certificate(), time.Now()) {
+			certPEM, keyPEM, err := ca.IssueClientCertificate(kc.Spec.Username, kc.Spec.Groups, kc.Spec.Validity.Duration)
+			if err != nil {
+				return fmt.Errorf("failed to issue client certificate: %w", err)
+			}
+
+			secret.Data, err = kubeconfig.NewSecretData(kc, layout, baseURL, kubeconfig.Credentials{CertPEM: certPEM, KeyPEM: keyPEM, CAPEM: ca.CertPEM})
+			if err != nil {
+				return err
+			}
+		}
+
+		return controllerutil.SetControllerReference(rootShard, secret, r.Scheme)
+	}); err != nil {
+		return 0, fmt.Errorf("failed to reconcile admin kubeconfig Secret: %w", err)
+	}
+
+	cert, err := kubeconfig.ClientCertificate(layout, secret.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(time.Until(kubeconfig.RenewalTime(cert, kubeconfig.GetRenewBefore(kc))), time.Second), nil
+}
+
+// reconcileBreakGlass grants the users listed in the BreakGlassAnnotation of the given RootShard read access
+// to its admin kubeconfig Secret, and revokes it again once the annotation is removed.
+func (r *RootShardReconciler) reconcileBreakGlass(ctx context.Context, rootShard *operatorkcpiov1alpha1.RootShard) error {
+	objMeta := metav1.ObjectMeta{Name: resources.GetBreakGlassName(rootShard), Namespace: rootShard.Namespace}
+
+	users := resources.GetBreakGlassUsers(rootShard)
+	if len(users) == 0 {
+		for _, obj := range []client.Object{&rbacv1.RoleBinding{ObjectMeta: objMeta}, &rbacv1.Role{ObjectMeta: objMeta}} {
+			if err := r.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to revoke break-glass access: %w", err)
+			}
+		}
+
+		meta.RemoveStatusCondition(&rootShard.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeBreakGlassGranted))
+		return nil
+	}
+
+	role := &rbacv1.Role{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		rootshard.MutateBreakGlassRole(role, rootShard)
+		return controllerutil.SetControllerReference(rootShard, role, r.Scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile break-glass Role: %w", err)
+	}
+
+	rb := &rbacv1.RoleBinding{ObjectMeta: objMeta}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, rb, func() error {
+		rootshard.MutateBreakGlassRoleBinding(rb, rootShard, users)
+		return controllerutil.SetControllerReference(rootShard, rb, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile break-glass RoleBinding: %w", err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("Granted break-glass access to admin kubeconfig", "users", users)
+	}
+
+	setCondition(&rootShard.Status.Conditions, rootShard.Generation, operatorkcpiov1alpha1.ConditionTypeBreakGlassGranted, metav1.ConditionTrue,
+		operatorkcpiov1alpha1.ConditionReasonAccessGranted, fmt.Sprintf("users %s may read Secret %s", strings.Join(users, ", "), resources.GetRootShardAdminKubeconfigName(rootShard)))
+
+	return nil
+}
+
+func (r *RootShardReconciler) reconcileWorkloads(ctx context.Context, rootShard *operatorkcpiov1alpha1.RootShard, etcdCertHash string) (*appsv1.Deployment, error) {
+	name := resources.GetRootShardDeploymentName(rootShard)
+	labels := resources.GetRootShardResourceLabels(rootShard)
+	objMeta := metav1.ObjectMeta{Name: name, Namespace: rootShard.Namespace}
+
+	if rootShard.Spec.CARef != nil {
+		caSecretName, err := kcpclient.GetCASecretName(ctx, r.Client, rootShard)
+		if err != nil {
+			return nil, err
+		}
+
+		issuer := certificates.New(certificates.IssuerGVK, resources.GetCAIssuerName(rootShard), rootShard.Namespace)
+		if err := reconcileUnstructured(ctx, r.Client, r.Scheme, rootShard, issuer, func() error {
+			return certificates.MutateCAIssuer(issuer, labels, caSecretName)
+		}); err != nil {
+			return nil, fmt.Errorf("failed to reconcile CA Issuer: %w", err)
+		}
+	}
+
+	if err := reconcileServerCertificate(ctx, r.Client, r.Scheme, rootShard, getCAIssuerName(rootShard), name, labels); err != nil {
+		return nil, err
+	}
+
+	if err := reconcileClientCABundle(ctx, r.Client, r.Scheme, rootShard, rootShard, name, labels); err != nil {
+		return nil, err
+	}
+
+	dep := &appsv1.Deployment{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		rootshard.MutateDeployment(dep, rootShard)
+		resources.SetEtcdCertificateHash(dep, etcdCertHash)
+		scaleDownForRestore(dep, rootShard)
+		return controllerutil.SetControllerReference(rootShard, dep, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile Deployment: %w", err)
+	}
+
+	svc := &corev1.Service{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		resources.MutateShardService(svc, labels)
+		return controllerutil.SetControllerReference(rootShard, svc, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile Service: %w", err)
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, pdb, func() error {
+		resources.MutatePodDisruptionBudget(pdb, labels)
+		return controllerutil.SetControllerReference(rootShard, pdb, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile PodDisruptionBudget: %w", err)
+	}
+
+	return dep, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RootShardReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorkcpiov1alpha1.RootShard{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.Secret{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.rootShardsForSecret)).
+		Watches(&operatorkcpiov1alpha1.FrontProxy{}, handler.EnqueueRequestsFromMapFunc(rootShardForFrontProxy)).
 		Complete(r)
 }
+
+// getExternalPort returns the port the kcp setup formed around the given RootShard is reachable on from
+// outside the cluster. It is derived from the exposure of the first FrontProxy (by namespace and name)
+// permitted to reference the RootShard; without any FrontProxy, the Ingress/Gateway port is assumed.
+func (r *RootShardReconciler) getExternalPort(ctx context.Context, rootShard *operatorkcpiov1alpha1.RootShard) (int32, error) {
+	var list operatorkcpiov1alpha1.FrontProxyList
+	if err := r.List(ctx, &list); err != nil {
+		return 0, fmt.Errorf("failed to list FrontProxies: %w", err)
+	}
+
+	var frontProxies []operatorkcpiov1alpha1.FrontProxy
+	for _, fp := range list.Items {
+		if fp.DeletionTimestamp != nil || !referencesRootShard(fp.Namespace, fp.Spec.RootShard, rootShard) {
+			continue
+		}
+
+		if _, err := reference.CheckRootShardReference(ctx, r.Client, reference.KindFrontProxy, fp.Namespace, fp.Spec.RootShard); err != nil {
+			if errors.Is(err, reference.ErrNotPermitted) {
+				continue
+			}
+			return 0, err
+		}
+
+		frontProxies = append(frontProxies, fp)
+	}
+
+	if len(frontProxies) == 0 {
+		return resources.ExternalPort, nil
+	}
+
+	first := slices.MinFunc(frontProxies, func(a, b operatorkcpiov1alpha1.FrontProxy) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	return resources.GetFrontProxyExternalPort(&first), nil
+}
+
+// getCAIssuerName returns the name of the Issuer signing certificates with the root shard's CA, or an
+// empty string if it has no CA.
+func getCAIssuerName(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	if rootShard.Spec.CARef == nil {
+		return ""
+	}
+
+	return resources.GetCAIssuerName(rootShard)
+}
+
+// rootShardForFrontProxy enqueues the RootShard referenced by the given FrontProxy, whose exposure
+// determines the external port of the kcp setup.
+func rootShardForFrontProxy(_ context.Context, obj client.Object) []reconcile.Request {
+	frontProxy := obj.(*operatorkcpiov1alpha1.FrontProxy)
+
+	key, err := reference.GetRootShardKey(frontProxy.Namespace, frontProxy.Spec.RootShard)
+	if err != nil {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: key}}
+}
+
+// rootShardsForSecret enqueues all RootShards using the given Secret as etcd client certificate, or the
+// RootShard trusting it as client CA.
+func (r *RootShardReconciler) rootShardsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	if key, ok := resources.GetClientCARootShard(obj); ok {
+		return []reconcile.Request{{NamespacedName: key}}
+	}
+
+	var rootShards operatorkcpiov1alpha1.RootShardList
+	if err := r.List(ctx, &rootShards, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list RootShards")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, rootShard := range rootShards.Items {
+		if rootShard.Spec.Etcd.ClientCert.SecretRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&rootShard)})
+		}
+	}
+
+	return requests
+}
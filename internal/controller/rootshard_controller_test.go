@@ -22,9 +22,13 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
@@ -57,6 +61,7 @@ var _ = Describe("RootShard Controller", func() {
 							Etcd: operatorkcpiov1alpha1.EtcdConfig{
 								Endpoints: []string{"https://localhost:2379"},
 							},
+							Replicas: ptr.To[int32](3),
 						},
 					},
 				}
@@ -84,8 +89,70 @@ var _ = Describe("RootShard Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Checking the kcp Deployment")
+			dep := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-kcp", Namespace: "default"}, dep)).To(Succeed())
+			Expect(dep.Spec.Replicas).To(HaveValue(Equal(int32(3))))
+			Expect(dep.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(dep.Spec.Template.Spec.Containers[0].ReadinessProbe.HTTPGet.Path).To(Equal("/readyz"))
+			Expect(dep.Spec.Template.Spec.Containers[0].LivenessProbe.HTTPGet.Path).To(Equal("/livez"))
+
+			By("Checking the RootShard status")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kcpinstance)).To(Succeed())
+			Expect(kcpinstance.Status.Replicas).To(Equal(int32(3)))
+			Expect(kcpinstance.Status.ReadyReplicas).To(BeZero())
+			Expect(kcpinstance.Status.URLs.Internal).To(Equal("https://test-resource-kcp.default.svc.cluster.local:6443"))
+			Expect(kcpinstance.Status.URLs.External).To(Equal("https://example.kcp.io:443"))
+			Expect(dep.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--shard-base-url=" + kcpinstance.Status.URLs.Internal))
+			Expect(kcpinstance.Status.Upgrade).NotTo(BeNil())
+			Expect(kcpinstance.Status.Upgrade.Phase).To(Equal(operatorkcpiov1alpha1.UpgradePhaseCompleted))
+			Expect(dep.Spec.Template.Spec.Containers[0].Image).To(Equal("ghcr.io/kcp-dev/kcp:" + kcpinstance.Status.Upgrade.TargetVersion))
+		})
+
+		It("should grant and revoke break-glass access to the admin kubeconfig", func() {
+			controllerReconciler := &RootShardReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			By("annotating the RootShard")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kcpinstance)).To(Succeed())
+			kcpinstance.Annotations = map[string]string{operatorkcpiov1alpha1.BreakGlassAnnotation: "alice, bob"}
+			Expect(k8sClient.Update(ctx, kcpinstance)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			breakGlassName := types.NamespacedName{Name: resourceName + "-break-glass", Namespace: "default"}
+			role := &rbacv1.Role{}
+			Expect(k8sClient.Get(ctx, breakGlassName, role)).To(Succeed())
+			Expect(role.Rules).To(HaveLen(1))
+			Expect(role.Rules[0].ResourceNames).To(Equal([]string{resourceName + "-admin-kubeconfig"}))
+			Expect(role.Rules[0].Verbs).To(Equal([]string{"get"}))
+
+			rb := &rbacv1.RoleBinding{}
+			Expect(k8sClient.Get(ctx, breakGlassName, rb)).To(Succeed())
+			Expect(rb.Subjects).To(HaveLen(2))
+			Expect(rb.Subjects[0].Name).To(Equal("alice"))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kcpinstance)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(kcpinstance.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeBreakGlassGranted))).To(BeTrue())
+
+			By("removing the annotation")
+			kcpinstance.Annotations = nil
+			Expect(k8sClient.Update(ctx, kcpinstance)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, breakGlassName, &rbacv1.RoleBinding{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			err = k8sClient.Get(ctx, breakGlassName, &rbacv1.Role{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, kcpinstance)).To(Succeed())
+			Expect(meta.FindStatusCondition(kcpinstance.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeBreakGlassGranted))).To(BeNil())
 		})
 	})
 })
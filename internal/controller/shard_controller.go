@@ -18,45 +18,536 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp-operator/internal/client"
+	"github.com/kcp-dev/kcp-operator/internal/etcd"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/registry"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/certificates"
+	"github.com/kcp-dev/kcp-operator/internal/resources/shard"
+	"github.com/kcp-dev/kcp-operator/internal/resources/virtualworkspaces"
+)
+
+const (
+	// shardFinalizer makes sure a shard is deregistered from kcp before the Shard object is deleted.
+	shardFinalizer = "operator.kcp.io/shard-deregistration"
+
+	// deletionProtectionRequeueInterval is how often a Shard whose deletion is blocked is checked again.
+	deletionProtectionRequeueInterval = 30 * time.Second
+
+	// shardRegistrationPollInterval is how often the operator checks whether a new shard has registered itself with kcp.
+	shardRegistrationPollInterval = 30 * time.Second
 )
 
 // ShardReconciler reconciles a Shard object
 type ShardReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// ImageResolver resolves image tags to digests if digest resolution is enabled. Defaults to
+	// querying the image registry.
+	ImageResolver registry.Resolver
+	// EtcdHealthChecker checks the configured etcd endpoints before rolling out. Defaults to querying
+	// etcd's health endpoint.
+	EtcdHealthChecker etcd.HealthChecker
 }
 
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=shards,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=shards/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.kcp.io,resources=shards/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=operator.kcp.io,resources=referencegrants,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates;issuers,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Shard object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
 func (r *ShardReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	logger := log.FromContext(ctx)
+	logger.V(4).Info("Reconciling Shard object")
+
+	var s operatorkcpiov1alpha1.Shard
+	if err := r.Get(ctx, req.NamespacedName, &s); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if s.DeletionTimestamp != nil {
+		return r.reconcileDeletion(ctx, &s)
+	}
+
+	oldShard := s.DeepCopy()
+
+	if isPaused(&s.Status.Conditions, &s) {
+		logger.V(4).Info("Reconciliation is paused, only updating status")
+
+		dep, err := getDeployment(ctx, r.Client, client.ObjectKey{Namespace: s.Namespace, Name: resources.GetShardDeploymentName(&s)})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if dep != nil {
+			s.Status.ReadyReplicas = dep.Status.ReadyReplicas
+			setAvailableCondition(&s.Status.Conditions, s.Generation, dep)
+		}
+
+		if err := r.Status().Patch(ctx, &s, client.MergeFrom(oldShard)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(&s, shardFinalizer) {
+		controllerutil.AddFinalizer(&s, shardFinalizer)
+		if err := r.Update(ctx, &s); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to add finalizer: %w", err)
+		}
+	}
+
+	s.Status.Replicas = resources.GetReplicas(&s.Spec.CommonShardSpec)
+
+	rootShard, err := getRootShard(ctx, r.Client, reference.KindShard, s.Namespace, s.Spec.RootShard)
+	if err != nil {
+		setRootShardCondition(&s.Status.Conditions, s.Generation, err)
+
+		if patchErr := r.Status().Patch(ctx, &s, client.MergeFrom(oldShard)); patchErr != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", patchErr)
+		}
+
+		return ctrl.Result{}, fmt.Errorf("failed to get RootShard: %w", err)
+	}
+
+	s.Status.Version = resources.GetEffectiveVersion(s.Spec.Image, resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseShards))
+	if !checkVersionCompatibility(&s.Status.Conditions, s.Generation, reference.KindShard, s.Status.Version, resources.GetRootShardVersion(rootShard)) {
+		if err := r.Status().Patch(ctx, &s, client.MergeFrom(oldShard)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	resolvedImage, err := resolveImageDigest(ctx, r.Client, r.ImageResolver, s.Namespace, s.Generation, &s.Status.Conditions, s.Spec.Image, resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseShards), s.Status.ResolvedImage)
+	s.Status.ResolvedImage = resolvedImage
+	if err != nil {
+		if patchErr := r.Status().Patch(ctx, &s, client.MergeFrom(oldShard)); patchErr != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", patchErr)
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	etcdCertHash, etcdCertRecheck, err := reconcileEtcdCertificate(ctx, r.Client, r.Scheme, &s, &s.Status.Conditions, s.Spec.Etcd, resources.GetShardResourceLabels(&s))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reachable, err := checkEtcdHealth(ctx, r.Client, r.EtcdHealthChecker, s.Namespace, s.Generation, &s.Status.Conditions, s.Spec.Etcd)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !reachable {
+		if err := r.Status().Patch(ctx, &s, client.MergeFrom(oldShard)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+
+		return ctrl.Result{RequeueAfter: etcdUnreachableRetryInterval}, nil
+	}
+
+	issuerName, err := r.reconcileCAIssuer(ctx, &s, rootShard)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	dep, err := r.reconcileWorkloads(ctx, &s, rootShard, issuerName, etcdCertHash)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := reconcileVirtualWorkspaces(ctx, r.Client, r.Scheme, &s, &s.Status.Conditions, &s.Spec.CommonShardSpec, rootShard, virtualworkspaces.Options{
+		ShardDeploymentName: resources.GetShardDeploymentName(&s),
+		Namespace:           s.Namespace,
+		ShardURL:            resources.GetShardURLs(&s, rootShard).Internal,
+		Image:               s.Spec.Image,
+		Version:             resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseShards),
+		ResolvedImage:       s.Status.ResolvedImage,
+		IssuerName:          issuerName,
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	synced, err := r.reconcileScheduling(ctx, &s, rootShard, dep)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	collectInventory := synced && rootShard.Spec.CARef != nil
+	if collectInventory && isInventoryDue(s.Status.Inventory) {
+		inventory, err := getShardInventory(ctx, r.Client, r.Scheme, rootShard, s.Name, resources.GetShardURLs(&s, rootShard).Internal)
+		if err != nil {
+			// the inventory is informational only, keep reporting the last one
+			logger.Error(err, "Failed to collect shard inventory")
+		} else {
+			s.Status.Inventory = inventory
+		}
+	}
+
+	s.Status.ReadyReplicas = dep.Status.ReadyReplicas
+	s.Status.URLs = resources.GetShardURLs(&s, rootShard)
+	setAvailableCondition(&s.Status.Conditions, s.Generation, dep)
+
+	if err := r.Status().Patch(ctx, &s, client.MergeFrom(oldShard)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	var result ctrl.Result
+	switch {
+	case !synced:
+		// the shard registers itself with kcp once it is running
+		result.RequeueAfter = shardRegistrationPollInterval
+	case collectInventory:
+		result.RequeueAfter = inventoryInterval
+	}
+
+	return requeueWithin(result, etcdCertRecheck), nil
+}
+
+// reconcileDeletion deregisters the shard from kcp before releasing the finalizer: the kcp Shard object
+// is cordoned first, so that no new workspaces are scheduled onto it, and deleted afterwards. If deletion
+// protection is enabled, the finalizer is kept as long as logical clusters are stored on the shard.
+func (r *ShardReconciler) reconcileDeletion(ctx context.Context, s *operatorkcpiov1alpha1.Shard) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(s, shardFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	logger := log.FromContext(ctx)
+
+	rootShard, err := getRootShard(ctx, r.Client, reference.KindShard, s.Namespace, s.Spec.RootShard)
+	switch {
+	case apierrors.IsNotFound(err), errors.Is(err, reference.ErrNotPermitted):
+		// without the RootShard there is no kcp setup to deregister from
+		logger.Info("RootShard is not available, skipping deregistration", "error", err)
+		return ctrl.Result{}, r.removeFinalizer(ctx, s)
+	case err != nil:
+		return ctrl.Result{}, fmt.Errorf("failed to get RootShard: %w", err)
+	case rootShard.Spec.CARef == nil:
+		logger.Info("RootShard has no CA configured, cannot deregister the shard from kcp")
+		return ctrl.Result{}, r.removeFinalizer(ctx, s)
+	}
+
+	kcpClient, err := kcpclient.NewRootShardClient(ctx, r.Client, rootShard, r.Scheme)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	kcpShard := &unstructured.Unstructured{}
+	kcpShard.SetGroupVersionKind(kcpclient.ShardGVK)
+	if err := kcpClient.Get(ctx, client.ObjectKey{Name: s.Name}, kcpShard); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, r.removeFinalizer(ctx, s)
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get kcp Shard: %w", err)
+	}
+
+	if err := setKCPShardUnschedulable(ctx, kcpClient, kcpShard, true); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to cordon kcp Shard: %w", err)
+	}
+
+	if s.Spec.DeletionProtection {
+		remaining, err := r.countLogicalClusters(ctx, s, rootShard)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if remaining > 0 {
+			oldShard := s.DeepCopy()
+			setCondition(&s.Status.Conditions, s.Generation, operatorkcpiov1alpha1.ConditionTypeDeletionBlocked, metav1.ConditionTrue,
+				operatorkcpiov1alpha1.ConditionReasonClustersRemaining, fmt.Sprintf("%d logical clusters are still stored on this shard", remaining))
+			if err := r.Status().Patch(ctx, s, client.MergeFrom(oldShard)); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+			}
+
+			return ctrl.Result{RequeueAfter: deletionProtectionRequeueInterval}, nil
+		}
+	}
+
+	if err := kcpClient.Delete(ctx, kcpShard); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("failed to delete kcp Shard: %w", err)
+	}
+
+	return ctrl.Result{}, r.removeFinalizer(ctx, s)
+}
+
+// reconcileScheduling propagates spec.unschedulable to the kcp Shard object and mirrors the kcp-side
+// state into the status. Nothing is done until the shard is ready and has registered itself with kcp, in
+// which case false is returned so that the shard is checked again later.
+func (r *ShardReconciler) reconcileScheduling(ctx context.Context, s *operatorkcpiov1alpha1.Shard, rootShard *operatorkcpiov1alpha1.RootShard, dep *appsv1.Deployment) (bool, error) {
+	if rootShard.Spec.CARef == nil {
+		if s.Spec.Unschedulable {
+			log.FromContext(ctx).Info("RootShard has no CA configured, cannot cordon the shard in kcp")
+		}
+		return true, nil
+	}
 
-	// TODO(user): your logic here
+	if dep.Status.ReadyReplicas == 0 {
+		return false, nil
+	}
 
-	return ctrl.Result{}, nil
+	kcpClient, err := kcpclient.NewRootShardClient(ctx, r.Client, rootShard, r.Scheme)
+	if err != nil {
+		return false, fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	kcpShard := &unstructured.Unstructured{}
+	kcpShard.SetGroupVersionKind(kcpclient.ShardGVK)
+	if err := kcpClient.Get(ctx, client.ObjectKey{Name: s.Name}, kcpShard); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get kcp Shard: %w", err)
+	}
+
+	if err := setKCPShardUnschedulable(ctx, kcpClient, kcpShard, s.Spec.Unschedulable); err != nil {
+		return false, fmt.Errorf("failed to update kcp Shard: %w", err)
+	}
+
+	s.Status.Unschedulable = kcpShard.GetAnnotations()[kcpclient.UnschedulableAnnotation] == "true"
+
+	return true, nil
+}
+
+// setKCPShardUnschedulable cordons or uncordons the given kcp Shard object.
+func setKCPShardUnschedulable(ctx context.Context, kcpClient client.Client, kcpShard *unstructured.Unstructured, unschedulable bool) error {
+	annotations := kcpShard.GetAnnotations()
+	if (annotations[kcpclient.UnschedulableAnnotation] == "true") == unschedulable {
+		return nil
+	}
+
+	oldKCPShard := kcpShard.DeepCopy()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if unschedulable {
+		annotations[kcpclient.UnschedulableAnnotation] = "true"
+	} else {
+		delete(annotations, kcpclient.UnschedulableAnnotation)
+	}
+	kcpShard.SetAnnotations(annotations)
+
+	return kcpClient.Patch(ctx, kcpShard, client.MergeFrom(oldKCPShard))
+}
+
+// countLogicalClusters returns the number of logical clusters stored on the given shard.
+func (r *ShardReconciler) countLogicalClusters(ctx context.Context, s *operatorkcpiov1alpha1.Shard, rootShard *operatorkcpiov1alpha1.RootShard) (int, error) {
+	shardClient, err := kcpclient.NewShardClient(ctx, r.Client, rootShard, resources.GetShardURLs(s, rootShard).Internal, kcpclient.WildcardCluster, r.Scheme)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	remaining, err := countObjects(ctx, shardClient, kcpclient.LogicalClusterGVK)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list logical clusters: %w", err)
+	}
+
+	return int(remaining), nil
+}
+
+func (r *ShardReconciler) removeFinalizer(ctx context.Context, s *operatorkcpiov1alpha1.Shard) error {
+	controllerutil.RemoveFinalizer(s, shardFinalizer)
+	if err := r.Update(ctx, s); err != nil {
+		return fmt.Errorf("failed to remove finalizer: %w", err)
+	}
+
+	return nil
+}
+
+// reconcileCAIssuer returns the name of the cert-manager Issuer signing the certificates of the given shard
+// with the root shard's CA, or an empty string if the root shard has no CA. Issuers are namespaced, so for a
+// shard in another namespace than its root shard, the CA Secret is copied into the shard's namespace and a
+// dedicated Issuer is created there. The ReferenceGrant permitting the shard to join the kcp setup implies
+// trusting it with the CA.
+func (r *ShardReconciler) reconcileCAIssuer(ctx context.Context, s *operatorkcpiov1alpha1.Shard, rootShard *operatorkcpiov1alpha1.RootShard) (string, error) {
+	if rootShard.Spec.CARef == nil {
+		return "", nil
+	}
+
+	if s.Namespace == rootShard.Namespace {
+		return resources.GetCAIssuerName(rootShard), nil
+	}
+
+	caSecretName, err := kcpclient.GetCASecretName(ctx, r.Client, rootShard)
+	if err != nil {
+		return "", err
+	}
+
+	var caSecret corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: rootShard.Namespace, Name: caSecretName}, &caSecret); err != nil {
+		return "", fmt.Errorf("failed to get CA Secret: %w", err)
+	}
+
+	name := resources.GetShardCAIssuerName(s)
+	labels := resources.GetShardResourceLabels(s)
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = labels
+		secret.Type = corev1.SecretTypeTLS
+		secret.Data = map[string][]byte{
+			corev1.TLSCertKey:       caSecret.Data[corev1.TLSCertKey],
+			corev1.TLSPrivateKeyKey: caSecret.Data[corev1.TLSPrivateKeyKey],
+		}
+		return controllerutil.SetControllerReference(s, secret, r.Scheme)
+	}); err != nil {
+		return "", fmt.Errorf("failed to reconcile CA Secret: %w", err)
+	}
+
+	issuer := certificates.New(certificates.IssuerGVK, name, s.Namespace)
+	if err := reconcileUnstructured(ctx, r.Client, r.Scheme, s, issuer, func() error {
+		return certificates.MutateCAIssuer(issuer, labels, name)
+	}); err != nil {
+		return "", fmt.Errorf("failed to reconcile CA Issuer: %w", err)
+	}
+
+	return name, nil
+}
+
+func (r *ShardReconciler) reconcileWorkloads(ctx context.Context, s *operatorkcpiov1alpha1.Shard, rootShard *operatorkcpiov1alpha1.RootShard, issuerName, etcdCertHash string) (*appsv1.Deployment, error) {
+	name := resources.GetShardDeploymentName(s)
+	labels := resources.GetShardResourceLabels(s)
+	objMeta := metav1.ObjectMeta{Name: name, Namespace: s.Namespace}
+
+	if err := reconcileServerCertificate(ctx, r.Client, r.Scheme, s, issuerName, name, labels); err != nil {
+		return nil, err
+	}
+
+	if err := reconcileClientCABundle(ctx, r.Client, r.Scheme, s, rootShard, name, labels); err != nil {
+		return nil, err
+	}
+
+	dep := &appsv1.Deployment{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, dep, func() error {
+		shard.MutateDeployment(dep, s, rootShard)
+		resources.SetEtcdCertificateHash(dep, etcdCertHash)
+		scaleDownForRestore(dep, s)
+		return controllerutil.SetControllerReference(s, dep, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile Deployment: %w", err)
+	}
+
+	svc := &corev1.Service{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, svc, func() error {
+		resources.MutateShardService(svc, labels)
+		return controllerutil.SetControllerReference(s, svc, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile Service: %w", err)
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, pdb, func() error {
+		resources.MutatePodDisruptionBudget(pdb, labels)
+		return controllerutil.SetControllerReference(s, pdb, r.Scheme)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to reconcile PodDisruptionBudget: %w", err)
+	}
+
+	return dep, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ShardReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&operatorkcpiov1alpha1.Shard{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.Service{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&corev1.ConfigMap{}).
+		Watches(&operatorkcpiov1alpha1.RootShard{}, handler.EnqueueRequestsFromMapFunc(r.shardsForRootShard)).
+		Watches(&operatorkcpiov1alpha1.ReferenceGrant{}, handler.EnqueueRequestsFromMapFunc(r.shardsForReferenceGrant)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.shardsForSecret)).
 		Complete(r)
 }
+
+// shardsForSecret enqueues all Shards using the given Secret as etcd client certificate, or all Shards
+// of the kcp setup trusting it as client CA.
+func (r *ShardReconciler) shardsForSecret(ctx context.Context, obj client.Object) []reconcile.Request {
+	if key, ok := resources.GetClientCARootShard(obj); ok {
+		return r.shardsForRootShard(ctx, &operatorkcpiov1alpha1.RootShard{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}})
+	}
+
+	var shards operatorkcpiov1alpha1.ShardList
+	if err := r.List(ctx, &shards, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Shards")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, s := range shards.Items {
+		if s.Spec.Etcd.ClientCert.SecretRef.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&s)})
+		}
+	}
+
+	return requests
+}
+
+// shardsForRootShard enqueues all Shards referencing the given RootShard, so that changes to the
+// root shard (e.g. its hostname) are propagated.
+func (r *ShardReconciler) shardsForRootShard(ctx context.Context, obj client.Object) []reconcile.Request {
+	var shards operatorkcpiov1alpha1.ShardList
+	if err := r.List(ctx, &shards); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Shards")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, s := range shards.Items {
+		if referencesRootShard(s.Namespace, s.Spec.RootShard, obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&s)})
+		}
+	}
+
+	return requests
+}
+
+// shardsForReferenceGrant enqueues all Shards referencing a RootShard in the namespace of the given ReferenceGrant
+// from another namespace, so that granting or revoking access takes effect immediately.
+func (r *ShardReconciler) shardsForReferenceGrant(ctx context.Context, obj client.Object) []reconcile.Request {
+	var list operatorkcpiov1alpha1.ShardList
+	if err := r.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list Shards")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, s := range list.Items {
+		key, err := reference.GetRootShardKey(s.Namespace, s.Spec.RootShard)
+		if err == nil && key.Namespace == obj.GetNamespace() && key.Namespace != s.Namespace {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&s)})
+		}
+	}
+
+	return requests
+}
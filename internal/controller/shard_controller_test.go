@@ -22,9 +22,13 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
@@ -42,9 +46,30 @@ var _ = Describe("Shard Controller", func() {
 		}
 		shard := &operatorkcpiov1alpha1.Shard{}
 
+		rootShard := &operatorkcpiov1alpha1.RootShard{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "shard-test-root",
+				Namespace: "default",
+			},
+			Spec: operatorkcpiov1alpha1.RootShardSpec{
+				Hostname: "example.kcp.io",
+				CommonShardSpec: operatorkcpiov1alpha1.CommonShardSpec{
+					Etcd: operatorkcpiov1alpha1.EtcdConfig{
+						Endpoints: []string{"https://localhost:2379"},
+					},
+				},
+			},
+		}
+
 		BeforeEach(func() {
+			By("creating the RootShard referenced by the Shard")
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(rootShard), &operatorkcpiov1alpha1.RootShard{})
+			if err != nil && errors.IsNotFound(err) {
+				Expect(k8sClient.Create(ctx, rootShard.DeepCopy())).To(Succeed())
+			}
+
 			By("creating the custom resource for the Kind Shard")
-			err := k8sClient.Get(ctx, typeNamespacedName, shard)
+			err = k8sClient.Get(ctx, typeNamespacedName, shard)
 			if err != nil && errors.IsNotFound(err) {
 				resource := &operatorkcpiov1alpha1.Shard{
 					ObjectMeta: metav1.ObjectMeta{
@@ -57,6 +82,9 @@ var _ = Describe("Shard Controller", func() {
 								Endpoints: []string{"https://localhost:2379"},
 							},
 						},
+						RootShard: operatorkcpiov1alpha1.RootShardConfig{
+							Reference: &corev1.ObjectReference{Name: rootShard.Name},
+						},
 					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
@@ -71,6 +99,17 @@ var _ = Describe("Shard Controller", func() {
 
 			By("Cleanup the specific resource instance Shard")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			By("Releasing the finalizer, since the RootShard has no CA to deregister the shard with")
+			controllerReconciler := &ShardReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, resource))).To(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, rootShard.DeepCopy())).To(Succeed())
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
@@ -83,8 +122,47 @@ var _ = Describe("Shard Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Checking the kcp Deployment")
+			dep := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-shard-kcp", Namespace: "default"}, dep)).To(Succeed())
+			Expect(dep.Spec.Replicas).To(HaveValue(Equal(int32(1))))
+			Expect(dep.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--shard-name=" + resourceName))
+			Expect(dep.Spec.Template.Spec.Containers[0].ReadinessProbe.HTTPGet.Path).To(Equal("/readyz"))
+
+			By("Checking the Shard status")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, shard)).To(Succeed())
+			Expect(shard.Status.Replicas).To(Equal(int32(1)))
+			Expect(shard.Finalizers).To(ContainElement(shardFinalizer))
+			Expect(shard.Status.URLs.Internal).To(Equal("https://test-resource-shard-kcp.default.svc.cluster.local:6443"))
+			Expect(shard.Status.URLs.External).To(Equal("https://example.kcp.io:443"))
+			Expect(dep.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--shard-external-url=" + shard.Status.URLs.External))
+		})
+
+		It("should report a missing CA for external virtual workspaces", func() {
+			By("Switching the shard to external virtual workspaces")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, shard)).To(Succeed())
+			shard.Spec.VirtualWorkspaces = &operatorkcpiov1alpha1.VirtualWorkspacesSpec{
+				Mode: operatorkcpiov1alpha1.VirtualWorkspacesModeExternal,
+			}
+			Expect(k8sClient.Update(ctx, shard)).To(Succeed())
+
+			controllerReconciler := &ShardReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the shard status")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, shard)).To(Succeed())
+			cond := meta.FindStatusCondition(shard.Status.Conditions, string(operatorkcpiov1alpha1.ConditionTypeVirtualWorkspacesAvailable))
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Reason).To(Equal(string(operatorkcpiov1alpha1.ConditionReasonCANotConfigured)))
+			Expect(shard.Status.URLs.VirtualWorkspaces).To(Equal("https://test-resource-shard-kcp-virtual-workspaces.default.svc.cluster.local:6444"))
 		})
 	})
 })
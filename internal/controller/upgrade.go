@@ -0,0 +1,252 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/version"
+)
+
+const (
+	// defaultUpgradeTimeout is the time a single upgrade phase may take if the RootShard does not configure a timeout.
+	defaultUpgradeTimeout = 15 * time.Minute
+	// upgradePollInterval is how often the progress of an upgrade is checked.
+	upgradePollInterval = 10 * time.Second
+)
+
+// reconcileUpgrade drives the rollout of the kcp version configured on the root shard across all components
+// of the kcp setup: the cache server, the root shard itself, all secondary shards and finally all front-proxies.
+// Each phase only starts once all components of the previous phase have rolled out the new version and are
+// ready. Components read the version they should run from the root shard's status (see resources.GetKCPVersion),
+// so this function only advances the status. It returns true while an upgrade is in progress.
+func reconcileUpgrade(ctx context.Context, c client.Client, rootShard *operatorkcpiov1alpha1.RootShard) (bool, error) {
+	desired := resources.GetDesiredVersion(rootShard)
+	upgrade := rootShard.Status.Upgrade
+	now := metav1.Now()
+
+	if upgrade == nil {
+		rootShard.Status.Upgrade = &operatorkcpiov1alpha1.UpgradeStatus{
+			FromVersion:   desired,
+			TargetVersion: desired,
+			Phase:         operatorkcpiov1alpha1.UpgradePhaseCompleted,
+		}
+		setCondition(&rootShard.Status.Conditions, rootShard.Generation, operatorkcpiov1alpha1.ConditionTypeUpgraded, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonUpgradeCompleted, fmt.Sprintf("kcp %s has been deployed", desired))
+		return false, nil
+	}
+
+	done := upgrade.Phase == operatorkcpiov1alpha1.UpgradePhaseCompleted
+	if (done || upgrade.Failed) && upgrade.TargetVersion != desired {
+		// After a failed upgrade, components that were not reached still run the original version,
+		// so the new rollout starts from there.
+		from := upgrade.TargetVersion
+		if upgrade.Failed {
+			from = upgrade.FromVersion
+		}
+
+		if err := version.CheckUpgrade(from, desired); err != nil && !errors.Is(err, version.ErrUnknownVersion) {
+			setCondition(&rootShard.Status.Conditions, rootShard.Generation, operatorkcpiov1alpha1.ConditionTypeUpgraded, metav1.ConditionFalse,
+				operatorkcpiov1alpha1.ConditionReasonUnsupportedSkew, err.Error())
+			return false, nil
+		}
+
+		upgrade.FromVersion = from
+		upgrade.TargetVersion = desired
+		upgrade.Phase = operatorkcpiov1alpha1.UpgradePhaseCacheServer
+		upgrade.PhaseStartTime = &now
+		upgrade.Failed = false
+		upgrade.Message = ""
+		done = false
+	}
+
+	if done || upgrade.Failed {
+		return false, nil
+	}
+
+	completed, message, err := isUpgradePhaseCompleted(ctx, c, rootShard, upgrade)
+	if err != nil {
+		return true, err
+	}
+
+	switch {
+	case completed:
+		upgrade.Phase = resources.NextUpgradePhase(upgrade.Phase)
+		upgrade.PhaseStartTime = &now
+		upgrade.Message = ""
+
+	case upgrade.PhaseStartTime != nil && now.Sub(upgrade.PhaseStartTime.Time) > getUpgradeTimeout(rootShard):
+		upgrade.Failed = true
+		upgrade.Message = fmt.Sprintf("phase %s did not complete within %s: %s", upgrade.Phase, getUpgradeTimeout(rootShard), message)
+
+	default:
+		upgrade.Message = message
+	}
+
+	switch {
+	case upgrade.Failed:
+		setCondition(&rootShard.Status.Conditions, rootShard.Generation, operatorkcpiov1alpha1.ConditionTypeUpgraded, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonUpgradeFailed, upgrade.Message)
+		return false, nil
+
+	case upgrade.Phase == operatorkcpiov1alpha1.UpgradePhaseCompleted:
+		setCondition(&rootShard.Status.Conditions, rootShard.Generation, operatorkcpiov1alpha1.ConditionTypeUpgraded, metav1.ConditionTrue,
+			operatorkcpiov1alpha1.ConditionReasonUpgradeCompleted, fmt.Sprintf("all components have been upgraded to kcp %s", upgrade.TargetVersion))
+		return false, nil
+
+	default:
+		setCondition(&rootShard.Status.Conditions, rootShard.Generation, operatorkcpiov1alpha1.ConditionTypeUpgraded, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonUpgradeInProgress, fmt.Sprintf("upgrading %s from kcp %s to %s", upgrade.Phase, upgrade.FromVersion, upgrade.TargetVersion))
+		return true, nil
+	}
+}
+
+func getUpgradeTimeout(rootShard *operatorkcpiov1alpha1.RootShard) time.Duration {
+	if rootShard.Spec.UpgradeTimeout != nil {
+		return rootShard.Spec.UpgradeTimeout.Duration
+	}
+
+	return defaultUpgradeTimeout
+}
+
+// upgradeTarget is a Deployment that needs to run a specific image for an upgrade phase to complete.
+type upgradeTarget struct {
+	key   client.ObjectKey
+	image string
+}
+
+// isUpgradePhaseCompleted checks whether all components of the current upgrade phase run the target
+// version and are ready. If not, a message describing what is being waited for is returned. Shards and
+// front-proxies that are not permitted to reference the root shard are not part of the kcp setup and are
+// skipped, while paused ones block the phase until they are resumed.
+func isUpgradePhaseCompleted(ctx context.Context, c client.Client, rootShard *operatorkcpiov1alpha1.RootShard, upgrade *operatorkcpiov1alpha1.UpgradeStatus) (bool, string, error) {
+	var targets []upgradeTarget
+
+	switch upgrade.Phase {
+	case operatorkcpiov1alpha1.UpgradePhaseCacheServer:
+		ref := rootShard.Spec.Cache.Reference
+		if ref == nil {
+			break
+		}
+
+		var cacheServer operatorkcpiov1alpha1.CacheServer
+		if err := c.Get(ctx, client.ObjectKey{Namespace: rootShard.Namespace, Name: ref.Name}, &cacheServer); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, fmt.Sprintf("CacheServer %s does not exist", ref.Name), nil
+			}
+			return false, "", fmt.Errorf("failed to get CacheServer: %w", err)
+		}
+
+		image := resources.GetImageReference(cacheServer.Spec.Image, upgrade.TargetVersion)
+		targets = append(targets, upgradeTarget{
+			key:   client.ObjectKey{Namespace: cacheServer.Namespace, Name: resources.GetCacheServerDeploymentName(&cacheServer)},
+			image: image,
+		})
+
+	case operatorkcpiov1alpha1.UpgradePhaseRootShard:
+		image := resources.GetImageReference(rootShard.Spec.Image, upgrade.TargetVersion)
+		targets = append(targets, upgradeTarget{
+			key:   client.ObjectKey{Namespace: rootShard.Namespace, Name: resources.GetRootShardDeploymentName(rootShard)},
+			image: image,
+		})
+
+	case operatorkcpiov1alpha1.UpgradePhaseShards:
+		var shards operatorkcpiov1alpha1.ShardList
+		if err := c.List(ctx, &shards); err != nil {
+			return false, "", fmt.Errorf("failed to list Shards: %w", err)
+		}
+
+		for _, s := range shards.Items {
+			if !referencesRootShard(s.Namespace, s.Spec.RootShard, rootShard) {
+				continue
+			}
+
+			if _, err := reference.CheckRootShardReference(ctx, c, reference.KindShard, s.Namespace, s.Spec.RootShard); err != nil {
+				if errors.Is(err, reference.ErrNotPermitted) {
+					continue
+				}
+				return false, "", err
+			}
+
+			if hasPausedAnnotation(&s) {
+				return false, fmt.Sprintf("Shard %s is paused", client.ObjectKeyFromObject(&s)), nil
+			}
+
+			image := resources.GetImageReference(s.Spec.Image, upgrade.TargetVersion)
+			targets = append(targets, upgradeTarget{
+				key:   client.ObjectKey{Namespace: s.Namespace, Name: resources.GetShardDeploymentName(&s)},
+				image: image,
+			})
+		}
+
+	case operatorkcpiov1alpha1.UpgradePhaseFrontProxies:
+		var frontProxies operatorkcpiov1alpha1.FrontProxyList
+		if err := c.List(ctx, &frontProxies); err != nil {
+			return false, "", fmt.Errorf("failed to list FrontProxies: %w", err)
+		}
+
+		for _, fp := range frontProxies.Items {
+			if !referencesRootShard(fp.Namespace, fp.Spec.RootShard, rootShard) {
+				continue
+			}
+
+			if _, err := reference.CheckRootShardReference(ctx, c, reference.KindFrontProxy, fp.Namespace, fp.Spec.RootShard); err != nil {
+				if errors.Is(err, reference.ErrNotPermitted) {
+					continue
+				}
+				return false, "", err
+			}
+
+			if hasPausedAnnotation(&fp) {
+				return false, fmt.Sprintf("FrontProxy %s is paused", client.ObjectKeyFromObject(&fp)), nil
+			}
+
+			image := resources.GetImageReference(fp.Spec.Image, upgrade.TargetVersion)
+			targets = append(targets, upgradeTarget{
+				key:   client.ObjectKey{Namespace: fp.Namespace, Name: resources.GetFrontProxyDeploymentName(&fp)},
+				image: image,
+			})
+		}
+	}
+
+	for _, target := range targets {
+		var dep appsv1.Deployment
+		if err := c.Get(ctx, target.key, &dep); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, fmt.Sprintf("Deployment %s does not exist yet", target.key), nil
+			}
+			return false, "", fmt.Errorf("failed to get Deployment: %w", err)
+		}
+
+		if !resources.IsDeploymentRolledOut(&dep, target.image) {
+			return false, fmt.Sprintf("waiting for Deployment %s to roll out %s", target.key, target.image), nil
+		}
+	}
+
+	return true, "", nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+func TestIsUpgradePhaseCompletedShards(t *testing.T) {
+	const targetVersion = "v0.26.0"
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := operatorkcpiov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	rootShard := &operatorkcpiov1alpha1.RootShard{ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "kcp"}}
+	upgrade := &operatorkcpiov1alpha1.UpgradeStatus{Phase: operatorkcpiov1alpha1.UpgradePhaseShards, TargetVersion: targetVersion}
+
+	newShard := func(namespace, name string, annotations map[string]string) *operatorkcpiov1alpha1.Shard {
+		return &operatorkcpiov1alpha1.Shard{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Annotations: annotations},
+			Spec: operatorkcpiov1alpha1.ShardSpec{
+				RootShard: operatorkcpiov1alpha1.RootShardConfig{
+					Reference: &corev1.ObjectReference{Name: rootShard.Name, Namespace: rootShard.Namespace},
+				},
+			},
+		}
+	}
+
+	rolledOut := func(s *operatorkcpiov1alpha1.Shard) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: resources.GetShardDeploymentName(s), Namespace: s.Namespace},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To[int32](1),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "kcp", Image: resources.GetImageReference(nil, targetVersion)}},
+					},
+				},
+			},
+			Status: appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1, AvailableReplicas: 1},
+		}
+	}
+
+	shard := newShard("kcp", "shard", nil)
+	paused := newShard("kcp", "paused", map[string]string{operatorkcpiov1alpha1.PausedAnnotation: "true"})
+	notPermitted := newShard("tenant", "shard", nil)
+
+	testcases := []struct {
+		name            string
+		objects         []client.Object
+		expected        bool
+		expectedMessage string
+	}{
+		{
+			name:     "rolled out shard",
+			objects:  []client.Object{shard, rolledOut(shard)},
+			expected: true,
+		},
+		{
+			name:     "shard not permitted to reference the root shard",
+			objects:  []client.Object{shard, rolledOut(shard), notPermitted},
+			expected: true,
+		},
+		{
+			name:            "paused shard",
+			objects:         []client.Object{shard, rolledOut(shard), paused, rolledOut(paused)},
+			expected:        false,
+			expectedMessage: "Shard kcp/paused is paused",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()
+
+			completed, message, err := isUpgradePhaseCompleted(context.Background(), c, rootShard, upgrade)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if completed != tc.expected || message != tc.expectedMessage {
+				t.Errorf("expected (%v, %q), got (%v, %q)", tc.expected, tc.expectedMessage, completed, message)
+			}
+		})
+	}
+}
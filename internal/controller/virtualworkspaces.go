@@ -0,0 +1,160 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/certificates"
+	"github.com/kcp-dev/kcp-operator/internal/resources/virtualworkspaces"
+)
+
+// reconcileUnstructured creates or updates the given unstructured object via mutate and makes owner its
+// controlling owner, for kinds that are not part of the operator's scheme (e.g. cert-manager Issuers and Certificates).
+func reconcileUnstructured(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, obj *unstructured.Unstructured, mutate func() error) error {
+	_, err := controllerutil.CreateOrUpdate(ctx, c, obj, func() error {
+		if err := mutate(); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(owner, obj, scheme)
+	})
+
+	return err
+}
+
+// reconcileServerCertificate makes sure a serving certificate issued by the given Issuer (which signs with
+// the root shard's CA) exists for the shard Deployment with the given name. Nothing is done if there is no
+// Issuer, i.e. the root shard has no CA.
+func reconcileServerCertificate(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, issuerName string, deploymentName string, labels map[string]string) error {
+	if issuerName == "" {
+		return nil
+	}
+
+	cert := certificates.New(certificates.CertificateGVK, resources.GetServerCertificateName(deploymentName), owner.GetNamespace())
+	if err := reconcileUnstructured(ctx, c, scheme, owner, cert, func() error {
+		return certificates.MutateCertificate(cert, labels, certificates.Options{
+			SecretName: resources.GetServerCertificateName(deploymentName),
+			IssuerName: issuerName,
+			CommonName: deploymentName,
+			DNSNames:   resources.GetServiceDNSNames(deploymentName, owner.GetNamespace()),
+			Usages:     []string{"server auth"},
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile serving Certificate: %w", err)
+	}
+
+	return nil
+}
+
+// reconcileVirtualWorkspaces deploys (or removes) the standalone virtual-workspaces server of a shard
+// and updates the VirtualWorkspacesAvailable condition accordingly. Its certificates are issued by
+// opts.IssuerName.
+func reconcileVirtualWorkspaces(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, conditions *[]metav1.Condition,
+	spec *operatorkcpiov1alpha1.CommonShardSpec, rootShard *operatorkcpiov1alpha1.RootShard, opts virtualworkspaces.Options) error {
+	objMeta := metav1.ObjectMeta{Name: opts.Name(), Namespace: opts.Namespace}
+
+	if !resources.WantsExternalVirtualWorkspaces(spec) {
+		meta.RemoveStatusCondition(conditions, string(operatorkcpiov1alpha1.ConditionTypeVirtualWorkspacesAvailable))
+		return cleanupVirtualWorkspaces(ctx, c, opts)
+	}
+
+	if !resources.IsExternalVirtualWorkspaces(spec, rootShard) {
+		setCondition(conditions, owner.GetGeneration(), operatorkcpiov1alpha1.ConditionTypeVirtualWorkspacesAvailable, metav1.ConditionFalse,
+			operatorkcpiov1alpha1.ConditionReasonCANotConfigured, "external virtual workspaces require the RootShard to have a CA configured, serving them embedded until then")
+		return cleanupVirtualWorkspaces(ctx, c, opts)
+	}
+
+	opts.Spec = spec.VirtualWorkspaces
+	labels := resources.GetVirtualWorkspacesResourceLabels(opts.ShardDeploymentName)
+
+	for _, certOpts := range []certificates.Options{opts.ServerCertificate(), opts.ClientCertificate()} {
+		cert := certificates.New(certificates.CertificateGVK, certOpts.SecretName, opts.Namespace)
+		if err := reconcileUnstructured(ctx, c, scheme, owner, cert, func() error {
+			return certificates.MutateCertificate(cert, labels, certOpts)
+		}); err != nil {
+			return fmt.Errorf("failed to reconcile virtual-workspaces Certificate: %w", err)
+		}
+	}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: opts.KubeconfigName(), Namespace: opts.Namespace}}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, cm, func() error {
+		if err := virtualworkspaces.MutateKubeconfig(cm, opts); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(owner, cm, scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile virtual-workspaces kubeconfig: %w", err)
+	}
+
+	dep := &appsv1.Deployment{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, dep, func() error {
+		virtualworkspaces.MutateDeployment(dep, opts)
+		return controllerutil.SetControllerReference(owner, dep, scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile virtual-workspaces Deployment: %w", err)
+	}
+
+	svc := &corev1.Service{ObjectMeta: objMeta}
+	if _, err := controllerutil.CreateOrUpdate(ctx, c, svc, func() error {
+		virtualworkspaces.MutateService(svc, opts)
+		return controllerutil.SetControllerReference(owner, svc, scheme)
+	}); err != nil {
+		return fmt.Errorf("failed to reconcile virtual-workspaces Service: %w", err)
+	}
+
+	// re-use the Available logic, but report it under the virtual workspaces condition type
+	var available []metav1.Condition
+	setAvailableCondition(&available, owner.GetGeneration(), dep)
+	cond := available[0]
+	cond.Type = string(operatorkcpiov1alpha1.ConditionTypeVirtualWorkspacesAvailable)
+	meta.SetStatusCondition(conditions, cond)
+
+	return nil
+}
+
+func cleanupVirtualWorkspaces(ctx context.Context, c client.Client, opts virtualworkspaces.Options) error {
+	objMeta := metav1.ObjectMeta{Name: opts.Name(), Namespace: opts.Namespace}
+
+	objs := []client.Object{
+		&appsv1.Deployment{ObjectMeta: objMeta},
+		&corev1.Service{ObjectMeta: objMeta},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: opts.KubeconfigName(), Namespace: opts.Namespace}},
+		certificates.New(certificates.CertificateGVK, opts.ServerCertificate().SecretName, opts.Namespace),
+		certificates.New(certificates.CertificateGVK, opts.ClientCertificate().SecretName, opts.Namespace),
+	}
+
+	for _, obj := range objs {
+		if err := c.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete %T %s: %w", obj, obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package etcd checks whether the etcd endpoints configured for kcp components are reachable.
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// defaultTimeout bounds the health check of a single endpoint.
+const defaultTimeout = 5 * time.Second
+
+// HealthChecker checks the health of a single etcd endpoint like "https://etcd-0:2379".
+type HealthChecker interface {
+	CheckHealth(ctx context.Context, endpoint string, tlsConfig *tls.Config) error
+}
+
+// HTTPHealthChecker implements HealthChecker using etcd's /health endpoint.
+type HTTPHealthChecker struct {
+	// Timeout bounds the health check of a single endpoint.
+	Timeout time.Duration
+}
+
+var _ HealthChecker = &HTTPHealthChecker{}
+
+// NewHealthChecker returns a HealthChecker with the default timeout.
+func NewHealthChecker() *HTTPHealthChecker {
+	return &HTTPHealthChecker{Timeout: defaultTimeout}
+}
+
+// healthResponse is the response of etcd's /health endpoint.
+type healthResponse struct {
+	Health string `json:"health"`
+	Reason string `json:"reason"`
+}
+
+func (c *HTTPHealthChecker) CheckHealth(ctx context.Context, endpoint string, tlsConfig *tls.Config) error {
+	client := &http.Client{
+		Timeout:   c.Timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(endpoint, "/")+"/health", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var health healthResponse
+	if err := json.Unmarshal(body, &health); err != nil {
+		return fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+
+	if health.Health != "true" {
+		if health.Reason != "" {
+			return fmt.Errorf("etcd reports unhealthy: %s", health.Reason)
+		}
+		return errors.New("etcd reports unhealthy")
+	}
+
+	return nil
+}
+
+// TLSConfigFromSecret returns the TLS configuration for talking to etcd with the client certificate and
+// CA bundle stored in the given Secret.
+func TLSConfigFromSecret(secret *corev1.Secret) (*tls.Config, error) {
+	keyPair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(secret.Data[corev1.ServiceAccountRootCAKey]) {
+		return nil, errors.New("invalid CA bundle: no certificates found")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
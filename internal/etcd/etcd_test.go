@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckHealth(t *testing.T) {
+	testcases := []struct {
+		name        string
+		status      int
+		body        string
+		expectedErr string
+	}{
+		{
+			name:   "healthy",
+			status: http.StatusOK,
+			body:   `{"health":"true","reason":""}`,
+		},
+		{
+			name:        "unhealthy",
+			status:      http.StatusServiceUnavailable,
+			body:        `{"health":"false","reason":"RAFT NO LEADER"}`,
+			expectedErr: "RAFT NO LEADER",
+		},
+		{
+			name:        "not etcd",
+			status:      http.StatusNotFound,
+			body:        `404 page not found`,
+			expectedErr: "HTTP 404",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/health" {
+					http.NotFound(w, r)
+					return
+				}
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			pool := x509.NewCertPool()
+			pool.AddCert(server.Certificate())
+
+			err := NewHealthChecker().CheckHealth(context.Background(), server.URL, &tls.Config{RootCAs: pool})
+			if tc.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckHealthUntrustedServer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"health":"true"}`)
+	}))
+	defer server.Close()
+
+	err := NewHealthChecker().CheckHealth(context.Background(), server.URL, &tls.Config{RootCAs: x509.NewCertPool()})
+	if err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected a TLS error, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package reference resolves references between kcp-operator objects, enforcing ReferenceGrants for
+// references that cross namespace boundaries.
+package reference
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+const (
+	KindShard       = "Shard"
+	KindFrontProxy  = "FrontProxy"
+	KindRootShard   = "RootShard"
+	KindCacheServer = "CacheServer"
+	KindKubeconfig  = "Kubeconfig"
+	KindSecret      = "Secret"
+)
+
+// ErrNotPermitted is returned when a cross-namespace reference is not permitted by any ReferenceGrant.
+var ErrNotPermitted = errors.New("reference not permitted")
+
+// GetRootShardKey returns the namespace and name of the RootShard referenced by an object of the given namespace.
+func GetRootShardKey(namespace string, ref operatorkcpiov1alpha1.RootShardConfig) (client.ObjectKey, error) {
+	if ref.Reference == nil || ref.Reference.Name == "" {
+		return client.ObjectKey{}, errors.New("no RootShard reference configured")
+	}
+
+	key := client.ObjectKey{Namespace: ref.Reference.Namespace, Name: ref.Reference.Name}
+	if key.Namespace == "" {
+		key.Namespace = namespace
+	}
+
+	return key, nil
+}
+
+// CheckRootShardReference verifies that an object of fromKind in fromNamespace may reference the
+// RootShard it points to. References within the same namespace are always permitted.
+func CheckRootShardReference(ctx context.Context, c client.Reader, fromKind, fromNamespace string, ref operatorkcpiov1alpha1.RootShardConfig) (client.ObjectKey, error) {
+	key, err := GetRootShardKey(fromNamespace, ref)
+	if err != nil {
+		return key, err
+	}
+
+	permitted, err := IsPermitted(ctx, c, fromKind, fromNamespace, KindRootShard, key.Namespace, key.Name)
+	if err != nil {
+		return key, err
+	}
+	if !permitted {
+		return key, fmt.Errorf("%w: no ReferenceGrant in namespace %s allows %s objects in namespace %s to reference RootShard %s",
+			ErrNotPermitted, key.Namespace, fromKind, fromNamespace, key.Name)
+	}
+
+	return key, nil
+}
+
+// IsPermitted returns true if an object of fromKind in fromNamespace may reference the object toKind/toName
+// in toNamespace, i.e. if both are in the same namespace or a ReferenceGrant in toNamespace permits it.
+func IsPermitted(ctx context.Context, c client.Reader, fromKind, fromNamespace, toKind, toNamespace, toName string) (bool, error) {
+	if toNamespace == fromNamespace {
+		return true, nil
+	}
+
+	var grants operatorkcpiov1alpha1.ReferenceGrantList
+	if err := c.List(ctx, &grants, client.InNamespace(toNamespace)); err != nil {
+		return false, fmt.Errorf("failed to list ReferenceGrants: %w", err)
+	}
+
+	for _, grant := range grants.Items {
+		if Permits(&grant, fromKind, fromNamespace, toKind, toName) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ResolveRootShard checks and resolves the RootShard referenced by an object of fromKind in fromNamespace.
+func ResolveRootShard(ctx context.Context, c client.Reader, fromKind, fromNamespace string, ref operatorkcpiov1alpha1.RootShardConfig) (*operatorkcpiov1alpha1.RootShard, error) {
+	key, err := CheckRootShardReference(ctx, c, fromKind, fromNamespace, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var rootShard operatorkcpiov1alpha1.RootShard
+	if err := c.Get(ctx, key, &rootShard); err != nil {
+		return nil, err
+	}
+
+	return &rootShard, nil
+}
+
+// Permits returns true if the given grant allows an object of fromKind in fromNamespace to reference
+// the object toKind/toName in the grant's namespace.
+func Permits(grant *operatorkcpiov1alpha1.ReferenceGrant, fromKind, fromNamespace, toKind, toName string) bool {
+	fromMatches := false
+	for _, from := range grant.Spec.From {
+		if from.Kind == fromKind && from.Namespace == fromNamespace {
+			fromMatches = true
+			break
+		}
+	}
+
+	if !fromMatches {
+		return false
+	}
+
+	for _, to := range grant.Spec.To {
+		if to.Kind == toKind && (to.Name == "" || to.Name == toName) {
+			return true
+		}
+	}
+
+	return false
+}
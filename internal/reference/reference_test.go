@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reference
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+func TestCheckRootShardReference(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := operatorkcpiov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	grant := &operatorkcpiov1alpha1.ReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenants", Namespace: "platform"},
+		Spec: operatorkcpiov1alpha1.ReferenceGrantSpec{
+			From: []operatorkcpiov1alpha1.ReferenceGrantFrom{{Kind: KindShard, Namespace: "tenant-a"}},
+			To:   []operatorkcpiov1alpha1.ReferenceGrantTo{{Kind: KindRootShard, Name: "root"}},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(grant).Build()
+
+	testcases := []struct {
+		name          string
+		kind          string
+		namespace     string
+		ref           *corev1.ObjectReference
+		expectedKey   client.ObjectKey
+		notPermitted  bool
+		expectedError bool
+	}{
+		{
+			name:        "same namespace without explicit namespace",
+			kind:        KindShard,
+			namespace:   "tenant-b",
+			ref:         &corev1.ObjectReference{Name: "root"},
+			expectedKey: client.ObjectKey{Namespace: "tenant-b", Name: "root"},
+		},
+		{
+			name:        "cross namespace permitted by grant",
+			kind:        KindShard,
+			namespace:   "tenant-a",
+			ref:         &corev1.ObjectReference{Name: "root", Namespace: "platform"},
+			expectedKey: client.ObjectKey{Namespace: "platform", Name: "root"},
+		},
+		{
+			name:         "cross namespace from a namespace without grant",
+			kind:         KindShard,
+			namespace:    "tenant-b",
+			ref:          &corev1.ObjectReference{Name: "root", Namespace: "platform"},
+			notPermitted: true,
+		},
+		{
+			name:         "cross namespace from a kind without grant",
+			kind:         KindFrontProxy,
+			namespace:    "tenant-a",
+			ref:          &corev1.ObjectReference{Name: "root", Namespace: "platform"},
+			notPermitted: true,
+		},
+		{
+			name:         "cross namespace to a RootShard not covered by the grant",
+			kind:         KindShard,
+			namespace:    "tenant-a",
+			ref:          &corev1.ObjectReference{Name: "other", Namespace: "platform"},
+			notPermitted: true,
+		},
+		{
+			name:          "missing reference",
+			kind:          KindShard,
+			namespace:     "tenant-a",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := CheckRootShardReference(context.Background(), c, tc.kind, tc.namespace, operatorkcpiov1alpha1.RootShardConfig{Reference: tc.ref})
+
+			switch {
+			case tc.notPermitted:
+				if !errors.Is(err, ErrNotPermitted) {
+					t.Fatalf("expected ErrNotPermitted, got %v", err)
+				}
+			case tc.expectedError:
+				if err == nil || errors.Is(err, ErrNotPermitted) {
+					t.Fatalf("expected a generic error, got %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if key != tc.expectedKey {
+					t.Fatalf("expected key %v, got %v", tc.expectedKey, key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registry resolves container image tags to digests using the OCI distribution (registry v2) API.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// dockerHubRegistry is the registry host used for images without an explicit registry.
+	dockerHubRegistry = "registry-1.docker.io"
+
+	digestHeader = "Docker-Content-Digest"
+
+	// defaultTimeout bounds a single request to a registry, including reading its response, so that an
+	// unresponsive registry cannot block reconciliation.
+	defaultTimeout = 30 * time.Second
+)
+
+// manifestMediaTypes are the manifest types accepted when resolving a tag. Index types are preferred so
+// that the digest of a multi-arch image is the same on all platforms.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Credentials are used to authenticate against a registry.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Resolver resolves image references like "ghcr.io/kcp-dev/kcp:v0.26.0" to the digest of the image.
+type Resolver interface {
+	Resolve(ctx context.Context, image string, creds *Credentials) (string, error)
+}
+
+// HTTPResolver implements Resolver against registries speaking the registry v2 API.
+type HTTPResolver struct {
+	// Client is the HTTP client used to talk to registries.
+	Client *http.Client
+	// InsecureRegistries lists registry hosts (e.g. a local stand-in registry) that are reached via plain HTTP.
+	InsecureRegistries []string
+}
+
+var _ Resolver = &HTTPResolver{}
+
+// NewResolver returns a Resolver using an HTTP client with the default timeout.
+func NewResolver(insecureRegistries []string) *HTTPResolver {
+	return &HTTPResolver{
+		Client:             &http.Client{Timeout: defaultTimeout},
+		InsecureRegistries: insecureRegistries,
+	}
+}
+
+// Reference is a parsed image reference.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+}
+
+// ParseReference splits an image reference into registry, repository and tag, applying the same defaults
+// as container runtimes (Docker Hub, "library/" prefix and "latest" tag).
+func ParseReference(image string) (Reference, error) {
+	if image == "" {
+		return Reference{}, errors.New("empty image reference")
+	}
+	if strings.Contains(image, "@") {
+		return Reference{}, fmt.Errorf("image %q already contains a digest", image)
+	}
+
+	ref := Reference{Registry: dockerHubRegistry, Tag: "latest"}
+	remainder := image
+
+	if idx := strings.Index(image, "/"); idx > 0 {
+		host := image[:idx]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = host
+			remainder = image[idx+1:]
+		}
+	}
+
+	if idx := strings.LastIndex(remainder, ":"); idx > 0 && !strings.Contains(remainder[idx:], "/") {
+		ref.Tag = remainder[idx+1:]
+		remainder = remainder[:idx]
+	}
+
+	if ref.Registry == dockerHubRegistry && !strings.Contains(remainder, "/") {
+		remainder = "library/" + remainder
+	}
+
+	ref.Repository = remainder
+
+	return ref, nil
+}
+
+// Resolve returns the digest of the manifest the given image reference points to.
+func (r *HTTPResolver) Resolve(ctx context.Context, image string, creds *Credentials) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	scheme := "https"
+	if slices.Contains(r.InsecureRegistries, ref.Registry) {
+		scheme = "http"
+	}
+
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, ref.Registry, ref.Repository, ref.Tag)
+
+	// HEAD requests do not count against the pull rate limits of most registries.
+	resp, err := r.do(ctx, http.MethodHead, manifestURL, ref, creds)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if digest := resp.Header.Get(digestHeader); resp.StatusCode == http.StatusOK && digest != "" {
+		return digest, nil
+	}
+
+	// fall back to fetching the manifest, since not all registries return the digest for HEAD requests
+	resp, err = r.do(ctx, http.MethodGet, manifestURL, ref, creds)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s: registry returned %s", image, resp.Status)
+	}
+
+	if digest := resp.Header.Get(digestHeader); digest != "" {
+		return digest, nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
+// do performs a request against the registry, handling the bearer token challenge if the registry
+// requires one.
+func (r *HTTPResolver) do(ctx context.Context, method, target string, ref Reference, creds *Credentials) (*http.Response, error) {
+	req, err := newManifestRequest(ctx, method, target, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registry: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("registry %s denied access to %s", ref.Registry, ref.Repository)
+	}
+
+	token, err := r.fetchToken(ctx, parseChallenge(challenge[len("bearer "):]), ref, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = newManifestRequest(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err = r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query registry: %w", err)
+	}
+
+	return resp, nil
+}
+
+func newManifestRequest(ctx context.Context, method, target string, creds *Credentials) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+
+	return req, nil
+}
+
+// fetchToken requests a bearer token from the token service named in the registry's challenge.
+func (r *HTTPResolver) fetchToken(ctx context.Context, params map[string]string, ref Reference, creds *Credentials) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", errors.New("registry token challenge has no realm")
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", realm, err)
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+	}
+
+	query := tokenURL.Query()
+	query.Set("scope", scope)
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds != nil {
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+creds.Password)))
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request registry token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request registry token: %s", resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode registry token: %w", err)
+	}
+
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+
+	return "", errors.New("registry returned an empty token")
+}
+
+// parseChallenge parses the parameters of a WWW-Authenticate challenge like
+// `realm="https://ghcr.io/token",service="ghcr.io",scope="repository:kcp-dev/kcp:pull"`.
+func parseChallenge(params string) map[string]string {
+	result := map[string]string{}
+
+	for params != "" {
+		key, rest, found := strings.Cut(params, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+
+		result[key] = value
+		params = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+
+	return result
+}
+
+// CredentialsFromDockerConfig returns the credentials for the given registry host from a
+// `.dockerconfigjson` document, as used in image pull secrets. It returns nil if the document
+// contains no credentials for the registry.
+func CredentialsFromDockerConfig(data []byte, registryHost string) (*Credentials, error) {
+	var config struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %w", err)
+	}
+
+	for host, entry := range config.Auths {
+		if normalizeRegistryHost(host) != normalizeRegistryHost(registryHost) {
+			continue
+		}
+
+		if entry.Username != "" {
+			return &Credentials{Username: entry.Username, Password: entry.Password}, nil
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode auth for %s: %w", host, err)
+		}
+
+		username, password, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return nil, fmt.Errorf("invalid auth for %s", host)
+		}
+
+		return &Credentials{Username: username, Password: password}, nil
+	}
+
+	return nil, nil
+}
+
+// normalizeRegistryHost strips schemes and paths from docker config keys and maps the
+// legacy Docker Hub names to the registry host.
+func normalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+
+	switch host {
+	case "docker.io", "index.docker.io":
+		return dockerHubRegistry
+	}
+
+	return host
+}
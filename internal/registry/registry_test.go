@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	testcases := []struct {
+		image    string
+		expected Reference
+	}{
+		{
+			image:    "ghcr.io/kcp-dev/kcp:v0.26.0",
+			expected: Reference{Registry: "ghcr.io", Repository: "kcp-dev/kcp", Tag: "v0.26.0"},
+		},
+		{
+			image:    "localhost:5000/kcp",
+			expected: Reference{Registry: "localhost:5000", Repository: "kcp", Tag: "latest"},
+		},
+		{
+			image:    "busybox:1.36",
+			expected: Reference{Registry: dockerHubRegistry, Repository: "library/busybox", Tag: "1.36"},
+		},
+		{
+			image:    "kcpdev/kcp:main",
+			expected: Reference{Registry: dockerHubRegistry, Repository: "kcpdev/kcp", Tag: "main"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.image, func(t *testing.T) {
+			ref, err := ParseReference(tc.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ref != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, ref)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	const (
+		digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		token  = "secret-token"
+	)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if scope := r.URL.Query().Get("scope"); scope != "repository:kcp-dev/kcp:pull" {
+				t.Errorf("unexpected scope %q", scope)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+
+		case "/v2/kcp-dev/kcp/manifests/v0.26.0":
+			if r.Header.Get("Authorization") != "Bearer "+token {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+				t.Errorf("unexpected Accept header %q", r.Header.Get("Accept"))
+			}
+			if r.Method != http.MethodHead {
+				t.Errorf("expected HEAD request, got %s", r.Method)
+			}
+			w.Header().Set(digestHeader, digest)
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	resolver := NewResolver([]string{host})
+	if resolver.Client.Timeout == 0 {
+		t.Error("expected the HTTP client to have a timeout")
+	}
+
+	resolved, err := resolver.Resolve(context.Background(), host+"/kcp-dev/kcp:v0.26.0", &Credentials{Username: "user", Password: "pass"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved != digest {
+		t.Errorf("expected digest %q, got %q", digest, resolved)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), host+"/kcp-dev/kcp:missing", nil); err == nil {
+		t.Error("expected an error for a missing tag")
+	}
+}
+
+func TestCredentialsFromDockerConfig(t *testing.T) {
+	config := []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"dXNlcjpwYXNz"},"ghcr.io":{"username":"bot","password":"token"}}}`)
+
+	creds, err := CredentialsFromDockerConfig(config, dockerHubRegistry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds == nil || creds.Username != "user" || creds.Password != "pass" {
+		t.Errorf("unexpected credentials for Docker Hub: %+v", creds)
+	}
+
+	creds, err = CredentialsFromDockerConfig(config, "ghcr.io")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds == nil || creds.Username != "bot" || creds.Password != "token" {
+		t.Errorf("unexpected credentials for ghcr.io: %+v", creds)
+	}
+
+	if creds, _ := CredentialsFromDockerConfig(config, "quay.io"); creds != nil {
+		t.Errorf("expected no credentials for quay.io, got %+v", creds)
+	}
+}
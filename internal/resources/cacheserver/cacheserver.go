@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cacheserver
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// MutateDeployment configures the Deployment running the given cache server with the given kcp version.
+func MutateDeployment(dep *appsv1.Deployment, cacheServer *operatorkcpiov1alpha1.CacheServer, version string) {
+	labels := resources.GetCacheServerResourceLabels(cacheServer)
+	image, pullSecrets := resources.GetImageSettings(cacheServer.Spec.Image, version, cacheServer.Status.ResolvedImage)
+	etcdVolume, etcdMount := resources.GetEtcdVolume(cacheServer.Spec.Etcd)
+	dataVolume, dataMount := resources.GetDataVolume()
+
+	httpGet := func(path string) corev1.ProbeHandler {
+		return corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt32(resources.CacheServerPort),
+				Scheme: corev1.URISchemeHTTPS,
+			},
+		}
+	}
+
+	args := []string{
+		fmt.Sprintf("--root-directory=%s", resources.DataMountPath),
+		fmt.Sprintf("--secure-port=%d", resources.CacheServerPort),
+	}
+
+	dep.Labels = labels
+	dep.Spec.Replicas = ptr.To[int32](1)
+	dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dep.Spec.Template.Labels = labels
+	dep.Spec.Template.Spec.ImagePullSecrets = pullSecrets
+	dep.Spec.Template.Spec.Volumes = []corev1.Volume{etcdVolume, dataVolume}
+	dep.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "cache-server",
+		Image:   image,
+		Command: []string{"/cache-server"},
+		Args:    append(args, resources.GetEtcdArgs(cacheServer.Spec.Etcd)...),
+		Ports: []corev1.ContainerPort{{
+			Name:          "https",
+			ContainerPort: resources.CacheServerPort,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		VolumeMounts: []corev1.VolumeMount{etcdMount, dataMount},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler:  httpGet("/readyz"),
+			PeriodSeconds: 5,
+		},
+		LivenessProbe: &corev1.Probe{
+			ProbeHandler:     httpGet("/livez"),
+			PeriodSeconds:    10,
+			FailureThreshold: 3,
+		},
+	}}
+}
+
+// MutateService configures the ClusterIP Service fronting the cache server.
+func MutateService(svc *corev1.Service, cacheServer *operatorkcpiov1alpha1.CacheServer) {
+	labels := resources.GetCacheServerResourceLabels(cacheServer)
+
+	svc.Labels = labels
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.Selector = labels
+	svc.Spec.Ports = []corev1.ServicePort{{
+		Name:       "https",
+		Port:       resources.CacheServerPort,
+		TargetPort: intstr.FromInt32(resources.CacheServerPort),
+		Protocol:   corev1.ProtocolTCP,
+	}}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certificates renders cert-manager Issuers and Certificates. cert-manager resources are
+// handled as unstructured objects so that the operator does not depend on cert-manager's Go API.
+package certificates
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// CertificateGVK is the cert-manager Certificate kind.
+	CertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+	// IssuerGVK is the cert-manager Issuer kind.
+	IssuerGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Issuer"}
+)
+
+// Options describes a certificate that should be issued by cert-manager.
+type Options struct {
+	// SecretName is the Secret that cert-manager writes the certificate to.
+	SecretName string
+	// IssuerName is the issuer signing the certificate.
+	IssuerName string
+	// IssuerKind is the kind of the issuer. Defaults to a namespaced Issuer.
+	IssuerKind string
+	// IssuerGroup is the API group of the issuer. Defaults to cert-manager's own group.
+	IssuerGroup string
+	// CommonName is the certificate's subject common name, which kcp uses as the username for client certificates.
+	CommonName string
+	// Organizations are the certificate's subject organizations, which kcp uses as groups for client certificates.
+	Organizations []string
+	// DNSNames are the subject alternative names for serving certificates.
+	DNSNames []string
+	// Usages are the key usages requested for the certificate, e.g. "server auth" or "client auth".
+	Usages []string
+}
+
+// New returns an empty unstructured object of the given cert-manager kind.
+func New(gvk schema.GroupVersionKind, name, namespace string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+
+	return obj
+}
+
+// MutateCAIssuer configures an Issuer that signs certificates with the CA stored in caSecretName.
+func MutateCAIssuer(issuer *unstructured.Unstructured, labels map[string]string, caSecretName string) error {
+	issuer.SetLabels(labels)
+
+	return unstructured.SetNestedField(issuer.Object, map[string]interface{}{
+		"ca": map[string]interface{}{
+			"secretName": caSecretName,
+		},
+	}, "spec")
+}
+
+// MutateCertificate configures a Certificate according to opts.
+func MutateCertificate(cert *unstructured.Unstructured, labels map[string]string, opts Options) error {
+	issuerKind := opts.IssuerKind
+	if issuerKind == "" {
+		issuerKind = IssuerGVK.Kind
+	}
+
+	issuerGroup := opts.IssuerGroup
+	if issuerGroup == "" {
+		issuerGroup = IssuerGVK.Group
+	}
+
+	spec := map[string]interface{}{
+		"secretName": opts.SecretName,
+		"issuerRef": map[string]interface{}{
+			"name":  opts.IssuerName,
+			"kind":  issuerKind,
+			"group": issuerGroup,
+		},
+		"privateKey": map[string]interface{}{
+			"algorithm":      "RSA",
+			"size":           int64(4096),
+			"rotationPolicy": "Always",
+		},
+	}
+
+	if opts.CommonName != "" {
+		spec["commonName"] = opts.CommonName
+	}
+	if len(opts.Organizations) > 0 {
+		spec["subject"] = map[string]interface{}{
+			"organizations": toInterfaceSlice(opts.Organizations),
+		}
+	}
+	if len(opts.DNSNames) > 0 {
+		spec["dnsNames"] = toInterfaceSlice(opts.DNSNames)
+	}
+	if len(opts.Usages) > 0 {
+		spec["usages"] = toInterfaceSlice(opts.Usages)
+	}
+
+	cert.SetLabels(labels)
+
+	return unstructured.SetNestedField(cert.Object, spec, "spec")
+}
+
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+
+	return result
+}
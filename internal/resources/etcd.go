@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EtcdCertificateHashAnnotation is set on the pod templates of kcp components with a hash of their etcd
+// client certificate Secret, so that pods are restarted when the certificate changes.
+const EtcdCertificateHashAnnotation = "operator.kcp.io/etcd-client-cert-hash"
+
+// etcdCertificateKeys are the keys an etcd client certificate Secret must contain.
+var etcdCertificateKeys = []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey, corev1.ServiceAccountRootCAKey}
+
+// EtcdCertificate describes a validated etcd client certificate Secret.
+type EtcdCertificate struct {
+	// NotAfter is the earliest expiry of the client certificate and all CA certificates.
+	NotAfter time.Time
+	// Hash is a hash of the Secret's certificate data.
+	Hash string
+}
+
+// ParseEtcdCertificateSecret validates that the given Secret contains a client certificate with a
+// matching private key and at least one CA certificate.
+func ParseEtcdCertificateSecret(secret *corev1.Secret) (*EtcdCertificate, error) {
+	hash := sha256.New()
+	for _, key := range etcdCertificateKeys {
+		value, ok := secret.Data[key]
+		if !ok || len(value) == 0 {
+			return nil, fmt.Errorf("Secret %s does not contain %q", secret.Name, key)
+		}
+
+		hash.Write([]byte(key))
+		hash.Write(value)
+	}
+
+	keyPair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+
+	notAfter := leaf.NotAfter
+
+	cas, err := parseCertificates(secret.Data[corev1.ServiceAccountRootCAKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA bundle: %w", err)
+	}
+
+	for _, ca := range cas {
+		if ca.NotAfter.Before(notAfter) {
+			notAfter = ca.NotAfter
+		}
+	}
+
+	return &EtcdCertificate{
+		NotAfter: notAfter,
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// parseCertificates parses all PEM-encoded certificates in data.
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+
+	return certs, nil
+}
+
+// SetEtcdCertificateHash puts the given etcd client certificate hash on the Deployment's pod template.
+// An empty hash keeps the current annotation, so that pods are not restarted while the Secret is missing.
+func SetEtcdCertificateHash(dep *appsv1.Deployment, hash string) {
+	if hash == "" {
+		return
+	}
+
+	if dep.Spec.Template.Annotations == nil {
+		dep.Spec.Template.Annotations = map[string]string{}
+	}
+	dep.Spec.Template.Annotations[EtcdCertificateHashAnnotation] = hash
+}
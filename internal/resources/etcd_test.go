@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newTestCertificate(t *testing.T, notAfter time.Time) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "etcd-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestParseEtcdCertificateSecret(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	clientCert, clientKey := newTestCertificate(t, now.Add(90*24*time.Hour))
+	_, otherKey := newTestCertificate(t, now.Add(90*24*time.Hour))
+	oldCA, _ := newTestCertificate(t, now.Add(10*24*time.Hour))
+	newCA, _ := newTestCertificate(t, now.Add(365*24*time.Hour))
+
+	testcases := []struct {
+		name             string
+		data             map[string][]byte
+		expectedErr      bool
+		expectedNotAfter time.Time
+	}{
+		{
+			name: "valid",
+			data: map[string][]byte{
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+				"ca.crt":  newCA,
+			},
+			expectedNotAfter: now.Add(90 * 24 * time.Hour),
+		},
+		{
+			name: "multiple CAs",
+			data: map[string][]byte{
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+				"ca.crt":  append(append([]byte{}, oldCA...), newCA...),
+			},
+			expectedNotAfter: now.Add(10 * 24 * time.Hour),
+		},
+		{
+			name: "missing CA",
+			data: map[string][]byte{
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "mismatching key",
+			data: map[string][]byte{
+				"tls.crt": clientCert,
+				"tls.key": otherKey,
+				"ca.crt":  newCA,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid CA bundle",
+			data: map[string][]byte{
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+				"ca.crt":  []byte("not a certificate"),
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := &corev1.Secret{Data: tc.data}
+			secret.Name = "etcd-client-cert"
+
+			cert, err := ParseEtcdCertificateSecret(secret)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !cert.NotAfter.Equal(tc.expectedNotAfter) {
+				t.Errorf("expected expiry %v, got %v", tc.expectedNotAfter, cert.NotAfter)
+			}
+			if cert.Hash == "" {
+				t.Error("expected a hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package etcdbackup renders the CronJob taking scheduled snapshots of the etcd cluster used by a
+// (root) shard or cache server.
+package etcdbackup
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+const (
+	// DefaultEtcdImage provides etcdctl for taking snapshots.
+	DefaultEtcdImage = "registry.k8s.io/etcd:3.5.16-0"
+	// DefaultPVCImage is used to copy snapshots onto a volume.
+	DefaultPVCImage = "docker.io/library/busybox:1.37"
+	// DefaultS3Image provides the MinIO client to upload snapshots to S3-compatible storage.
+	DefaultS3Image = "docker.io/minio/mc:RELEASE.2024-11-21T17-21-54Z"
+
+	snapshotMountPath = "/snapshot"
+	snapshotFile      = snapshotMountPath + "/snapshot.db"
+	backupMountPath   = "/backup"
+
+	// AccessKeyIDKey and SecretAccessKeyKey are the keys expected in the S3 credentials Secret.
+	AccessKeyIDKey     = "accessKeyID"
+	SecretAccessKeyKey = "secretAccessKey"
+)
+
+// Snapshots are named after the time they have been taken, so sorting them by name sorts them by age.
+const pvcScript = `set -eu
+dir="` + backupMountPath + `/${BACKUP_PATH}"
+mkdir -p "${dir}"
+cp ` + snapshotFile + ` "${dir}/$(date -u +%Y%m%dT%H%M%SZ).db"
+find "${dir}" -maxdepth 1 -name '*.db' | sort -r | tail -n +$((RETENTION + 1)) | xargs -r rm -f
+`
+
+const s3Script = `set -eu
+mc ${MC_FLAGS} alias set backup "${S3_ENDPOINT}" "${S3_ACCESS_KEY_ID}" "${S3_SECRET_ACCESS_KEY}"
+dir="backup/${S3_BUCKET}/${BACKUP_PATH}"
+mc ${MC_FLAGS} cp ` + snapshotFile + ` "${dir}/$(date -u +%Y%m%dT%H%M%SZ).db"
+mc ${MC_FLAGS} find "${dir}" --name '*.db' | sort -r | tail -n +$((RETENTION + 1)) | while read -r obj; do mc ${MC_FLAGS} rm "${obj}"; done
+`
+
+// GetBackupPath returns the directory (relative to the configured storage location) that the snapshots
+// of the given EtcdBackup are stored in. It includes the EtcdBackup's namespace and name, so that backups
+// sharing a storage location never prune each other's snapshots.
+func GetBackupPath(backup *operatorkcpiov1alpha1.EtcdBackup) string {
+	dir := path.Join(backup.Namespace, backup.Name, fmt.Sprintf("%s-%s", strings.ToLower(string(backup.Spec.Target.Kind)), backup.Spec.Target.Name))
+
+	switch {
+	case backup.Spec.Storage.PVC != nil:
+		return path.Join(backup.Spec.Storage.PVC.Path, dir)
+	case backup.Spec.Storage.S3 != nil:
+		return path.Join(backup.Spec.Storage.S3.Prefix, dir)
+	default:
+		return dir
+	}
+}
+
+// MutateCronJob configures the CronJob taking snapshots of the given etcd cluster. Every Job takes a
+// snapshot with etcdctl in an init container and then stores it with a second container, which also
+// deletes snapshots exceeding the retention.
+func MutateCronJob(cj *batchv1.CronJob, backup *operatorkcpiov1alpha1.EtcdBackup, etcd operatorkcpiov1alpha1.EtcdConfig) {
+	labels := resources.GetEtcdBackupResourceLabels(backup)
+	etcdVolume, etcdMount := resources.GetEtcdVolume(etcd)
+
+	snapshotVolume := corev1.Volume{
+		Name:         "snapshot",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	snapshotMount := corev1.VolumeMount{Name: snapshotVolume.Name, MountPath: snapshotMountPath}
+
+	etcdImage := backup.Spec.EtcdImage
+	if etcdImage == "" {
+		etcdImage = DefaultEtcdImage
+	}
+
+	// etcdctl can only take snapshots from a single endpoint
+	var endpoint string
+	if len(etcd.Endpoints) > 0 {
+		endpoint = etcd.Endpoints[0]
+	}
+
+	store := getStoreContainer(backup)
+	store.VolumeMounts = append(store.VolumeMounts, snapshotMount)
+
+	volumes := []corev1.Volume{etcdVolume, snapshotVolume}
+	if pvc := backup.Spec.Storage.PVC; pvc != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "backup",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.ClaimName},
+			},
+		})
+	}
+
+	cj.Labels = labels
+	cj.Spec.Schedule = backup.Spec.Schedule
+	cj.Spec.Suspend = ptr.To(backup.Spec.Suspend)
+	cj.Spec.ConcurrencyPolicy = batchv1.ForbidConcurrent
+	cj.Spec.SuccessfulJobsHistoryLimit = ptr.To[int32](3)
+	cj.Spec.FailedJobsHistoryLimit = ptr.To[int32](3)
+	cj.Spec.JobTemplate.Labels = labels
+	cj.Spec.JobTemplate.Spec.BackoffLimit = ptr.To[int32](2)
+	cj.Spec.JobTemplate.Spec.Template.Labels = labels
+	cj.Spec.JobTemplate.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	cj.Spec.JobTemplate.Spec.Template.Spec.Volumes = volumes
+	cj.Spec.JobTemplate.Spec.Template.Spec.InitContainers = []corev1.Container{{
+		Name:    "snapshot",
+		Image:   etcdImage,
+		Command: []string{"etcdctl", "snapshot", "save", snapshotFile},
+		Args: []string{
+			fmt.Sprintf("--endpoints=%s", endpoint),
+			fmt.Sprintf("--cacert=%s/ca.crt", resources.EtcdCertificateMountPath),
+			fmt.Sprintf("--cert=%s/tls.crt", resources.EtcdCertificateMountPath),
+			fmt.Sprintf("--key=%s/tls.key", resources.EtcdCertificateMountPath),
+		},
+		Env:          []corev1.EnvVar{{Name: "ETCDCTL_API", Value: "3"}},
+		VolumeMounts: []corev1.VolumeMount{etcdMount, snapshotMount},
+	}}
+	cj.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{store}
+}
+
+func getStoreContainer(backup *operatorkcpiov1alpha1.EtcdBackup) corev1.Container {
+	env := []corev1.EnvVar{
+		{Name: "BACKUP_PATH", Value: GetBackupPath(backup)},
+		{Name: "RETENTION", Value: fmt.Sprintf("%d", getRetention(backup))},
+	}
+
+	if s3 := backup.Spec.Storage.S3; s3 != nil {
+		image := s3.Image
+		if image == "" {
+			image = DefaultS3Image
+		}
+
+		var flags string
+		if s3.Insecure {
+			flags = "--insecure"
+		}
+
+		secretEnv := func(name, key string) corev1.EnvVar {
+			return corev1.EnvVar{
+				Name: name,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: s3.CredentialsSecretRef, Key: key},
+				},
+			}
+		}
+
+		return corev1.Container{
+			Name:    "upload",
+			Image:   image,
+			Command: []string{"/bin/sh", "-c", s3Script},
+			Env: append(env,
+				corev1.EnvVar{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+				corev1.EnvVar{Name: "S3_BUCKET", Value: s3.Bucket},
+				corev1.EnvVar{Name: "MC_FLAGS", Value: flags},
+				corev1.EnvVar{Name: "MC_CONFIG_DIR", Value: "/tmp/.mc"},
+				secretEnv("S3_ACCESS_KEY_ID", AccessKeyIDKey),
+				secretEnv("S3_SECRET_ACCESS_KEY", SecretAccessKeyKey),
+			),
+		}
+	}
+
+	image := DefaultPVCImage
+	if pvc := backup.Spec.Storage.PVC; pvc != nil && pvc.Image != "" {
+		image = pvc.Image
+	}
+
+	return corev1.Container{
+		Name:         "store",
+		Image:        image,
+		Command:      []string{"/bin/sh", "-c", pvcScript},
+		Env:          env,
+		VolumeMounts: []corev1.VolumeMount{{Name: "backup", MountPath: backupMountPath}},
+	}
+}
+
+func getRetention(backup *operatorkcpiov1alpha1.EtcdBackup) int32 {
+	if backup.Spec.Retention < 1 {
+		return 7
+	}
+
+	return backup.Spec.Retention
+}
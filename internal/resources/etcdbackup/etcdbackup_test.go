@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+func TestMutateCronJob(t *testing.T) {
+	etcd := operatorkcpiov1alpha1.EtcdConfig{
+		Endpoints: []string{"https://etcd-0:2379"},
+		ClientCert: operatorkcpiov1alpha1.EtcdCertificate{
+			SecretRef: corev1.LocalObjectReference{Name: "etcd-client-cert"},
+		},
+	}
+
+	testcases := []struct {
+		name          string
+		storage       operatorkcpiov1alpha1.EtcdBackupStorage
+		expectedImage string
+		expectedPath  string
+		expectedEnv   map[string]string
+	}{
+		{
+			name: "pvc",
+			storage: operatorkcpiov1alpha1.EtcdBackupStorage{
+				PVC: &operatorkcpiov1alpha1.PVCBackupStorage{ClaimName: "backups", Path: "kcp"},
+			},
+			expectedImage: DefaultPVCImage,
+			expectedPath:  "kcp/kcp/backup/shard-alpha",
+		},
+		{
+			name: "s3",
+			storage: operatorkcpiov1alpha1.EtcdBackupStorage{
+				S3: &operatorkcpiov1alpha1.S3BackupStorage{
+					Endpoint:             "http://minio:9000",
+					Bucket:               "backups",
+					CredentialsSecretRef: corev1.LocalObjectReference{Name: "s3-credentials"},
+					Insecure:             true,
+				},
+			},
+			expectedImage: DefaultS3Image,
+			expectedPath:  "kcp/backup/shard-alpha",
+			expectedEnv: map[string]string{
+				"S3_ENDPOINT": "http://minio:9000",
+				"S3_BUCKET":   "backups",
+				"MC_FLAGS":    "--insecure",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			backup := &operatorkcpiov1alpha1.EtcdBackup{
+				ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "kcp"},
+				Spec: operatorkcpiov1alpha1.EtcdBackupSpec{
+					Target: operatorkcpiov1alpha1.EtcdBackupTarget{
+						Kind: operatorkcpiov1alpha1.EtcdBackupTargetShard,
+						Name: "alpha",
+					},
+					Schedule:  "@daily",
+					Storage:   tc.storage,
+					Retention: 3,
+				},
+			}
+
+			cj := &batchv1.CronJob{}
+			MutateCronJob(cj, backup, etcd)
+
+			if cj.Spec.ConcurrencyPolicy != batchv1.ForbidConcurrent {
+				t.Errorf("expected concurrent snapshots to be forbidden, got %q", cj.Spec.ConcurrencyPolicy)
+			}
+
+			podSpec := cj.Spec.JobTemplate.Spec.Template.Spec
+			if len(podSpec.InitContainers) != 1 || len(podSpec.Containers) != 1 {
+				t.Fatalf("expected one init container and one container, got %d and %d", len(podSpec.InitContainers), len(podSpec.Containers))
+			}
+
+			store := podSpec.Containers[0]
+			if store.Image != tc.expectedImage {
+				t.Errorf("expected image %q, got %q", tc.expectedImage, store.Image)
+			}
+
+			env := map[string]string{}
+			for _, e := range store.Env {
+				env[e.Name] = e.Value
+			}
+
+			if env["BACKUP_PATH"] != tc.expectedPath {
+				t.Errorf("expected backup path %q, got %q", tc.expectedPath, env["BACKUP_PATH"])
+			}
+			if env["RETENTION"] != "3" {
+				t.Errorf("expected retention 3, got %q", env["RETENTION"])
+			}
+			for key, value := range tc.expectedEnv {
+				if env[key] != value {
+					t.Errorf("expected %s=%q, got %q", key, value, env[key])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package etcdrestore renders the Job restoring an etcd snapshot taken by an EtcdBackup.
+package etcdrestore
+
+import (
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/etcdbackup"
+)
+
+const (
+	// DefaultImage provides a shell as well as etcd, etcdctl and etcdutl.
+	DefaultImage = "docker.io/bitnami/etcd:3.5.16"
+
+	snapshotMountPath = "/snapshot"
+	snapshotFile      = snapshotMountPath + "/snapshot.db"
+	backupMountPath   = "/backup"
+	dataMountPath     = "/restore"
+
+	// jobDeadline bounds the whole restore, so that a hanging fetch or mirror fails the Job instead of
+	// keeping the target scaled down forever.
+	jobDeadline = time.Hour
+)
+
+// Without an explicit snapshot, the latest one is restored. Snapshots are named after the time they
+// have been taken, so sorting them by name sorts them by age.
+const pvcFetchScript = `set -eu
+dir="` + backupMountPath + `/${BACKUP_PATH}"
+if [ -z "${SNAPSHOT}" ]; then SNAPSHOT="$(find "${dir}" -maxdepth 1 -name '*.db' | sort | tail -n 1)"; else SNAPSHOT="${dir}/${SNAPSHOT}"; fi
+[ -n "${SNAPSHOT}" ] || { echo "no snapshot found in ${dir}"; exit 1; }
+echo "fetching ${SNAPSHOT}"
+cp "${SNAPSHOT}" ` + snapshotFile + `
+`
+
+const s3FetchScript = `set -eu
+mc ${MC_FLAGS} alias set backup "${S3_ENDPOINT}" "${S3_ACCESS_KEY_ID}" "${S3_SECRET_ACCESS_KEY}"
+dir="backup/${S3_BUCKET}/${BACKUP_PATH}"
+if [ -z "${SNAPSHOT}" ]; then SNAPSHOT="$(mc ${MC_FLAGS} find "${dir}" --name '*.db' | sort | tail -n 1)"; else SNAPSHOT="${dir}/${SNAPSHOT}"; fi
+[ -n "${SNAPSHOT}" ] || { echo "no snapshot found in ${dir}"; exit 1; }
+echo "fetching ${SNAPSHOT}"
+mc ${MC_FLAGS} cp "${SNAPSHOT}" ` + snapshotFile + `
+`
+
+// The snapshot is restored into a temporary local etcd member, whose keys below the component's key prefix
+// are then mirrored into the target etcd cluster after all existing keys below the prefix have been
+// deleted. Keys of other components sharing the etcd cluster are left untouched. make-mirror does not
+// terminate by itself, so it is stopped once all keys have been copied; if it exits before, e.g. because
+// the target is unreachable, the restore fails.
+const restoreScript = `set -eu
+peer="http://127.0.0.1:12380"
+source="--endpoints=http://127.0.0.1:12379"
+target="--endpoints=${ETCD_ENDPOINT} --cacert=${ETCD_TLS}/ca.crt --cert=${ETCD_TLS}/tls.crt --key=${ETCD_TLS}/tls.key"
+
+etcdutl snapshot restore ` + snapshotFile + ` --data-dir ` + dataMountPath + `/data --name restore --initial-cluster "restore=${peer}" --initial-advertise-peer-urls "${peer}"
+etcd --name restore --data-dir ` + dataMountPath + `/data --listen-peer-urls "${peer}" --initial-advertise-peer-urls "${peer}" --initial-cluster "restore=${peer}" \
+  --listen-client-urls http://127.0.0.1:12379 --advertise-client-urls http://127.0.0.1:12379 &
+until etcdctl ${source} endpoint health >/dev/null 2>&1; do sleep 1; done
+
+count() { etcdctl "$@" get "${ETCD_PREFIX}" --prefix --count-only -w fields | grep '"Count"' | tr -dc '0-9'; }
+expected="$(count ${source})"
+echo "restoring ${expected} keys below ${ETCD_PREFIX}"
+
+etcdctl ${target} del "${ETCD_PREFIX}" --prefix
+etcdctl ${source} make-mirror --prefix "${ETCD_PREFIX}" \
+  --dest-cacert "${ETCD_TLS}/ca.crt" --dest-cert "${ETCD_TLS}/tls.crt" --dest-key "${ETCD_TLS}/tls.key" "${ETCD_ENDPOINT}" &
+mirror=$!
+until [ "$(count ${target})" -ge "${expected}" ]; do
+  kill -0 "${mirror}" 2>/dev/null || { echo "make-mirror exited before all keys were restored"; exit 1; }
+  sleep 2
+done
+kill "${mirror}"
+echo "restored ${expected} keys"
+`
+
+// GetJobName returns the name of the Job restoring the snapshot for the given EtcdRestore.
+func GetJobName(restore *operatorkcpiov1alpha1.EtcdRestore) string {
+	return fmt.Sprintf("%s-etcd-restore", restore.Name)
+}
+
+// NewJob returns the Job restoring a snapshot of the given EtcdBackup into the given etcd cluster. Jobs
+// are immutable, so unlike other objects it is only created and never updated.
+func NewJob(restore *operatorkcpiov1alpha1.EtcdRestore, backup *operatorkcpiov1alpha1.EtcdBackup, etcd operatorkcpiov1alpha1.EtcdConfig) *batchv1.Job {
+	labels := resources.GetEtcdRestoreResourceLabels(restore)
+	etcdVolume, etcdMount := resources.GetEtcdVolume(etcd)
+
+	snapshotMount := corev1.VolumeMount{Name: "snapshot", MountPath: snapshotMountPath}
+	dataMount := corev1.VolumeMount{Name: "data", MountPath: dataMountPath}
+
+	volumes := []corev1.Volume{
+		etcdVolume,
+		{Name: snapshotMount.Name, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+		{Name: dataMount.Name, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+	if pvc := backup.Spec.Storage.PVC; pvc != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "backup",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvc.ClaimName, ReadOnly: true},
+			},
+		})
+	}
+
+	image := restore.Spec.Image
+	if image == "" {
+		image = DefaultImage
+	}
+
+	// make-mirror can only write to a single endpoint
+	var endpoint string
+	if len(etcd.Endpoints) > 0 {
+		endpoint = etcd.Endpoints[0]
+	}
+
+	fetch := getFetchContainer(restore, backup)
+	fetch.VolumeMounts = append(fetch.VolumeMounts, snapshotMount)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetJobName(restore),
+			Namespace: restore.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			// restores must not be retried behind the user's back
+			BackoffLimit:          ptr.To[int32](0),
+			ActiveDeadlineSeconds: ptr.To(int64(jobDeadline.Seconds())),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy:  corev1.RestartPolicyNever,
+					Volumes:        volumes,
+					InitContainers: []corev1.Container{fetch},
+					Containers: []corev1.Container{{
+						Name:    "restore",
+						Image:   image,
+						Command: []string{"/bin/sh", "-c", restoreScript},
+						Env: []corev1.EnvVar{
+							{Name: "ETCD_ENDPOINT", Value: endpoint},
+							{Name: "ETCD_TLS", Value: resources.EtcdCertificateMountPath},
+							// the trailing slash keeps keys of components sharing the etcd cluster under other prefixes
+							{Name: "ETCD_PREFIX", Value: resources.GetEtcdKeyPrefix(etcd) + "/"},
+							{Name: "ETCDCTL_API", Value: "3"},
+						},
+						VolumeMounts: []corev1.VolumeMount{etcdMount, snapshotMount, dataMount},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func getFetchContainer(restore *operatorkcpiov1alpha1.EtcdRestore, backup *operatorkcpiov1alpha1.EtcdBackup) corev1.Container {
+	env := []corev1.EnvVar{
+		{Name: "BACKUP_PATH", Value: etcdbackup.GetBackupPath(backup)},
+		{Name: "SNAPSHOT", Value: restore.Spec.Snapshot},
+	}
+
+	if s3 := backup.Spec.Storage.S3; s3 != nil {
+		image := s3.Image
+		if image == "" {
+			image = etcdbackup.DefaultS3Image
+		}
+
+		var flags string
+		if s3.Insecure {
+			flags = "--insecure"
+		}
+
+		secretEnv := func(name, key string) corev1.EnvVar {
+			return corev1.EnvVar{
+				Name: name,
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: s3.CredentialsSecretRef, Key: key},
+				},
+			}
+		}
+
+		return corev1.Container{
+			Name:    "fetch",
+			Image:   image,
+			Command: []string{"/bin/sh", "-c", s3FetchScript},
+			Env: append(env,
+				corev1.EnvVar{Name: "S3_ENDPOINT", Value: s3.Endpoint},
+				corev1.EnvVar{Name: "S3_BUCKET", Value: s3.Bucket},
+				corev1.EnvVar{Name: "MC_FLAGS", Value: flags},
+				corev1.EnvVar{Name: "MC_CONFIG_DIR", Value: "/tmp/.mc"},
+				secretEnv("S3_ACCESS_KEY_ID", etcdbackup.AccessKeyIDKey),
+				secretEnv("S3_SECRET_ACCESS_KEY", etcdbackup.SecretAccessKeyKey),
+			),
+		}
+	}
+
+	image := etcdbackup.DefaultPVCImage
+	if pvc := backup.Spec.Storage.PVC; pvc != nil && pvc.Image != "" {
+		image = pvc.Image
+	}
+
+	return corev1.Container{
+		Name:         "fetch",
+		Image:        image,
+		Command:      []string{"/bin/sh", "-c", pvcFetchScript},
+		Env:          env,
+		VolumeMounts: []corev1.VolumeMount{{Name: "backup", MountPath: backupMountPath, ReadOnly: true}},
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdrestore
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources/etcdbackup"
+)
+
+func TestNewJob(t *testing.T) {
+	etcd := operatorkcpiov1alpha1.EtcdConfig{
+		Endpoints: []string{"https://etcd-0:2379", "https://etcd-1:2379"},
+		ClientCert: operatorkcpiov1alpha1.EtcdCertificate{
+			SecretRef: corev1.LocalObjectReference{Name: "etcd-client-cert"},
+		},
+	}
+
+	testcases := []struct {
+		name               string
+		storage            operatorkcpiov1alpha1.EtcdBackupStorage
+		snapshot           string
+		expectedFetchImage string
+		expectedBackupPVC  bool
+		expectedEnv        map[string]string
+	}{
+		{
+			name: "latest snapshot from pvc",
+			storage: operatorkcpiov1alpha1.EtcdBackupStorage{
+				PVC: &operatorkcpiov1alpha1.PVCBackupStorage{ClaimName: "backups", Path: "kcp"},
+			},
+			expectedFetchImage: etcdbackup.DefaultPVCImage,
+			expectedBackupPVC:  true,
+			expectedEnv: map[string]string{
+				"BACKUP_PATH": "kcp/kcp/backup/shard-alpha",
+				"SNAPSHOT":    "",
+			},
+		},
+		{
+			name: "specific snapshot from s3",
+			storage: operatorkcpiov1alpha1.EtcdBackupStorage{
+				S3: &operatorkcpiov1alpha1.S3BackupStorage{
+					Endpoint:             "http://minio:9000",
+					Bucket:               "backups",
+					CredentialsSecretRef: corev1.LocalObjectReference{Name: "s3-credentials"},
+				},
+			},
+			snapshot:           "20250101T000000Z.db",
+			expectedFetchImage: etcdbackup.DefaultS3Image,
+			expectedEnv: map[string]string{
+				"BACKUP_PATH": "kcp/backup/shard-alpha",
+				"SNAPSHOT":    "20250101T000000Z.db",
+				"S3_ENDPOINT": "http://minio:9000",
+				"S3_BUCKET":   "backups",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := operatorkcpiov1alpha1.EtcdBackupTarget{Kind: operatorkcpiov1alpha1.EtcdBackupTargetShard, Name: "alpha"}
+
+			backup := &operatorkcpiov1alpha1.EtcdBackup{
+				ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "kcp"},
+				Spec:       operatorkcpiov1alpha1.EtcdBackupSpec{Target: target, Storage: tc.storage},
+			}
+			restore := &operatorkcpiov1alpha1.EtcdRestore{
+				ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "kcp"},
+				Spec: operatorkcpiov1alpha1.EtcdRestoreSpec{
+					Target:     target,
+					BackupName: backup.Name,
+					Snapshot:   tc.snapshot,
+				},
+			}
+
+			job := NewJob(restore, backup, etcd)
+
+			if job.Name != "restore-etcd-restore" {
+				t.Errorf("expected Job name %q, got %q", "restore-etcd-restore", job.Name)
+			}
+
+			if job.Spec.ActiveDeadlineSeconds == nil {
+				t.Error("expected the Job to have a deadline")
+			}
+
+			podSpec := job.Spec.Template.Spec
+			if podSpec.RestartPolicy != corev1.RestartPolicyNever {
+				t.Errorf("expected restart policy %q, got %q", corev1.RestartPolicyNever, podSpec.RestartPolicy)
+			}
+
+			if len(podSpec.InitContainers) != 1 || len(podSpec.Containers) != 1 {
+				t.Fatalf("expected one init container and one container, got %d and %d", len(podSpec.InitContainers), len(podSpec.Containers))
+			}
+
+			fetch := podSpec.InitContainers[0]
+			if fetch.Image != tc.expectedFetchImage {
+				t.Errorf("expected fetch image %q, got %q", tc.expectedFetchImage, fetch.Image)
+			}
+
+			env := map[string]string{}
+			for _, e := range fetch.Env {
+				env[e.Name] = e.Value
+			}
+			for name, value := range tc.expectedEnv {
+				if got, ok := env[name]; !ok || got != value {
+					t.Errorf("expected env %s=%q, got %q", name, value, got)
+				}
+			}
+
+			var hasBackupPVC bool
+			for _, v := range podSpec.Volumes {
+				if v.PersistentVolumeClaim != nil {
+					hasBackupPVC = true
+				}
+			}
+			if hasBackupPVC != tc.expectedBackupPVC {
+				t.Errorf("expected backup PVC volume to be present: %v", tc.expectedBackupPVC)
+			}
+
+			restoreContainer := podSpec.Containers[0]
+			if restoreContainer.Image != DefaultImage {
+				t.Errorf("expected restore image %q, got %q", DefaultImage, restoreContainer.Image)
+			}
+
+			for _, e := range restoreContainer.Env {
+				if e.Name == "ETCD_ENDPOINT" && e.Value != etcd.Endpoints[0] {
+					t.Errorf("expected snapshot to be restored into %q, got %q", etcd.Endpoints[0], e.Value)
+				}
+				if e.Name == "ETCD_PREFIX" && e.Value != "/registry/" {
+					t.Errorf("expected keys below %q to be restored, got %q", "/registry/", e.Value)
+				}
+			}
+		})
+	}
+}
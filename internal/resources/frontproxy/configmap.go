@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+const pathMappingKey = "path-mapping.yaml"
+
+// PathMapping is a single entry in kcp-front-proxy's path mapping file.
+type PathMapping struct {
+	Path    string `json:"path"`
+	Backend string `json:"backend"`
+}
+
+// GetPathMappings returns the default path mappings for a front-proxy in front of the given root shard.
+// Requests for logical clusters are routed to the root shard, which kcp-front-proxy uses to look up
+// the shard that is actually hosting a logical cluster. Requests for virtual workspaces are routed to
+// the root shard's virtual workspaces, which might be served by a standalone server. The standalone
+// virtual-workspaces servers of the given (secondary) shards are reachable below /shards/<name>/services/.
+func GetPathMappings(rootShard *operatorkcpiov1alpha1.RootShard, shards []operatorkcpiov1alpha1.Shard) []PathMapping {
+	urls := resources.GetRootShardURLs(rootShard)
+
+	mappings := []PathMapping{
+		{Path: "/clusters/", Backend: urls.Internal},
+		{Path: "/services/", Backend: urls.VirtualWorkspaces},
+	}
+
+	for _, shard := range shards {
+		if !resources.IsExternalVirtualWorkspaces(&shard.Spec.CommonShardSpec, rootShard) {
+			continue
+		}
+
+		mappings = append(mappings, PathMapping{
+			Path:    fmt.Sprintf("/shards/%s/services/", shard.Name),
+			Backend: resources.GetShardURLs(&shard, rootShard).VirtualWorkspaces,
+		})
+	}
+
+	return mappings
+}
+
+// MutateConfigMap configures the ConfigMap holding the front-proxy's path mapping.
+func MutateConfigMap(cm *corev1.ConfigMap, frontProxy *operatorkcpiov1alpha1.FrontProxy, mappings []PathMapping) error {
+	data, err := yaml.Marshal(mappings)
+	if err != nil {
+		return err
+	}
+
+	cm.Labels = resources.GetFrontProxyResourceLabels(frontProxy)
+	cm.Data = map[string]string{
+		pathMappingKey: string(data),
+	}
+
+	return nil
+}
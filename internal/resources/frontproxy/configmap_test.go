@@ -0,0 +1,81 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+func TestGetPathMappings(t *testing.T) {
+	external := operatorkcpiov1alpha1.CommonShardSpec{
+		VirtualWorkspaces: &operatorkcpiov1alpha1.VirtualWorkspacesSpec{Mode: operatorkcpiov1alpha1.VirtualWorkspacesModeExternal},
+	}
+
+	shards := []operatorkcpiov1alpha1.Shard{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "alpha", Namespace: "kcp"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "beta", Namespace: "kcp"},
+			Spec:       operatorkcpiov1alpha1.ShardSpec{CommonShardSpec: external},
+		},
+	}
+
+	testcases := []struct {
+		name     string
+		caRef    *corev1.LocalObjectReference
+		expected []PathMapping
+	}{
+		{
+			name:  "external virtual workspaces",
+			caRef: &corev1.LocalObjectReference{Name: "root-ca"},
+			expected: []PathMapping{
+				{Path: "/clusters/", Backend: "https://root-kcp.kcp.svc.cluster.local:6443"},
+				{Path: "/services/", Backend: "https://root-kcp.kcp.svc.cluster.local:6443"},
+				{Path: "/shards/beta/services/", Backend: "https://beta-shard-kcp-virtual-workspaces.kcp.svc.cluster.local:6444"},
+			},
+		},
+		{
+			name: "no CA configured",
+			expected: []PathMapping{
+				{Path: "/clusters/", Backend: "https://root-kcp.kcp.svc.cluster.local:6443"},
+				{Path: "/services/", Backend: "https://root-kcp.kcp.svc.cluster.local:6443"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootShard := &operatorkcpiov1alpha1.RootShard{
+				ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "kcp"},
+				Spec:       operatorkcpiov1alpha1.RootShardSpec{Hostname: "kcp.example.com", CARef: tc.caRef},
+			}
+
+			mappings := GetPathMappings(rootShard, shards)
+
+			if !slices.Equal(mappings, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, mappings)
+			}
+		})
+	}
+}
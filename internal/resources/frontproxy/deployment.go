@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	"fmt"
+	"path"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+const (
+	configMountPath = "/etc/kcp-front-proxy/config"
+	dataMountPath   = "/etc/kcp-front-proxy/data"
+)
+
+// MutateDeployment configures the kcp-front-proxy Deployment for the given front-proxy.
+func MutateDeployment(dep *appsv1.Deployment, frontProxy *operatorkcpiov1alpha1.FrontProxy, rootShard *operatorkcpiov1alpha1.RootShard) {
+	labels := resources.GetFrontProxyResourceLabels(frontProxy)
+	image, pullSecrets := resources.GetImageSettings(frontProxy.Spec.Image, resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseFrontProxies), frontProxy.Status.ResolvedImage)
+
+	replicas := int32(1)
+	if frontProxy.Spec.Replicas != nil {
+		replicas = *frontProxy.Spec.Replicas
+	}
+
+	httpGet := func(path string) corev1.ProbeHandler {
+		return corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt32(resources.FrontProxyPort),
+				Scheme: corev1.URISchemeHTTPS,
+			},
+		}
+	}
+
+	dep.Labels = labels
+	dep.Spec.Replicas = ptr.To(replicas)
+	dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dep.Spec.Template.Labels = labels
+	dep.Spec.Template.Spec.ImagePullSecrets = pullSecrets
+	dep.Spec.Template.Spec.Volumes = []corev1.Volume{
+		{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: resources.GetFrontProxyConfigName(frontProxy)},
+				},
+			},
+		},
+		{
+			Name:         "data",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+	}
+	dep.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "kcp-front-proxy",
+		Image:   image,
+		Command: []string{"/kcp-front-proxy"},
+		Args:    getArgs(frontProxy),
+		Ports: []corev1.ContainerPort{{
+			Name:          "https",
+			ContainerPort: resources.FrontProxyPort,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "config", MountPath: configMountPath, ReadOnly: true},
+			{Name: "data", MountPath: dataMountPath},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler:  httpGet("/readyz"),
+			PeriodSeconds: 5,
+		},
+		LivenessProbe: &corev1.Probe{
+			ProbeHandler:     httpGet("/livez"),
+			PeriodSeconds:    10,
+			FailureThreshold: 6,
+		},
+	}}
+	// kcp-front-proxy authenticates client certificates itself, so it has to trust the same client CAs as the
+	// shards for generated kubeconfigs to work, and to reject them once they have been revoked
+	if rootShard.Spec.CARef != nil {
+		container := &dep.Spec.Template.Spec.Containers[0]
+		container.Args = append(container.Args, fmt.Sprintf("--client-ca-file=%s/%s", resources.ClientCAMountPath, resources.ClientCABundleKey))
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{Name: "client-ca", MountPath: resources.ClientCAMountPath, ReadOnly: true})
+
+		dep.Spec.Template.Spec.Volumes = append(dep.Spec.Template.Spec.Volumes, corev1.Volume{
+			Name: "client-ca",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: resources.GetClientCABundleName(resources.GetFrontProxyDeploymentName(frontProxy))},
+			},
+		})
+	}
+}
+
+func getArgs(frontProxy *operatorkcpiov1alpha1.FrontProxy) []string {
+	args := []string{
+		fmt.Sprintf("--secure-port=%d", resources.FrontProxyPort),
+		fmt.Sprintf("--cert-dir=%s", dataMountPath),
+		fmt.Sprintf("--mapping-file=%s", path.Join(configMountPath, pathMappingKey)),
+	}
+
+	if frontProxy.Spec.Auth != nil && frontProxy.Spec.Auth.OIDC != nil && frontProxy.Spec.Auth.OIDC.Enabled {
+		oidc := frontProxy.Spec.Auth.OIDC
+		args = append(args,
+			fmt.Sprintf("--oidc-issuer-url=%s", oidc.IssuerURL),
+			fmt.Sprintf("--oidc-client-id=%s", oidc.ClientID),
+		)
+
+		if oidc.GroupsClaim != "" {
+			args = append(args, fmt.Sprintf("--oidc-groups-claim=%s", oidc.GroupsClaim))
+		}
+		if oidc.UsernameClaim != "" {
+			args = append(args, fmt.Sprintf("--oidc-username-claim=%s", oidc.UsernameClaim))
+		}
+		if oidc.GroupsPrefix != "" {
+			args = append(args, fmt.Sprintf("--oidc-groups-prefix=%s", oidc.GroupsPrefix))
+		}
+		if oidc.UsernamePrefix != "" {
+			args = append(args, fmt.Sprintf("--oidc-username-prefix=%s", oidc.UsernamePrefix))
+		}
+	}
+
+	return args
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+func TestMutateDeploymentClientCA(t *testing.T) {
+	testcases := []struct {
+		name             string
+		caRef            *corev1.LocalObjectReference
+		expectedClientCA bool
+	}{
+		{
+			name:             "RootShard with CA",
+			caRef:            &corev1.LocalObjectReference{Name: "root-ca"},
+			expectedClientCA: true,
+		},
+		{
+			name: "RootShard without CA",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootShard := &operatorkcpiov1alpha1.RootShard{
+				ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "kcp"},
+				Spec:       operatorkcpiov1alpha1.RootShardSpec{Hostname: "kcp.example.com", CARef: tc.caRef},
+			}
+			frontProxy := &operatorkcpiov1alpha1.FrontProxy{
+				ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: "kcp"},
+			}
+
+			dep := &appsv1.Deployment{}
+			MutateDeployment(dep, frontProxy, rootShard)
+
+			args := dep.Spec.Template.Spec.Containers[0].Args
+			if hasArg := slices.Contains(args, "--client-ca-file=/etc/kcp/tls/client-ca/ca.crt"); hasArg != tc.expectedClientCA {
+				t.Errorf("expected --client-ca-file to be set: %v, got args %v", tc.expectedClientCA, args)
+			}
+
+			hasVolume := slices.ContainsFunc(dep.Spec.Template.Spec.Volumes, func(v corev1.Volume) bool {
+				return v.Secret != nil && v.Secret.SecretName == "proxy-front-proxy-client-ca"
+			})
+			if hasVolume != tc.expectedClientCA {
+				t.Errorf("expected client CA bundle volume to be present: %v", tc.expectedClientCA)
+			}
+		})
+	}
+}
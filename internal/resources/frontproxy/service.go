@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+var (
+	// TLSRouteGVK is the Gateway API kind used to route TLS passthrough traffic to the front-proxy.
+	TLSRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Kind: "TLSRoute"}
+	// GatewayGVK is the Gateway API kind that TLSRoutes attach to.
+	GatewayGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+)
+
+// GetServiceType returns the configured Service type, defaulting to LoadBalancer.
+func GetServiceType(frontProxy *operatorkcpiov1alpha1.FrontProxy) corev1.ServiceType {
+	if frontProxy.Spec.Service == nil || frontProxy.Spec.Service.Type == "" {
+		return corev1.ServiceTypeLoadBalancer
+	}
+
+	return frontProxy.Spec.Service.Type
+}
+
+// MutateService configures the Service exposing the front-proxy.
+func MutateService(svc *corev1.Service, frontProxy *operatorkcpiov1alpha1.FrontProxy) {
+	labels := resources.GetFrontProxyResourceLabels(frontProxy)
+	serviceType := GetServiceType(frontProxy)
+	spec := frontProxy.Spec.Service
+	if spec == nil {
+		spec = &operatorkcpiov1alpha1.FrontProxyServiceSpec{}
+	}
+
+	port := corev1.ServicePort{
+		Name:       "https",
+		Port:       resources.FrontProxyPort,
+		TargetPort: intstr.FromInt32(resources.FrontProxyPort),
+		Protocol:   corev1.ProtocolTCP,
+	}
+
+	if serviceType != corev1.ServiceTypeClusterIP && spec.NodePort != nil {
+		port.NodePort = *spec.NodePort
+	}
+
+	// annotations are merged, as cloud controllers and other tools annotate Services as well
+	if len(spec.Annotations) > 0 && svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	for k, v := range spec.Annotations {
+		svc.Annotations[k] = v
+	}
+
+	svc.Labels = labels
+	svc.Spec.Type = serviceType
+	svc.Spec.Selector = labels
+	svc.Spec.Ports = []corev1.ServicePort{port}
+	svc.Spec.LoadBalancerClass = nil
+	svc.Spec.LoadBalancerSourceRanges = nil
+
+	if serviceType == corev1.ServiceTypeLoadBalancer {
+		svc.Spec.LoadBalancerClass = spec.LoadBalancerClass
+		svc.Spec.LoadBalancerSourceRanges = spec.LoadBalancerSourceRanges
+	}
+}
+
+// MutateIngress configures an Ingress that passes TLS connections for the root shard's hostname
+// through to the front-proxy Service.
+func MutateIngress(ing *networkingv1.Ingress, frontProxy *operatorkcpiov1alpha1.FrontProxy, rootShard *operatorkcpiov1alpha1.RootShard) {
+	annotations := map[string]string{
+		"nginx.ingress.kubernetes.io/ssl-passthrough":  "true",
+		"nginx.ingress.kubernetes.io/backend-protocol": "HTTPS",
+	}
+	for k, v := range frontProxy.Spec.Ingress.Annotations {
+		annotations[k] = v
+	}
+
+	ing.Labels = resources.GetFrontProxyResourceLabels(frontProxy)
+	ing.Annotations = annotations
+	ing.Spec.IngressClassName = frontProxy.Spec.Ingress.IngressClassName
+	ing.Spec.Rules = []networkingv1.IngressRule{{
+		Host: rootShard.Spec.Hostname,
+		IngressRuleValue: networkingv1.IngressRuleValue{
+			HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{
+					Path:     "/",
+					PathType: ptr.To(networkingv1.PathTypePrefix),
+					Backend: networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: resources.GetFrontProxyDeploymentName(frontProxy),
+							Port: networkingv1.ServiceBackendPort{Number: resources.FrontProxyPort},
+						},
+					},
+				}},
+			},
+		},
+	}}
+}
+
+// MutateTLSRoute configures a Gateway API TLSRoute that routes TLS connections for the root shard's
+// hostname to the front-proxy Service.
+func MutateTLSRoute(route *unstructured.Unstructured, frontProxy *operatorkcpiov1alpha1.FrontProxy, rootShard *operatorkcpiov1alpha1.RootShard) error {
+	parentRefs := []interface{}{}
+	for _, ref := range frontProxy.Spec.Gateway.ParentRefs {
+		parentRef := map[string]interface{}{
+			"name": ref.Name,
+		}
+		if ref.Namespace != "" {
+			parentRef["namespace"] = ref.Namespace
+		}
+		if ref.SectionName != "" {
+			parentRef["sectionName"] = ref.SectionName
+		}
+		parentRefs = append(parentRefs, parentRef)
+	}
+
+	route.SetLabels(resources.GetFrontProxyResourceLabels(frontProxy))
+
+	return unstructured.SetNestedField(route.Object, map[string]interface{}{
+		"parentRefs": parentRefs,
+		"hostnames":  []interface{}{rootShard.Spec.Hostname},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{
+					map[string]interface{}{
+						"name": resources.GetFrontProxyDeploymentName(frontProxy),
+						"port": int64(resources.FrontProxyPort),
+					},
+				},
+			},
+		},
+	}, "spec")
+}
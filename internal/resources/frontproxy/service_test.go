@@ -0,0 +1,137 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package frontproxy
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+func TestMutateService(t *testing.T) {
+	frontProxy := &operatorkcpiov1alpha1.FrontProxy{
+		ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: "kcp"},
+		Spec: operatorkcpiov1alpha1.FrontProxySpec{
+			Service: &operatorkcpiov1alpha1.FrontProxyServiceSpec{
+				Annotations: map[string]string{"example.com/owner": "kcp"},
+			},
+		},
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"cloud.example.com/load-balancer-id": "lb-1"},
+		},
+	}
+	MutateService(svc, frontProxy)
+
+	expected := map[string]string{
+		"cloud.example.com/load-balancer-id": "lb-1",
+		"example.com/owner":                  "kcp",
+	}
+	for k, v := range expected {
+		if svc.Annotations[k] != v {
+			t.Errorf("expected annotation %s=%q, got %q", k, v, svc.Annotations[k])
+		}
+	}
+
+	if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != resources.FrontProxyPort {
+		t.Errorf("expected the Service to expose port %d, got %+v", resources.FrontProxyPort, svc.Spec.Ports)
+	}
+}
+
+func TestExternalURLMatchesExposure(t *testing.T) {
+	nodePort := int32(30443)
+
+	testcases := []struct {
+		name    string
+		spec    operatorkcpiov1alpha1.FrontProxySpec
+		viaPort func(svc *corev1.Service) int32
+	}{
+		{
+			name:    "default LoadBalancer Service",
+			viaPort: func(svc *corev1.Service) int32 { return svc.Spec.Ports[0].Port },
+		},
+		{
+			name: "ClusterIP Service",
+			spec: operatorkcpiov1alpha1.FrontProxySpec{
+				Service: &operatorkcpiov1alpha1.FrontProxyServiceSpec{Type: corev1.ServiceTypeClusterIP},
+			},
+			viaPort: func(svc *corev1.Service) int32 { return svc.Spec.Ports[0].Port },
+		},
+		{
+			name: "NodePort Service",
+			spec: operatorkcpiov1alpha1.FrontProxySpec{
+				Service: &operatorkcpiov1alpha1.FrontProxyServiceSpec{Type: corev1.ServiceTypeNodePort, NodePort: &nodePort},
+			},
+			viaPort: func(svc *corev1.Service) int32 { return svc.Spec.Ports[0].NodePort },
+		},
+		{
+			name: "Ingress",
+			spec: operatorkcpiov1alpha1.FrontProxySpec{
+				Ingress: &operatorkcpiov1alpha1.FrontProxyIngressSpec{},
+			},
+			viaPort: func(*corev1.Service) int32 { return 443 },
+		},
+		{
+			name: "Gateway",
+			spec: operatorkcpiov1alpha1.FrontProxySpec{
+				Gateway: &operatorkcpiov1alpha1.FrontProxyGatewaySpec{
+					ParentRefs: []operatorkcpiov1alpha1.GatewayParentReference{{Name: "gateway"}},
+				},
+			},
+			viaPort: func(*corev1.Service) int32 { return 443 },
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			frontProxy := &operatorkcpiov1alpha1.FrontProxy{
+				ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: "kcp"},
+				Spec:       tc.spec,
+			}
+			rootShard := &operatorkcpiov1alpha1.RootShard{
+				ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "kcp"},
+				Spec:       operatorkcpiov1alpha1.RootShardSpec{Hostname: "kcp.example.com"},
+				Status:     operatorkcpiov1alpha1.RootShardStatus{ExternalPort: resources.GetFrontProxyExternalPort(frontProxy)},
+			}
+
+			svc := &corev1.Service{}
+			MutateService(svc, frontProxy)
+			expected := strconv.Itoa(int(tc.viaPort(svc)))
+
+			for _, externalURL := range []string{
+				resources.GetFrontProxyExternalURL(frontProxy, rootShard),
+				resources.GetRootShardURLs(rootShard).External,
+			} {
+				u, err := url.Parse(externalURL)
+				if err != nil {
+					t.Fatalf("failed to parse external URL %q: %v", externalURL, err)
+				}
+				if u.Hostname() != "kcp.example.com" || u.Port() != expected {
+					t.Errorf("expected external URL on kcp.example.com:%s, got %q", expected, externalURL)
+				}
+			}
+		})
+	}
+}
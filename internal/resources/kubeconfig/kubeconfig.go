@@ -0,0 +1,464 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kubeconfig renders the kubeconfigs generated for Kubeconfig objects.
+package kubeconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+const (
+	// DefaultKubeconfigKey is the default key in the Secret that the kubeconfig is written to.
+	DefaultKubeconfigKey = "kubeconfig"
+	// DefaultContextName is the name of the context pointing at the cluster path of the Kubeconfig's target.
+	DefaultContextName = "default"
+
+	// KubeconfigUIDLabel identifies the ClusterRoleBindings created in kcp and the Secrets copied into other
+	// namespaces for a Kubeconfig. The UID is used, as Kubeconfigs from different namespaces may create objects
+	// with the same name in the same workspace or namespace.
+	KubeconfigUIDLabel = "operator.kcp.io/kubeconfig-uid"
+	// KubeconfigAnnotation is set on copies of a kubeconfig Secret, as namespace/name of the Kubeconfig.
+	KubeconfigAnnotation = "operator.kcp.io/kubeconfig"
+
+	// AdminUsername is the user of the admin kubeconfig generated for every RootShard.
+	AdminUsername = "system:kcp-operator"
+	// AdminValidity is the lifetime of the client certificate in the admin kubeconfig of a RootShard.
+	AdminValidity = 24 * time.Hour
+
+	rootClusterPath = "root"
+)
+
+// Context is a single context of a generated kubeconfig.
+type Context struct {
+	Name        string
+	ClusterPath string
+}
+
+// Credentials are the PEM encoded client certificate and private key of a kubeconfig, and the CA bundle
+// verifying kcp's serving certificates.
+type Credentials struct {
+	CertPEM []byte
+	KeyPEM  []byte
+	CAPEM   []byte
+}
+
+// Layout is the layout of a kubeconfig Secret, with defaults applied. Keys that are not written are empty.
+type Layout struct {
+	KubeconfigKey        string
+	CertificateKey       string
+	PrivateKeyKey        string
+	CABundleKey          string
+	CredentialsMountPath string
+}
+
+// GetLayout returns the layout of the Secret the given Kubeconfig is written to. It fails if keys collide.
+func GetLayout(kc *operatorkcpiov1alpha1.Kubeconfig) (Layout, error) {
+	layout := Layout{KubeconfigKey: DefaultKubeconfigKey}
+
+	if output := kc.Spec.Output; output != nil {
+		layout.KubeconfigKey = withDefault(output.KubeconfigKey, DefaultKubeconfigKey)
+		if output.IncludeCertificate {
+			layout.CertificateKey = withDefault(output.CertificateKey, corev1.TLSCertKey)
+			layout.PrivateKeyKey = withDefault(output.PrivateKeyKey, corev1.TLSPrivateKeyKey)
+		}
+		if output.IncludeCABundle {
+			layout.CABundleKey = withDefault(output.CABundleKey, corev1.ServiceAccountRootCAKey)
+		}
+		layout.CredentialsMountPath = output.CredentialsMountPath
+	}
+
+	keys := sets.New[string]()
+	for _, key := range layout.keys() {
+		if keys.Has(key) {
+			return Layout{}, fmt.Errorf("key %q is used more than once", key)
+		}
+		keys.Insert(key)
+	}
+
+	return layout, nil
+}
+
+func (l Layout) keys() []string {
+	var keys []string
+	for _, key := range []string{l.KubeconfigKey, l.CertificateKey, l.PrivateKeyKey, l.CABundleKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func withDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+// GetContexts returns all contexts of the given Kubeconfig, starting with the default context.
+func GetContexts(kc *operatorkcpiov1alpha1.Kubeconfig) []Context {
+	contexts := []Context{{Name: DefaultContextName, ClusterPath: kc.Spec.Target.ClusterPath}}
+	for _, c := range kc.Spec.Contexts {
+		contexts = append(contexts, Context{Name: c.Name, ClusterPath: c.ClusterPath})
+	}
+
+	return contexts
+}
+
+// NewRootShardAdmin returns the Kubeconfig describing the admin kubeconfig of the given RootShard, a
+// system:masters credential pointing at its root workspace. It only exists in memory and is rendered like any
+// other Kubeconfig, into the Secret named by resources.GetRootShardAdminKubeconfigName.
+func NewRootShardAdmin(rootShard *operatorkcpiov1alpha1.RootShard) *operatorkcpiov1alpha1.Kubeconfig {
+	return &operatorkcpiov1alpha1.Kubeconfig{
+		ObjectMeta: metav1.ObjectMeta{Name: rootShard.Name, Namespace: rootShard.Namespace},
+		Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+			Target: operatorkcpiov1alpha1.KubeconfigTarget{
+				RootShardRef: &corev1.LocalObjectReference{Name: rootShard.Name},
+				ClusterPath:  rootClusterPath,
+			},
+			Username:  AdminUsername,
+			Groups:    []string{"system:masters"},
+			Validity:  metav1.Duration{Duration: AdminValidity},
+			SecretRef: corev1.LocalObjectReference{Name: resources.GetRootShardAdminKubeconfigName(rootShard)},
+		},
+	}
+}
+
+// GetServerURL returns the URL of the logical cluster at clusterPath behind baseURL. An empty path
+// returns baseURL itself.
+func GetServerURL(baseURL, clusterPath string) (string, error) {
+	if clusterPath == "" {
+		return baseURL, nil
+	}
+
+	return url.JoinPath(baseURL, "clusters", clusterPath)
+}
+
+// New renders the kubeconfig for the given Kubeconfig. Every context gets its own cluster entry of the same
+// name, while all of them share a single user authenticating with the given client certificate. The credentials
+// are embedded, unless the layout references them as files.
+func New(kc *operatorkcpiov1alpha1.Kubeconfig, layout Layout, baseURL string, creds Credentials) (*clientcmdapi.Config, error) {
+	config := clientcmdapi.NewConfig()
+
+	authInfo := &clientcmdapi.AuthInfo{
+		ClientCertificateData: creds.CertPEM,
+		ClientKeyData:         creds.KeyPEM,
+	}
+	if layout.CredentialsMountPath != "" {
+		authInfo = &clientcmdapi.AuthInfo{
+			ClientCertificate: path.Join(layout.CredentialsMountPath, layout.CertificateKey),
+			ClientKey:         path.Join(layout.CredentialsMountPath, layout.PrivateKeyKey),
+		}
+	}
+
+	for _, c := range GetContexts(kc) {
+		server, err := GetServerURL(baseURL, c.ClusterPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server URL %q: %w", baseURL, err)
+		}
+
+		cluster := &clientcmdapi.Cluster{
+			Server:                   server,
+			CertificateAuthorityData: creds.CAPEM,
+		}
+		if layout.CredentialsMountPath != "" {
+			cluster = &clientcmdapi.Cluster{
+				Server:               server,
+				CertificateAuthority: path.Join(layout.CredentialsMountPath, layout.CABundleKey),
+			}
+		}
+
+		config.Clusters[c.Name] = cluster
+		config.Contexts[c.Name] = &clientcmdapi.Context{
+			Cluster:  c.Name,
+			AuthInfo: kc.Spec.Username,
+		}
+	}
+
+	config.AuthInfos[kc.Spec.Username] = authInfo
+	config.CurrentContext = DefaultContextName
+
+	return config, nil
+}
+
+// NewSecretData renders the data of the Secret the kubeconfig of the given Kubeconfig is written to.
+func NewSecretData(kc *operatorkcpiov1alpha1.Kubeconfig, layout Layout, baseURL string, creds Credentials) (map[string][]byte, error) {
+	config, err := New(kc, layout, baseURL, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := clientcmd.Write(*config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+
+	data := map[string][]byte{layout.KubeconfigKey: kubeconfig}
+	if layout.CertificateKey != "" {
+		data[layout.CertificateKey] = creds.CertPEM
+		data[layout.PrivateKeyKey] = creds.KeyPEM
+	}
+	if layout.CABundleKey != "" {
+		data[layout.CABundleKey] = creds.CAPEM
+	}
+
+	return data, nil
+}
+
+// ClientCertificate returns the client certificate from Secret data rendered by NewSecretData.
+func ClientCertificate(layout Layout, data map[string][]byte) (*x509.Certificate, error) {
+	var certPEM []byte
+
+	if layout.CertificateKey != "" {
+		certPEM = data[layout.CertificateKey]
+	} else {
+		config, err := clientcmd.Load(data[layout.KubeconfigKey])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+		}
+
+		authInfo, err := currentAuthInfo(config)
+		if err != nil {
+			return nil, err
+		}
+		certPEM = authInfo.ClientCertificateData
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("no PEM encoded client certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func currentAuthInfo(config *clientcmdapi.Config) (*clientcmdapi.AuthInfo, error) {
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("current context %q not found", config.CurrentContext)
+	}
+
+	authInfo, ok := config.AuthInfos[context.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("user %q not found", context.AuthInfo)
+	}
+
+	return authInfo, nil
+}
+
+// GetRenewBefore returns how long before their expiry the client certificates of the given Kubeconfig are
+// renewed: spec.renewBefore, or a third of spec.validity if it is not set.
+func GetRenewBefore(kc *operatorkcpiov1alpha1.Kubeconfig) time.Duration {
+	if kc.Spec.RenewBefore != nil {
+		return kc.Spec.RenewBefore.Duration
+	}
+
+	return kc.Spec.Validity.Duration / 3
+}
+
+// RenewalTime returns the time after which a client certificate is renewed, renewBefore ahead of its expiry.
+func RenewalTime(cert *x509.Certificate, renewBefore time.Duration) time.Time {
+	return cert.NotAfter.Add(-renewBefore)
+}
+
+// GetCertificateStatus describes the given client certificate for the Kubeconfig's status.
+func GetCertificateStatus(kc *operatorkcpiov1alpha1.Kubeconfig, cert *x509.Certificate) *operatorkcpiov1alpha1.KubeconfigCertificateStatus {
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	return &operatorkcpiov1alpha1.KubeconfigCertificateStatus{
+		NotBefore:         metav1.NewTime(cert.NotBefore),
+		NotAfter:          metav1.NewTime(cert.NotAfter),
+		RenewalTime:       metav1.NewTime(RenewalTime(cert, GetRenewBefore(kc))),
+		SerialNumber:      cert.SerialNumber.Text(16),
+		SHA256Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}
+}
+
+// NeedsUpdate returns true if the existing Secret data does not match the layout, if the kubeconfig in it
+// differs from the desired one in its clusters, contexts or file references, if the client certificate does not
+// match the Kubeconfig's user and groups or has not been signed by ca, or if the certificate is due for renewal.
+func NeedsUpdate(existing map[string][]byte, kc *operatorkcpiov1alpha1.Kubeconfig, layout Layout, baseURL string, caPEM []byte, ca *x509.Certificate, now time.Time) bool {
+	desired, err := New(kc, layout, baseURL, Credentials{CAPEM: caPEM})
+	if err != nil {
+		return true
+	}
+
+	if !sets.KeySet(existing).Equal(sets.New(layout.keys()...)) {
+		return true
+	}
+
+	if layout.CABundleKey != "" && !bytes.Equal(existing[layout.CABundleKey], caPEM) {
+		return true
+	}
+
+	current, err := clientcmd.Load(existing[layout.KubeconfigKey])
+	if err != nil || !configMatches(current, desired) {
+		return true
+	}
+
+	cert, err := ClientCertificate(layout, existing)
+	if err != nil {
+		return true
+	}
+
+	if cert.Subject.CommonName != kc.Spec.Username || !slices.Equal(cert.Subject.Organization, kc.Spec.Groups) {
+		return true
+	}
+
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		return true
+	}
+
+	return !now.Before(RenewalTime(cert, GetRenewBefore(kc)))
+}
+
+// configMatches compares everything but embedded credentials of two kubeconfigs rendered by New.
+func configMatches(existing, desired *clientcmdapi.Config) bool {
+	if existing.CurrentContext != desired.CurrentContext || len(existing.Clusters) != len(desired.Clusters) ||
+		len(existing.Contexts) != len(desired.Contexts) || len(existing.AuthInfos) != len(desired.AuthInfos) {
+		return false
+	}
+
+	for name, cluster := range desired.Clusters {
+		c, ok := existing.Clusters[name]
+		if !ok || c.Server != cluster.Server || c.CertificateAuthority != cluster.CertificateAuthority ||
+			!bytes.Equal(c.CertificateAuthorityData, cluster.CertificateAuthorityData) {
+			return false
+		}
+	}
+
+	for name, context := range desired.Contexts {
+		c, ok := existing.Contexts[name]
+		if !ok || c.Cluster != context.Cluster || c.AuthInfo != context.AuthInfo {
+			return false
+		}
+	}
+
+	for name, authInfo := range desired.AuthInfos {
+		a, ok := existing.AuthInfos[name]
+		if !ok || a.ClientCertificate != authInfo.ClientCertificate || a.ClientKey != authInfo.ClientKey {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetCASecretName returns the name of the Secret holding the CA that signs the client certificates of the
+// given Kubeconfig.
+func GetCASecretName(kc *operatorkcpiov1alpha1.Kubeconfig) string {
+	return fmt.Sprintf("%s-kubeconfig-ca", kc.Name)
+}
+
+// GetPermissionsClusterPath returns the logical cluster path of the workspace that the ClusterRoleBindings of
+// the given Kubeconfig are created in. It must only be called if spec.permissions is set.
+func GetPermissionsClusterPath(kc *operatorkcpiov1alpha1.Kubeconfig) string {
+	switch {
+	case kc.Spec.Permissions.ClusterPath != "":
+		return kc.Spec.Permissions.ClusterPath
+	case kc.Spec.Target.ClusterPath != "":
+		return kc.Spec.Target.ClusterPath
+	default:
+		return rootClusterPath
+	}
+}
+
+// GetClusterRoleBindingName returns the name of the ClusterRoleBinding binding clusterRole for the given Kubeconfig.
+func GetClusterRoleBindingName(kc *operatorkcpiov1alpha1.Kubeconfig, clusterRole string) string {
+	return fmt.Sprintf("kubeconfig:%s:%s:%s", kc.Namespace, kc.Name, clusterRole)
+}
+
+// MutateClusterRoleBinding binds clusterRole to the user and groups of the given Kubeconfig.
+func MutateClusterRoleBinding(crb *rbacv1.ClusterRoleBinding, kc *operatorkcpiov1alpha1.Kubeconfig, clusterRole string) {
+	if crb.Labels == nil {
+		crb.Labels = map[string]string{}
+	}
+	crb.Labels[KubeconfigUIDLabel] = string(kc.UID)
+
+	// the role of an existing binding cannot be changed, but as the binding is named after it, it never has to
+	crb.RoleRef = rbacv1.RoleRef{
+		APIGroup: rbacv1.GroupName,
+		Kind:     "ClusterRole",
+		Name:     clusterRole,
+	}
+
+	crb.Subjects = []rbacv1.Subject{{
+		APIGroup: rbacv1.GroupName,
+		Kind:     rbacv1.UserKind,
+		Name:     kc.Spec.Username,
+	}}
+	for _, group := range kc.Spec.Groups {
+		crb.Subjects = append(crb.Subjects, rbacv1.Subject{
+			APIGroup: rbacv1.GroupName,
+			Kind:     rbacv1.GroupKind,
+			Name:     group,
+		})
+	}
+}
+
+// MutateMirroredSecret turns secret into a copy of the given Kubeconfig's Secret with the given data.
+func MutateMirroredSecret(secret *corev1.Secret, kc *operatorkcpiov1alpha1.Kubeconfig, data map[string][]byte) {
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[KubeconfigUIDLabel] = string(kc.UID)
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[KubeconfigAnnotation] = kc.Namespace + "/" + kc.Name
+
+	secret.Data = data
+}
+
+// GetMirroredKubeconfig returns the Kubeconfig that the given Secret is a copy of.
+func GetMirroredKubeconfig(secret metav1.Object) (types.NamespacedName, bool) {
+	if secret.GetLabels()[KubeconfigUIDLabel] == "" {
+		return types.NamespacedName{}, false
+	}
+
+	namespace, name, ok := strings.Cut(secret.GetAnnotations()[KubeconfigAnnotation], "/")
+	if !ok {
+		return types.NamespacedName{}, false
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
@@ -0,0 +1,500 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+func TestNew(t *testing.T) {
+	kc := &operatorkcpiov1alpha1.Kubeconfig{
+		Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+			Target:   operatorkcpiov1alpha1.KubeconfigTarget{ClusterPath: "root:org:team"},
+			Username: "alice",
+			Contexts: []operatorkcpiov1alpha1.KubeconfigContext{
+				{Name: "root", ClusterPath: "root"},
+			},
+		},
+	}
+
+	config, err := New(kc, Layout{KubeconfigKey: DefaultKubeconfigKey}, "https://kcp.example.com:443", Credentials{CertPEM: []byte("cert"), KeyPEM: []byte("key"), CAPEM: []byte("ca")})
+	if err != nil {
+		t.Fatalf("failed to render kubeconfig: %v", err)
+	}
+
+	expectedServers := map[string]string{
+		DefaultContextName: "https://kcp.example.com:443/clusters/root:org:team",
+		"root":             "https://kcp.example.com:443/clusters/root",
+	}
+	if len(config.Clusters) != len(expectedServers) {
+		t.Fatalf("expected %d clusters, got %d", len(expectedServers), len(config.Clusters))
+	}
+	for name, server := range expectedServers {
+		if config.Clusters[name] == nil || config.Clusters[name].Server != server {
+			t.Errorf("expected cluster %q to point at %q, got %+v", name, server, config.Clusters[name])
+		}
+		if config.Contexts[name] == nil || config.Contexts[name].AuthInfo != "alice" {
+			t.Errorf("expected context %q to use user alice, got %+v", name, config.Contexts[name])
+		}
+	}
+
+	if config.CurrentContext != DefaultContextName {
+		t.Errorf("expected current context %q, got %q", DefaultContextName, config.CurrentContext)
+	}
+
+	if _, err := clientcmd.Write(*config); err != nil {
+		t.Errorf("failed to serialize kubeconfig: %v", err)
+	}
+}
+
+func TestGetServerURL(t *testing.T) {
+	testcases := []struct {
+		baseURL     string
+		clusterPath string
+		expected    string
+	}{
+		{baseURL: "https://kcp.example.com:443", expected: "https://kcp.example.com:443"},
+		{baseURL: "https://kcp.example.com:443", clusterPath: "root", expected: "https://kcp.example.com:443/clusters/root"},
+		{baseURL: "https://kcp.example.com:443/", clusterPath: "root:org", expected: "https://kcp.example.com:443/clusters/root:org"},
+	}
+
+	for _, tc := range testcases {
+		server, err := GetServerURL(tc.baseURL, tc.clusterPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if server != tc.expected {
+			t.Errorf("expected %q for %q and %q, got %q", tc.expected, tc.baseURL, tc.clusterPath, server)
+		}
+	}
+}
+
+func TestNeedsUpdate(t *testing.T) {
+	now := time.Now()
+	kc := &operatorkcpiov1alpha1.Kubeconfig{
+		Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+			Username: "alice",
+			Groups:   []string{"team"},
+			Validity: metav1.Duration{Duration: 3 * time.Hour},
+		},
+	}
+
+	ca, caKey := newCA(t)
+	otherCA, otherCAKey := newCA(t)
+
+	testcases := []struct {
+		name      string
+		modify    func(kc *operatorkcpiov1alpha1.Kubeconfig)
+		notBefore time.Time
+		notAfter  time.Time
+		otherCA   bool
+		expected  bool
+	}{
+		{
+			name:      "up to date",
+			notBefore: now.Add(-time.Hour),
+			notAfter:  now.Add(2 * time.Hour),
+		},
+		{
+			name:      "signed by another CA",
+			notBefore: now.Add(-time.Hour),
+			notAfter:  now.Add(2 * time.Hour),
+			otherCA:   true,
+			expected:  true,
+		},
+		{
+			name:      "due for renewal",
+			notBefore: now.Add(-2 * time.Hour),
+			notAfter:  now.Add(time.Hour),
+			expected:  true,
+		},
+		{
+			name: "within configured renewal window",
+			modify: func(kc *operatorkcpiov1alpha1.Kubeconfig) {
+				kc.Spec.RenewBefore = &metav1.Duration{Duration: 2 * time.Hour}
+			},
+			notBefore: now.Add(-time.Hour),
+			notAfter:  now.Add(2 * time.Hour),
+			expected:  true,
+		},
+		{
+			name: "new context",
+			modify: func(kc *operatorkcpiov1alpha1.Kubeconfig) {
+				kc.Spec.Contexts = []operatorkcpiov1alpha1.KubeconfigContext{{Name: "other", ClusterPath: "root:other"}}
+			},
+			notBefore: now.Add(-time.Hour),
+			notAfter:  now.Add(2 * time.Hour),
+			expected:  true,
+		},
+		{
+			name:      "changed cluster path",
+			modify:    func(kc *operatorkcpiov1alpha1.Kubeconfig) { kc.Spec.Target.ClusterPath = "root:org" },
+			notBefore: now.Add(-time.Hour),
+			notAfter:  now.Add(2 * time.Hour),
+			expected:  true,
+		},
+		{
+			name: "certificate written to separate keys",
+			modify: func(kc *operatorkcpiov1alpha1.Kubeconfig) {
+				kc.Spec.Output = &operatorkcpiov1alpha1.KubeconfigOutput{IncludeCertificate: true}
+			},
+			notBefore: now.Add(-time.Hour),
+			notAfter:  now.Add(2 * time.Hour),
+			expected:  true,
+		},
+		{
+			name:      "changed groups",
+			modify:    func(kc *operatorkcpiov1alpha1.Kubeconfig) { kc.Spec.Groups = []string{"admins"} },
+			notBefore: now.Add(-time.Hour),
+			notAfter:  now.Add(2 * time.Hour),
+			expected:  true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			signer, signerKey := ca, caKey
+			if tc.otherCA {
+				signer, signerKey = otherCA, otherCAKey
+			}
+
+			certPEM := newCertificate(t, signer, signerKey, kc.Spec.Username, kc.Spec.Groups, tc.notBefore, tc.notAfter)
+			existing, err := NewSecretData(kc, Layout{KubeconfigKey: DefaultKubeconfigKey}, "https://kcp.example.com:443", Credentials{CertPEM: certPEM, KeyPEM: []byte("key"), CAPEM: []byte("ca")})
+			if err != nil {
+				t.Fatalf("failed to render existing Secret data: %v", err)
+			}
+
+			desired := kc.DeepCopy()
+			if tc.modify != nil {
+				tc.modify(desired)
+			}
+			layout, err := GetLayout(desired)
+			if err != nil {
+				t.Fatalf("invalid layout: %v", err)
+			}
+
+			if needsUpdate := NeedsUpdate(existing, desired, layout, "https://kcp.example.com:443", []byte("ca"), ca, now); needsUpdate != tc.expected {
+				t.Errorf("expected NeedsUpdate to return %v, got %v", tc.expected, needsUpdate)
+			}
+		})
+	}
+}
+
+func TestNewSecretData(t *testing.T) {
+	kc := &operatorkcpiov1alpha1.Kubeconfig{
+		Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+			Username: "alice",
+			Output: &operatorkcpiov1alpha1.KubeconfigOutput{
+				KubeconfigKey:        "config",
+				IncludeCertificate:   true,
+				IncludeCABundle:      true,
+				CABundleKey:          "kcp-ca.crt",
+				CredentialsMountPath: "/etc/kcp",
+			},
+		},
+	}
+	creds := Credentials{CertPEM: []byte("cert"), KeyPEM: []byte("key"), CAPEM: []byte("ca")}
+
+	layout, err := GetLayout(kc)
+	if err != nil {
+		t.Fatalf("invalid layout: %v", err)
+	}
+
+	data, err := NewSecretData(kc, layout, "https://kcp.example.com:443", creds)
+	if err != nil {
+		t.Fatalf("failed to render Secret data: %v", err)
+	}
+
+	expected := map[string]string{"tls.crt": "cert", "tls.key": "key", "kcp-ca.crt": "ca"}
+	if len(data) != len(expected)+1 {
+		t.Errorf("expected %d keys, got %d", len(expected)+1, len(data))
+	}
+	for key, value := range expected {
+		if string(data[key]) != value {
+			t.Errorf("expected key %q to contain %q, got %q", key, value, data[key])
+		}
+	}
+
+	config, err := clientcmd.Load(data["config"])
+	if err != nil {
+		t.Fatalf("failed to parse kubeconfig: %v", err)
+	}
+
+	authInfo := config.AuthInfos["alice"]
+	if authInfo.ClientCertificate != "/etc/kcp/tls.crt" || authInfo.ClientKey != "/etc/kcp/tls.key" || len(authInfo.ClientCertificateData) > 0 {
+		t.Errorf("expected credentials to be referenced as files, got %+v", authInfo)
+	}
+	if cluster := config.Clusters[DefaultContextName]; cluster.CertificateAuthority != "/etc/kcp/kcp-ca.crt" || len(cluster.CertificateAuthorityData) > 0 {
+		t.Errorf("expected CA bundle to be referenced as file, got %+v", cluster)
+	}
+}
+
+func TestGetLayout(t *testing.T) {
+	testcases := []struct {
+		name          string
+		output        *operatorkcpiov1alpha1.KubeconfigOutput
+		expected      Layout
+		expectedError bool
+	}{
+		{
+			name:     "defaults",
+			expected: Layout{KubeconfigKey: "kubeconfig"},
+		},
+		{
+			name:     "separate certificates",
+			output:   &operatorkcpiov1alpha1.KubeconfigOutput{IncludeCertificate: true, IncludeCABundle: true},
+			expected: Layout{KubeconfigKey: "kubeconfig", CertificateKey: "tls.crt", PrivateKeyKey: "tls.key", CABundleKey: "ca.crt"},
+		},
+		{
+			name:          "colliding keys",
+			output:        &operatorkcpiov1alpha1.KubeconfigOutput{KubeconfigKey: "tls.crt", IncludeCertificate: true},
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			kc := &operatorkcpiov1alpha1.Kubeconfig{Spec: operatorkcpiov1alpha1.KubeconfigSpec{Output: tc.output}}
+
+			layout, err := GetLayout(kc)
+			if (err != nil) != tc.expectedError {
+				t.Fatalf("expected error: %v, got %v", tc.expectedError, err)
+			}
+			if layout != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, layout)
+			}
+		})
+	}
+}
+
+func TestGetRenewBefore(t *testing.T) {
+	testcases := []struct {
+		name        string
+		renewBefore *metav1.Duration
+		expected    time.Duration
+	}{
+		{
+			name:     "defaults to a third of the validity",
+			expected: 8 * time.Hour,
+		},
+		{
+			name:        "configured",
+			renewBefore: &metav1.Duration{Duration: time.Hour},
+			expected:    time.Hour,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			kc := &operatorkcpiov1alpha1.Kubeconfig{
+				Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+					Validity:    metav1.Duration{Duration: 24 * time.Hour},
+					RenewBefore: tc.renewBefore,
+				},
+			}
+
+			if renewBefore := GetRenewBefore(kc); renewBefore != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, renewBefore)
+			}
+		})
+	}
+}
+
+func TestGetCertificateStatus(t *testing.T) {
+	ca, caKey := newCA(t)
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	block, _ := pem.Decode(newCertificate(t, ca, caKey, "admin", nil, notBefore, notBefore.Add(30*time.Hour)))
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	kc := &operatorkcpiov1alpha1.Kubeconfig{
+		Spec: operatorkcpiov1alpha1.KubeconfigSpec{Validity: metav1.Duration{Duration: 30 * time.Hour}},
+	}
+	status := GetCertificateStatus(kc, cert)
+
+	if !status.NotBefore.Time.Equal(notBefore) {
+		t.Errorf("expected notBefore %v, got %v", notBefore, status.NotBefore)
+	}
+	if expected := notBefore.Add(30 * time.Hour); !status.NotAfter.Time.Equal(expected) {
+		t.Errorf("expected notAfter %v, got %v", expected, status.NotAfter)
+	}
+	if expected := notBefore.Add(20 * time.Hour); !status.RenewalTime.Time.Equal(expected) {
+		t.Errorf("expected renewal at %v, got %v", expected, status.RenewalTime)
+	}
+	if status.SerialNumber != "2" {
+		t.Errorf("expected serial number %q, got %q", "2", status.SerialNumber)
+	}
+
+	fingerprint := sha256.Sum256(block.Bytes)
+	if expected := hex.EncodeToString(fingerprint[:]); status.SHA256Fingerprint != expected {
+		t.Errorf("expected fingerprint %q, got %q", expected, status.SHA256Fingerprint)
+	}
+}
+
+func TestGetPermissionsClusterPath(t *testing.T) {
+	testcases := []struct {
+		name       string
+		targetPath string
+		permsPath  string
+		expected   string
+	}{
+		{name: "defaults to root", expected: "root"},
+		{name: "defaults to target path", targetPath: "root:org", expected: "root:org"},
+		{name: "explicit path", targetPath: "root:org", permsPath: "root:org:team", expected: "root:org:team"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			kc := &operatorkcpiov1alpha1.Kubeconfig{
+				Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+					Target:      operatorkcpiov1alpha1.KubeconfigTarget{ClusterPath: tc.targetPath},
+					Permissions: &operatorkcpiov1alpha1.KubeconfigPermissions{ClusterPath: tc.permsPath},
+				},
+			}
+
+			if path := GetPermissionsClusterPath(kc); path != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, path)
+			}
+		})
+	}
+}
+
+func TestMutateClusterRoleBinding(t *testing.T) {
+	kc := &operatorkcpiov1alpha1.Kubeconfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "kcp", UID: "1234"},
+		Spec: operatorkcpiov1alpha1.KubeconfigSpec{
+			Username: "alice",
+			Groups:   []string{"team"},
+		},
+	}
+
+	crb := &rbacv1.ClusterRoleBinding{}
+	MutateClusterRoleBinding(crb, kc, "admin")
+
+	if crb.Labels[KubeconfigUIDLabel] != "1234" {
+		t.Errorf("expected UID label, got %v", crb.Labels)
+	}
+	if crb.RoleRef.Kind != "ClusterRole" || crb.RoleRef.Name != "admin" {
+		t.Errorf("expected binding to ClusterRole admin, got %+v", crb.RoleRef)
+	}
+
+	expectedSubjects := []rbacv1.Subject{
+		{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: "alice"},
+		{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "team"},
+	}
+	if !slices.Equal(crb.Subjects, expectedSubjects) {
+		t.Errorf("expected subjects %+v, got %+v", expectedSubjects, crb.Subjects)
+	}
+}
+
+func TestMutateMirroredSecret(t *testing.T) {
+	kc := &operatorkcpiov1alpha1.Kubeconfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice", Namespace: "kcp", UID: "1234"},
+	}
+
+	secret := &corev1.Secret{}
+	MutateMirroredSecret(secret, kc, map[string][]byte{DefaultKubeconfigKey: []byte("config")})
+
+	if secret.Labels[KubeconfigUIDLabel] != "1234" {
+		t.Errorf("expected UID label, got %v", secret.Labels)
+	}
+	if string(secret.Data[DefaultKubeconfigKey]) != "config" {
+		t.Errorf("expected data to be copied, got %v", secret.Data)
+	}
+
+	owner, ok := GetMirroredKubeconfig(secret)
+	if !ok || owner.Namespace != "kcp" || owner.Name != "alice" {
+		t.Errorf("expected copy of kcp/alice, got %v (%v)", owner, ok)
+	}
+
+	if _, ok := GetMirroredKubeconfig(&corev1.Secret{}); ok {
+		t.Error("expected unrelated Secret not to be a copy")
+	}
+}
+
+func newCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	return cert, key
+}
+
+func newCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, commonName string, groups []string, notBefore, notAfter time.Time) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName, Organization: groups},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
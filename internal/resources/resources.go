@@ -0,0 +1,543 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resources contains helpers shared by the packages that render the Kubernetes
+// objects (Deployments, Services, ...) making up a kcp setup.
+package resources
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+const (
+	// ImageRepository is the default container image repository for kcp components.
+	ImageRepository = "ghcr.io/kcp-dev/kcp"
+	// ImageTag is the default container image tag for kcp components, i.e. the latest
+	// kcp release supported by the operator.
+	ImageTag = "v0.26.0"
+
+	// ShardPort is the port that kcp shards serve their (secure) API on.
+	ShardPort = 6443
+	// FrontProxyPort is the port that kcp-front-proxy serves on.
+	FrontProxyPort = 6443
+	// ExternalPort is the port that the kcp setup is reachable on from outside the cluster when it is
+	// exposed through an Ingress or Gateway in front of the front-proxy Service.
+	ExternalPort = 443
+	// VirtualWorkspacesPort is the port that standalone virtual-workspaces servers serve on.
+	VirtualWorkspacesPort = 6444
+	// CacheServerPort is the port that standalone cache servers serve on.
+	CacheServerPort = 6443
+
+	// DefaultEtcdKeyPrefix is the etcd key prefix kcp stores its data under by default.
+	DefaultEtcdKeyPrefix = "/registry"
+
+	// EtcdCertificateMountPath is where the etcd client certificate is mounted into kcp containers.
+	EtcdCertificateMountPath = "/etc/etcd/tls"
+	// DataMountPath is the root directory for kcp's runtime data.
+	DataMountPath = "/etc/kcp"
+	// ServerCertificateMountPath is where the serving certificate is mounted into kcp containers.
+	ServerCertificateMountPath = "/etc/kcp/tls/server"
+	// ClientCAMountPath is where the bundle of trusted client CAs is mounted into kcp containers.
+	ClientCAMountPath = "/etc/kcp/tls/client-ca"
+	// ClientCABundleKey is the key of the client CA bundle Secret holding the CA certificates.
+	ClientCABundleKey = "ca.crt"
+
+	// ClientCALabel marks Secrets holding a CA that signs client certificates for generated kubeconfigs. kcp
+	// only trusts these CAs while the Secret exists.
+	ClientCALabel = "operator.kcp.io/client-ca"
+	// ClientCARootShardAnnotation names the RootShard ("<namespace>/<name>") whose kcp setup trusts a client CA.
+	ClientCARootShardAnnotation = "operator.kcp.io/root-shard"
+
+	appNameLabel      = "app.kubernetes.io/name"
+	appInstanceLabel  = "app.kubernetes.io/instance"
+	appComponentLabel = "app.kubernetes.io/component"
+	appManagedByLabel = "app.kubernetes.io/managed-by"
+
+	etcdBackupComponent = "etcd-backup"
+)
+
+// GetRootShardDeploymentName returns the name of the Deployment (and Service) running the given root shard.
+func GetRootShardDeploymentName(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	return fmt.Sprintf("%s-kcp", rootShard.Name)
+}
+
+// GetShardDeploymentName returns the name of the Deployment (and Service) running the given shard.
+func GetShardDeploymentName(shard *operatorkcpiov1alpha1.Shard) string {
+	return fmt.Sprintf("%s-shard-kcp", shard.Name)
+}
+
+// GetFrontProxyDeploymentName returns the name of the Deployment (and Service) running the given front-proxy.
+func GetFrontProxyDeploymentName(frontProxy *operatorkcpiov1alpha1.FrontProxy) string {
+	return fmt.Sprintf("%s-front-proxy", frontProxy.Name)
+}
+
+// GetFrontProxyConfigName returns the name of the ConfigMap holding the front-proxy's path mapping.
+func GetFrontProxyConfigName(frontProxy *operatorkcpiov1alpha1.FrontProxy) string {
+	return fmt.Sprintf("%s-front-proxy-config", frontProxy.Name)
+}
+
+// GetCacheServerDeploymentName returns the name of the Deployment (and Service) running the given cache server.
+func GetCacheServerDeploymentName(cacheServer *operatorkcpiov1alpha1.CacheServer) string {
+	return fmt.Sprintf("%s-cache-server", cacheServer.Name)
+}
+
+// GetEtcdBackupCronJobName returns the name of the CronJob taking the snapshots of the given EtcdBackup.
+func GetEtcdBackupCronJobName(backup *operatorkcpiov1alpha1.EtcdBackup) string {
+	return fmt.Sprintf("%s-etcd-backup", backup.Name)
+}
+
+// GetRootShardURLs returns the URLs the given root shard is configured with.
+func GetRootShardURLs(rootShard *operatorkcpiov1alpha1.RootShard) operatorkcpiov1alpha1.ShardURLs {
+	return shardURLs(GetRootShardDeploymentName(rootShard), rootShard.Namespace, &rootShard.Spec.CommonShardSpec, rootShard)
+}
+
+// GetShardURLs returns the URLs the given shard is configured with. Shards are exposed
+// externally through the front-proxy of the kcp setup formed around rootShard.
+func GetShardURLs(shard *operatorkcpiov1alpha1.Shard, rootShard *operatorkcpiov1alpha1.RootShard) operatorkcpiov1alpha1.ShardURLs {
+	return shardURLs(GetShardDeploymentName(shard), shard.Namespace, &shard.Spec.CommonShardSpec, rootShard)
+}
+
+func shardURLs(deploymentName, namespace string, spec *operatorkcpiov1alpha1.CommonShardSpec, rootShard *operatorkcpiov1alpha1.RootShard) operatorkcpiov1alpha1.ShardURLs {
+	internal := serviceURL(deploymentName, namespace, ShardPort)
+
+	urls := operatorkcpiov1alpha1.ShardURLs{
+		Internal:          internal,
+		External:          externalURL(rootShard),
+		VirtualWorkspaces: internal,
+	}
+
+	if IsExternalVirtualWorkspaces(spec, rootShard) {
+		urls.VirtualWorkspaces = serviceURL(GetVirtualWorkspacesName(deploymentName), namespace, VirtualWorkspacesPort)
+	}
+
+	return urls
+}
+
+// GetURLArgs returns the kcp command line flags that configure a shard with the given URLs.
+func GetURLArgs(urls operatorkcpiov1alpha1.ShardURLs) []string {
+	return []string{
+		fmt.Sprintf("--shard-base-url=%s", urls.Internal),
+		fmt.Sprintf("--shard-external-url=%s", urls.External),
+		fmt.Sprintf("--shard-virtual-workspace-url=%s", urls.VirtualWorkspaces),
+	}
+}
+
+func serviceURL(name, namespace string, port int) string {
+	return fmt.Sprintf("https://%s.%s.svc.cluster.local:%d", name, namespace, port)
+}
+
+func externalURL(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	port := rootShard.Status.ExternalPort
+	if port == 0 {
+		port = ExternalPort
+	}
+
+	return fmt.Sprintf("https://%s:%d", rootShard.Spec.Hostname, port)
+}
+
+// GetFrontProxyExternalPort returns the port the given front-proxy is reachable on from outside the
+// cluster: 443 if it is exposed through an Ingress or Gateway, the pinned node port for NodePort
+// Services and the front-proxy Service port otherwise.
+func GetFrontProxyExternalPort(frontProxy *operatorkcpiov1alpha1.FrontProxy) int32 {
+	if frontProxy.Spec.Ingress != nil || frontProxy.Spec.Gateway != nil {
+		return ExternalPort
+	}
+
+	if svc := frontProxy.Spec.Service; svc != nil && svc.Type == corev1.ServiceTypeNodePort && svc.NodePort != nil {
+		return *svc.NodePort
+	}
+
+	return FrontProxyPort
+}
+
+// GetFrontProxyExternalURL returns the URL the given front-proxy is reachable on from outside the cluster.
+func GetFrontProxyExternalURL(frontProxy *operatorkcpiov1alpha1.FrontProxy, rootShard *operatorkcpiov1alpha1.RootShard) string {
+	return fmt.Sprintf("https://%s:%d", rootShard.Spec.Hostname, GetFrontProxyExternalPort(frontProxy))
+}
+
+// GetFrontProxyServiceURL returns the URL of the given front-proxy's Service from within the cluster.
+func GetFrontProxyServiceURL(frontProxy *operatorkcpiov1alpha1.FrontProxy) string {
+	return serviceURL(GetFrontProxyDeploymentName(frontProxy), frontProxy.Namespace, FrontProxyPort)
+}
+
+// GetVirtualWorkspacesName returns the name of the Deployment (and Service) running the standalone
+// virtual-workspaces server for the shard Deployment with the given name.
+func GetVirtualWorkspacesName(shardDeploymentName string) string {
+	return fmt.Sprintf("%s-virtual-workspaces", shardDeploymentName)
+}
+
+// GetCAIssuerName returns the name of the cert-manager Issuer signing certificates with the root shard's CA.
+func GetCAIssuerName(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	return fmt.Sprintf("%s-ca", rootShard.Name)
+}
+
+// GetShardCAIssuerName returns the name of the cert-manager Issuer (and the Secret holding its copy of the
+// root shard's CA) signing certificates for a shard in another namespace than its root shard.
+func GetShardCAIssuerName(shard *operatorkcpiov1alpha1.Shard) string {
+	return fmt.Sprintf("%s-ca", GetShardDeploymentName(shard))
+}
+
+// GetServerCertificateName returns the name of the Certificate (and its Secret) holding the serving
+// certificate for the Deployment with the given name.
+func GetServerCertificateName(deploymentName string) string {
+	return fmt.Sprintf("%s-server", deploymentName)
+}
+
+// GetClientCABundleName returns the name of the Secret holding the client CAs trusted by the Deployment
+// with the given name.
+func GetClientCABundleName(deploymentName string) string {
+	return fmt.Sprintf("%s-client-ca", deploymentName)
+}
+
+// GetRootShardAdminKubeconfigName returns the name of the Secret holding the admin kubeconfig of the given
+// root shard.
+func GetRootShardAdminKubeconfigName(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	return fmt.Sprintf("%s-admin-kubeconfig", rootShard.Name)
+}
+
+// GetBreakGlassName returns the name of the Role and RoleBinding granting access to the admin kubeconfig of
+// the given root shard.
+func GetBreakGlassName(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	return fmt.Sprintf("%s-break-glass", rootShard.Name)
+}
+
+// GetBreakGlassUsers returns the sorted users listed in the BreakGlassAnnotation of the given root shard.
+func GetBreakGlassUsers(rootShard *operatorkcpiov1alpha1.RootShard) []string {
+	users := sets.New[string]()
+	for _, user := range strings.Split(rootShard.Annotations[operatorkcpiov1alpha1.BreakGlassAnnotation], ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			users.Insert(user)
+		}
+	}
+
+	return sets.List(users)
+}
+
+// GetClientCARootShard returns the key of the RootShard trusting the client CA in the given Secret, if it
+// is one.
+func GetClientCARootShard(secret metav1.Object) (types.NamespacedName, bool) {
+	if secret.GetLabels()[ClientCALabel] != "true" {
+		return types.NamespacedName{}, false
+	}
+
+	namespace, name, ok := strings.Cut(secret.GetAnnotations()[ClientCARootShardAnnotation], "/")
+	if !ok {
+		return types.NamespacedName{}, false
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
+
+// GetServiceDNSNames returns all DNS names that a Service can be reached under from within the cluster.
+func GetServiceDNSNames(name, namespace string) []string {
+	return []string{
+		name,
+		fmt.Sprintf("%s.%s", name, namespace),
+		fmt.Sprintf("%s.%s.svc", name, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", name, namespace),
+	}
+}
+
+// IsExternalVirtualWorkspaces returns true if the shard runs its virtual workspaces in a separate Deployment.
+// The standalone server needs a certificate signed by the root shard's CA, so until rootShard has a CA
+// configured, the virtual workspaces keep being served embedded in the shard.
+func IsExternalVirtualWorkspaces(spec *operatorkcpiov1alpha1.CommonShardSpec, rootShard *operatorkcpiov1alpha1.RootShard) bool {
+	return WantsExternalVirtualWorkspaces(spec) && rootShard.Spec.CARef != nil
+}
+
+// WantsExternalVirtualWorkspaces returns true if the shard is configured to run its virtual workspaces in a
+// separate Deployment, regardless of whether it can do so yet.
+func WantsExternalVirtualWorkspaces(spec *operatorkcpiov1alpha1.CommonShardSpec) bool {
+	return spec.VirtualWorkspaces != nil && spec.VirtualWorkspaces.Mode == operatorkcpiov1alpha1.VirtualWorkspacesModeExternal
+}
+
+// GetRootShardResourceLabels returns the labels applied to all objects belonging to a root shard.
+func GetRootShardResourceLabels(rootShard *operatorkcpiov1alpha1.RootShard) map[string]string {
+	return componentLabels("rootshard", rootShard.Name)
+}
+
+// GetShardResourceLabels returns the labels applied to all objects belonging to a shard.
+func GetShardResourceLabels(shard *operatorkcpiov1alpha1.Shard) map[string]string {
+	return componentLabels("shard", shard.Name)
+}
+
+// GetFrontProxyResourceLabels returns the labels applied to all objects belonging to a front-proxy.
+func GetFrontProxyResourceLabels(frontProxy *operatorkcpiov1alpha1.FrontProxy) map[string]string {
+	return componentLabels("front-proxy", frontProxy.Name)
+}
+
+// GetCacheServerResourceLabels returns the labels applied to all objects belonging to a cache server.
+func GetCacheServerResourceLabels(cacheServer *operatorkcpiov1alpha1.CacheServer) map[string]string {
+	return componentLabels("cache-server", cacheServer.Name)
+}
+
+// GetEtcdBackupResourceLabels returns the labels applied to all objects (including Jobs) belonging to an EtcdBackup.
+func GetEtcdBackupResourceLabels(backup *operatorkcpiov1alpha1.EtcdBackup) map[string]string {
+	return componentLabels(etcdBackupComponent, backup.Name)
+}
+
+// GetEtcdRestoreResourceLabels returns the labels applied to the Job restoring a snapshot for an EtcdRestore.
+func GetEtcdRestoreResourceLabels(restore *operatorkcpiov1alpha1.EtcdRestore) map[string]string {
+	return componentLabels("etcd-restore", restore.Name)
+}
+
+// GetEtcdBackupNameFromLabels returns the name of the EtcdBackup an object with the given labels belongs to.
+func GetEtcdBackupNameFromLabels(labels map[string]string) (string, bool) {
+	if labels[appComponentLabel] != etcdBackupComponent || labels[appInstanceLabel] == "" {
+		return "", false
+	}
+
+	return labels[appInstanceLabel], true
+}
+
+// GetVirtualWorkspacesResourceLabels returns the labels applied to all objects belonging to the
+// standalone virtual-workspaces server of the shard Deployment with the given name.
+func GetVirtualWorkspacesResourceLabels(shardDeploymentName string) map[string]string {
+	return componentLabels("virtual-workspaces", shardDeploymentName)
+}
+
+func componentLabels(component, instance string) map[string]string {
+	return map[string]string{
+		appNameLabel:      "kcp",
+		appInstanceLabel:  instance,
+		appComponentLabel: component,
+		appManagedByLabel: "kcp-operator",
+	}
+}
+
+// GetImageSettings returns the container image and pull secrets configured by the given ImageSpec,
+// falling back to the given kcp version (if any) and the operator defaults. The image is pinned to the
+// digest from the ImageSpec or, if digest resolution is enabled, to the given resolved digest as long as
+// it has been resolved for the same image reference.
+func GetImageSettings(imageSpec *operatorkcpiov1alpha1.ImageSpec, version string, resolved *operatorkcpiov1alpha1.ResolvedImage) (string, []corev1.LocalObjectReference) {
+	var pullSecrets []corev1.LocalObjectReference
+	if imageSpec != nil {
+		pullSecrets = imageSpec.ImagePullSecrets
+	}
+
+	image := GetImageReference(imageSpec, version)
+
+	switch {
+	case imageSpec != nil && imageSpec.Digest != "":
+		image = fmt.Sprintf("%s@%s", image, imageSpec.Digest)
+	case imageSpec != nil && imageSpec.ResolveDigest && resolved != nil && resolved.Image == image && resolved.Digest != "":
+		image = fmt.Sprintf("%s@%s", image, resolved.Digest)
+	}
+
+	return image, pullSecrets
+}
+
+// GetImageReference returns the image reference (repository and tag, without digest) for the given
+// ImageSpec and kcp version.
+func GetImageReference(imageSpec *operatorkcpiov1alpha1.ImageSpec, version string) string {
+	repository := ImageRepository
+	if imageSpec != nil && imageSpec.Repository != "" {
+		repository = imageSpec.Repository
+	}
+
+	return fmt.Sprintf("%s:%s", repository, GetEffectiveVersion(imageSpec, version))
+}
+
+// StripImageDigest removes the digest (if any) from an image reference.
+func StripImageDigest(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	return name
+}
+
+// GetEffectiveVersion returns the kcp version (i.e. image tag) a component runs: the tag configured in its
+// ImageSpec, the given (inherited) kcp version or the operator default, in that order.
+func GetEffectiveVersion(imageSpec *operatorkcpiov1alpha1.ImageSpec, version string) string {
+	if imageSpec != nil && imageSpec.Tag != "" {
+		return imageSpec.Tag
+	}
+
+	if version != "" {
+		return version
+	}
+
+	return ImageTag
+}
+
+// GetReplicas returns the desired replica count for a shard, defaulting to 1.
+func GetReplicas(spec *operatorkcpiov1alpha1.CommonShardSpec) int32 {
+	if spec.Replicas == nil {
+		return 1
+	}
+
+	return *spec.Replicas
+}
+
+// GetEtcdArgs returns the kcp command line flags required to connect to the configured etcd cluster.
+func GetEtcdArgs(etcd operatorkcpiov1alpha1.EtcdConfig) []string {
+	args := []string{
+		fmt.Sprintf("--etcd-servers=%s", strings.Join(etcd.Endpoints, ",")),
+		fmt.Sprintf("--etcd-certfile=%s/tls.crt", EtcdCertificateMountPath),
+		fmt.Sprintf("--etcd-keyfile=%s/tls.key", EtcdCertificateMountPath),
+		fmt.Sprintf("--etcd-cafile=%s/ca.crt", EtcdCertificateMountPath),
+	}
+
+	if etcd.KeyPrefix != "" {
+		args = append(args, fmt.Sprintf("--etcd-prefix=%s", etcd.KeyPrefix))
+	}
+
+	return args
+}
+
+// GetEtcdKeyPrefix returns the etcd key prefix kcp stores its data under.
+func GetEtcdKeyPrefix(etcd operatorkcpiov1alpha1.EtcdConfig) string {
+	if etcd.KeyPrefix != "" {
+		return etcd.KeyPrefix
+	}
+
+	return DefaultEtcdKeyPrefix
+}
+
+// GetEtcdVolume returns the volume and mount for the etcd client certificate.
+func GetEtcdVolume(etcd operatorkcpiov1alpha1.EtcdConfig) (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: "etcd-client-cert",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: etcd.ClientCert.SecretRef.Name,
+			},
+		},
+	}
+
+	mount := corev1.VolumeMount{
+		Name:      volume.Name,
+		MountPath: EtcdCertificateMountPath,
+		ReadOnly:  true,
+	}
+
+	return volume, mount
+}
+
+// GetServerTLSSettings returns the command line flags, volumes and mounts that configure a kcp shard
+// to serve with a certificate issued by the root shard's CA and to accept client certificates signed
+// by it or by one of the client CAs of generated kubeconfigs. It returns nothing if the root shard has
+// no CA configured, in which case kcp falls back to self-signed serving certificates.
+func GetServerTLSSettings(deploymentName string, rootShard *operatorkcpiov1alpha1.RootShard) ([]string, []corev1.Volume, []corev1.VolumeMount) {
+	if rootShard.Spec.CARef == nil {
+		return nil, nil, nil
+	}
+
+	volumes := []corev1.Volume{
+		{
+			Name: "server-cert",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: GetServerCertificateName(deploymentName),
+				},
+			},
+		},
+		{
+			Name: "client-ca",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: GetClientCABundleName(deploymentName),
+				},
+			},
+		},
+	}
+
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      "server-cert",
+			MountPath: ServerCertificateMountPath,
+			ReadOnly:  true,
+		},
+		{
+			Name:      "client-ca",
+			MountPath: ClientCAMountPath,
+			ReadOnly:  true,
+		},
+	}
+
+	// kcp reloads the client CA file when it changes, so revoked CAs are rejected without a restart
+	args := []string{
+		fmt.Sprintf("--tls-cert-file=%s/tls.crt", ServerCertificateMountPath),
+		fmt.Sprintf("--tls-private-key-file=%s/tls.key", ServerCertificateMountPath),
+		fmt.Sprintf("--client-ca-file=%s/%s", ClientCAMountPath, ClientCABundleKey),
+	}
+
+	return args, volumes, mounts
+}
+
+// GetDataVolume returns the volume and mount for kcp's root directory.
+func GetDataVolume() (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: "kcp-data",
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+
+	mount := corev1.VolumeMount{
+		Name:      volume.Name,
+		MountPath: DataMountPath,
+	}
+
+	return volume, mount
+}
+
+// GetShardProbes returns the readiness, liveness and startup probes for a kcp shard container.
+// kcp only reports itself ready once it has connected to etcd and finished bootstrapping,
+// so readiness is checked against /readyz while /livez is used to restart wedged replicas.
+func GetShardProbes() (readiness, liveness, startup *corev1.Probe) {
+	httpGet := func(path string) corev1.ProbeHandler {
+		return corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt32(ShardPort),
+				Scheme: corev1.URISchemeHTTPS,
+			},
+		}
+	}
+
+	readiness = &corev1.Probe{
+		ProbeHandler:     httpGet("/readyz"),
+		PeriodSeconds:    5,
+		TimeoutSeconds:   5,
+		FailureThreshold: 3,
+	}
+
+	liveness = &corev1.Probe{
+		ProbeHandler:     httpGet("/livez"),
+		PeriodSeconds:    10,
+		TimeoutSeconds:   5,
+		FailureThreshold: 6,
+	}
+
+	// bootstrapping a fresh etcd can take a while; give kcp up to 5 minutes
+	// before the liveness probe takes over.
+	startup = &corev1.Probe{
+		ProbeHandler:     httpGet("/livez"),
+		PeriodSeconds:    10,
+		TimeoutSeconds:   5,
+		FailureThreshold: 30,
+	}
+
+	return readiness, liveness, startup
+}
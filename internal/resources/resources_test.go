@@ -0,0 +1,298 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestGetImageSettings(t *testing.T) {
+	testcases := []struct {
+		name          string
+		spec          *operatorkcpiov1alpha1.ImageSpec
+		version       string
+		resolved      *operatorkcpiov1alpha1.ResolvedImage
+		expectedImage string
+		expectedPulls int
+	}{
+		{
+			name:          "defaults",
+			spec:          nil,
+			expectedImage: ImageRepository + ":" + ImageTag,
+		},
+		{
+			name:          "custom tag only",
+			spec:          &operatorkcpiov1alpha1.ImageSpec{Tag: "v0.25.0"},
+			expectedImage: ImageRepository + ":v0.25.0",
+		},
+		{
+			name:          "version from root shard",
+			version:       "v0.27.0",
+			expectedImage: ImageRepository + ":v0.27.0",
+		},
+		{
+			name:          "custom tag overrides version",
+			spec:          &operatorkcpiov1alpha1.ImageSpec{Tag: "v0.25.0"},
+			version:       "v0.27.0",
+			expectedImage: ImageRepository + ":v0.25.0",
+		},
+		{
+			name: "custom repository and pull secrets",
+			spec: &operatorkcpiov1alpha1.ImageSpec{
+				Repository:       "registry.example.com/kcp",
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "pull-secret"}},
+			},
+			expectedImage: "registry.example.com/kcp:" + ImageTag,
+			expectedPulls: 1,
+		},
+		{
+			name:          "pinned digest",
+			spec:          &operatorkcpiov1alpha1.ImageSpec{Tag: "v0.25.0", Digest: testDigest},
+			expectedImage: ImageRepository + ":v0.25.0@" + testDigest,
+		},
+		{
+			name:          "resolved digest",
+			spec:          &operatorkcpiov1alpha1.ImageSpec{Tag: "v0.25.0", ResolveDigest: true},
+			resolved:      &operatorkcpiov1alpha1.ResolvedImage{Image: ImageRepository + ":v0.25.0", Digest: testDigest},
+			expectedImage: ImageRepository + ":v0.25.0@" + testDigest,
+		},
+		{
+			name:          "resolved digest for another image is ignored",
+			spec:          &operatorkcpiov1alpha1.ImageSpec{Tag: "v0.26.0", ResolveDigest: true},
+			resolved:      &operatorkcpiov1alpha1.ResolvedImage{Image: ImageRepository + ":v0.25.0", Digest: testDigest},
+			expectedImage: ImageRepository + ":v0.26.0",
+		},
+		{
+			name:          "resolved digest is ignored if resolution is disabled",
+			spec:          &operatorkcpiov1alpha1.ImageSpec{Tag: "v0.25.0"},
+			resolved:      &operatorkcpiov1alpha1.ResolvedImage{Image: ImageRepository + ":v0.25.0", Digest: testDigest},
+			expectedImage: ImageRepository + ":v0.25.0",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			image, pullSecrets := GetImageSettings(tc.spec, tc.version, tc.resolved)
+			if image != tc.expectedImage {
+				t.Errorf("expected image %q, got %q", tc.expectedImage, image)
+			}
+			if len(pullSecrets) != tc.expectedPulls {
+				t.Errorf("expected %d pull secrets, got %d", tc.expectedPulls, len(pullSecrets))
+			}
+		})
+	}
+}
+
+func TestGetShardURLs(t *testing.T) {
+	rootShard := &operatorkcpiov1alpha1.RootShard{
+		ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "kcp"},
+		Spec:       operatorkcpiov1alpha1.RootShardSpec{Hostname: "kcp.example.com"},
+	}
+	shard := &operatorkcpiov1alpha1.Shard{
+		ObjectMeta: metav1.ObjectMeta{Name: "beta", Namespace: "kcp"},
+	}
+
+	urls := GetShardURLs(shard, rootShard)
+
+	expected := operatorkcpiov1alpha1.ShardURLs{
+		Internal:          "https://beta-shard-kcp.kcp.svc.cluster.local:6443",
+		External:          "https://kcp.example.com:443",
+		VirtualWorkspaces: "https://beta-shard-kcp.kcp.svc.cluster.local:6443",
+	}
+
+	if urls != expected {
+		t.Errorf("expected %+v, got %+v", expected, urls)
+	}
+}
+
+func TestGetRootShardURLsWithExternalVirtualWorkspaces(t *testing.T) {
+	rootShard := &operatorkcpiov1alpha1.RootShard{
+		ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "kcp"},
+		Spec: operatorkcpiov1alpha1.RootShardSpec{
+			Hostname: "kcp.example.com",
+			CARef:    &corev1.LocalObjectReference{Name: "root-ca"},
+			CommonShardSpec: operatorkcpiov1alpha1.CommonShardSpec{
+				VirtualWorkspaces: &operatorkcpiov1alpha1.VirtualWorkspacesSpec{
+					Mode: operatorkcpiov1alpha1.VirtualWorkspacesModeExternal,
+				},
+			},
+		},
+	}
+
+	urls := GetRootShardURLs(rootShard)
+
+	if expected := "https://root-kcp.kcp.svc.cluster.local:6443"; urls.Internal != expected {
+		t.Errorf("expected internal URL %q, got %q", expected, urls.Internal)
+	}
+	if expected := "https://root-kcp-virtual-workspaces.kcp.svc.cluster.local:6444"; urls.VirtualWorkspaces != expected {
+		t.Errorf("expected virtual workspaces URL %q, got %q", expected, urls.VirtualWorkspaces)
+	}
+
+	// without a CA, the virtual workspaces stay embedded
+	rootShard.Spec.CARef = nil
+	urls = GetRootShardURLs(rootShard)
+
+	if urls.VirtualWorkspaces != urls.Internal {
+		t.Errorf("expected virtual workspaces URL %q without a CA, got %q", urls.Internal, urls.VirtualWorkspaces)
+	}
+}
+
+func TestGetKCPVersion(t *testing.T) {
+	upgrading := &operatorkcpiov1alpha1.UpgradeStatus{
+		FromVersion:   "v0.26.0",
+		TargetVersion: "v0.27.0",
+		Phase:         operatorkcpiov1alpha1.UpgradePhaseRootShard,
+	}
+
+	testcases := []struct {
+		name     string
+		spec     string
+		upgrade  *operatorkcpiov1alpha1.UpgradeStatus
+		phase    operatorkcpiov1alpha1.UpgradePhase
+		expected string
+	}{
+		{
+			name:     "no upgrade status uses default",
+			phase:    operatorkcpiov1alpha1.UpgradePhaseShards,
+			expected: ImageTag,
+		},
+		{
+			name:     "no upgrade status uses spec",
+			spec:     "v0.27.0",
+			phase:    operatorkcpiov1alpha1.UpgradePhaseShards,
+			expected: "v0.27.0",
+		},
+		{
+			name:     "earlier phase has been upgraded",
+			spec:     "v0.27.0",
+			upgrade:  upgrading,
+			phase:    operatorkcpiov1alpha1.UpgradePhaseCacheServer,
+			expected: "v0.27.0",
+		},
+		{
+			name:     "current phase is being upgraded",
+			spec:     "v0.27.0",
+			upgrade:  upgrading,
+			phase:    operatorkcpiov1alpha1.UpgradePhaseRootShard,
+			expected: "v0.27.0",
+		},
+		{
+			name:     "later phase keeps the previous version",
+			spec:     "v0.27.0",
+			upgrade:  upgrading,
+			phase:    operatorkcpiov1alpha1.UpgradePhaseFrontProxies,
+			expected: "v0.26.0",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootShard := &operatorkcpiov1alpha1.RootShard{
+				Spec:   operatorkcpiov1alpha1.RootShardSpec{Version: tc.spec},
+				Status: operatorkcpiov1alpha1.RootShardStatus{Upgrade: tc.upgrade},
+			}
+
+			if version := GetKCPVersion(rootShard, tc.phase); version != tc.expected {
+				t.Errorf("expected version %q, got %q", tc.expected, version)
+			}
+		})
+	}
+}
+
+func TestGetClientCARootShard(t *testing.T) {
+	testcases := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		expected    string
+		expectedOK  bool
+	}{
+		{
+			name:        "client CA",
+			labels:      map[string]string{ClientCALabel: "true"},
+			annotations: map[string]string{ClientCARootShardAnnotation: "kcp/root"},
+			expected:    "kcp/root",
+			expectedOK:  true,
+		},
+		{
+			name:        "unlabelled Secret",
+			annotations: map[string]string{ClientCARootShardAnnotation: "kcp/root"},
+		},
+		{
+			name:        "malformed annotation",
+			labels:      map[string]string{ClientCALabel: "true"},
+			annotations: map[string]string{ClientCARootShardAnnotation: "root"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: tc.labels, Annotations: tc.annotations}}
+
+			key, ok := GetClientCARootShard(secret)
+			if ok != tc.expectedOK {
+				t.Fatalf("expected ok to be %v, got %v", tc.expectedOK, ok)
+			}
+			if ok && key.String() != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, key.String())
+			}
+		})
+	}
+}
+
+func TestGetBreakGlassUsers(t *testing.T) {
+	testcases := []struct {
+		name       string
+		annotation *string
+		expected   []string
+	}{
+		{
+			name: "no annotation",
+		},
+		{
+			name:       "empty annotation",
+			annotation: ptr.To(" , "),
+		},
+		{
+			name:       "users",
+			annotation: ptr.To("bob, alice,,bob"),
+			expected:   []string{"alice", "bob"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootShard := &operatorkcpiov1alpha1.RootShard{}
+			if tc.annotation != nil {
+				rootShard.Annotations = map[string]string{operatorkcpiov1alpha1.BreakGlassAnnotation: *tc.annotation}
+			}
+
+			if users := GetBreakGlassUsers(rootShard); !slices.Equal(users, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, users)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rootshard
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// MutateBreakGlassRole allows reading the admin kubeconfig Secret of the given root shard, and nothing else.
+func MutateBreakGlassRole(role *rbacv1.Role, rootShard *operatorkcpiov1alpha1.RootShard) {
+	role.Labels = resources.GetRootShardResourceLabels(rootShard)
+	role.Rules = []rbacv1.PolicyRule{{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		ResourceNames: []string{resources.GetRootShardAdminKubeconfigName(rootShard)},
+		Verbs:         []string{"get"},
+	}}
+}
+
+// MutateBreakGlassRoleBinding binds the break-glass Role of the given root shard to users.
+func MutateBreakGlassRoleBinding(rb *rbacv1.RoleBinding, rootShard *operatorkcpiov1alpha1.RootShard, users []string) {
+	rb.Labels = resources.GetRootShardResourceLabels(rootShard)
+
+	// the role of an existing binding cannot be changed, but it is always the same
+	rb.RoleRef = rbacv1.RoleRef{
+		APIGroup: rbacv1.GroupName,
+		Kind:     "Role",
+		Name:     resources.GetBreakGlassName(rootShard),
+	}
+
+	rb.Subjects = nil
+	for _, user := range users {
+		rb.Subjects = append(rb.Subjects, rbacv1.Subject{
+			APIGroup: rbacv1.GroupName,
+			Kind:     rbacv1.UserKind,
+			Name:     user,
+		})
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+// MutateShardService configures the ClusterIP Service fronting the replicas of a (root) shard.
+func MutateShardService(svc *corev1.Service, labels map[string]string) {
+	svc.Labels = labels
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.Selector = labels
+	svc.Spec.Ports = []corev1.ServicePort{{
+		Name:       "https",
+		Port:       ShardPort,
+		TargetPort: intstr.FromInt32(ShardPort),
+		Protocol:   corev1.ProtocolTCP,
+	}}
+}
+
+// MutatePodDisruptionBudget configures a PodDisruptionBudget that allows voluntary disruptions
+// of at most one replica at a time, so that multi-replica shards stay available during node drains.
+func MutatePodDisruptionBudget(pdb *policyv1.PodDisruptionBudget, labels map[string]string) {
+	pdb.Labels = labels
+	pdb.Spec.MaxUnavailable = ptr.To(intstr.FromInt32(1))
+	pdb.Spec.MinAvailable = nil
+	pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+}
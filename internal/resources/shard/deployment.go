@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shard
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// MutateDeployment configures the kcp Deployment for the given shard, which joins the kcp setup
+// formed around rootShard.
+func MutateDeployment(dep *appsv1.Deployment, shard *operatorkcpiov1alpha1.Shard, rootShard *operatorkcpiov1alpha1.RootShard) {
+	labels := resources.GetShardResourceLabels(shard)
+	image, pullSecrets := resources.GetImageSettings(shard.Spec.Image, resources.GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseShards), shard.Status.ResolvedImage)
+	readiness, liveness, startup := resources.GetShardProbes()
+	etcdVolume, etcdMount := resources.GetEtcdVolume(shard.Spec.Etcd)
+	dataVolume, dataMount := resources.GetDataVolume()
+	tlsArgs, tlsVolumes, tlsMounts := resources.GetServerTLSSettings(resources.GetShardDeploymentName(shard), rootShard)
+
+	dep.Labels = labels
+	dep.Spec.Replicas = ptr.To(resources.GetReplicas(&shard.Spec.CommonShardSpec))
+	dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dep.Spec.Template.Labels = labels
+	dep.Spec.Template.Spec.ImagePullSecrets = pullSecrets
+	dep.Spec.Template.Spec.Volumes = append([]corev1.Volume{etcdVolume, dataVolume}, tlsVolumes...)
+	dep.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "kcp",
+		Image:   image,
+		Command: []string{"/kcp", "start"},
+		Args:    append(getArgs(shard, rootShard), tlsArgs...),
+		Ports: []corev1.ContainerPort{{
+			Name:          "https",
+			ContainerPort: resources.ShardPort,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		VolumeMounts:   append([]corev1.VolumeMount{etcdMount, dataMount}, tlsMounts...),
+		ReadinessProbe: readiness,
+		LivenessProbe:  liveness,
+		StartupProbe:   startup,
+	}}
+}
+
+func getArgs(shard *operatorkcpiov1alpha1.Shard, rootShard *operatorkcpiov1alpha1.RootShard) []string {
+	args := []string{
+		fmt.Sprintf("--root-directory=%s", resources.DataMountPath),
+		fmt.Sprintf("--secure-port=%d", resources.ShardPort),
+		fmt.Sprintf("--shard-name=%s", shard.Name),
+		fmt.Sprintf("--external-hostname=%s", rootShard.Spec.Hostname),
+	}
+
+	args = append(args, resources.GetURLArgs(resources.GetShardURLs(shard, rootShard))...)
+
+	if resources.IsExternalVirtualWorkspaces(&shard.Spec.CommonShardSpec, rootShard) {
+		args = append(args, "--run-virtual-workspaces=false")
+	}
+
+	return append(args, resources.GetEtcdArgs(shard.Spec.Etcd)...)
+}
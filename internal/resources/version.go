@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+// upgradeOrder lists the upgrade phases in the order they are executed.
+var upgradeOrder = []operatorkcpiov1alpha1.UpgradePhase{
+	operatorkcpiov1alpha1.UpgradePhaseCacheServer,
+	operatorkcpiov1alpha1.UpgradePhaseRootShard,
+	operatorkcpiov1alpha1.UpgradePhaseShards,
+	operatorkcpiov1alpha1.UpgradePhaseFrontProxies,
+	operatorkcpiov1alpha1.UpgradePhaseCompleted,
+}
+
+// GetDesiredVersion returns the kcp version configured for the kcp setup formed around the given root shard.
+func GetDesiredVersion(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	if rootShard.Spec.Version != "" {
+		return rootShard.Spec.Version
+	}
+
+	return ImageTag
+}
+
+// GetRootShardVersion returns the kcp version the given root shard currently runs.
+func GetRootShardVersion(rootShard *operatorkcpiov1alpha1.RootShard) string {
+	return GetEffectiveVersion(rootShard.Spec.Image, GetKCPVersion(rootShard, operatorkcpiov1alpha1.UpgradePhaseRootShard))
+}
+
+// GetKCPVersion returns the kcp version that components of the given upgrade phase should currently run.
+// While an upgrade is in progress, components of phases that have not been reached yet keep running the
+// previous version.
+func GetKCPVersion(rootShard *operatorkcpiov1alpha1.RootShard, phase operatorkcpiov1alpha1.UpgradePhase) string {
+	upgrade := rootShard.Status.Upgrade
+	if upgrade == nil {
+		return GetDesiredVersion(rootShard)
+	}
+
+	if upgradePhaseIndex(phase) <= upgradePhaseIndex(upgrade.Phase) {
+		return upgrade.TargetVersion
+	}
+
+	return upgrade.FromVersion
+}
+
+// NextUpgradePhase returns the phase following the given one.
+func NextUpgradePhase(phase operatorkcpiov1alpha1.UpgradePhase) operatorkcpiov1alpha1.UpgradePhase {
+	idx := upgradePhaseIndex(phase)
+	if idx+1 >= len(upgradeOrder) {
+		return operatorkcpiov1alpha1.UpgradePhaseCompleted
+	}
+
+	return upgradeOrder[idx+1]
+}
+
+func upgradePhaseIndex(phase operatorkcpiov1alpha1.UpgradePhase) int {
+	for i, p := range upgradeOrder {
+		if p == phase {
+			return i
+		}
+	}
+
+	// unknown phases are treated as completed
+	return len(upgradeOrder) - 1
+}
+
+// IsDeploymentRolledOut returns true if the Deployment's first container runs the given image and all
+// replicas have been updated and are available. Digests are ignored when comparing images.
+func IsDeploymentRolledOut(dep *appsv1.Deployment, image string) bool {
+	if len(dep.Spec.Template.Spec.Containers) == 0 || StripImageDigest(dep.Spec.Template.Spec.Containers[0].Image) != StripImageDigest(image) {
+		return false
+	}
+
+	if dep.Status.ObservedGeneration < dep.Generation {
+		return false
+	}
+
+	replicas := ptr.Deref(dep.Spec.Replicas, 1)
+
+	return dep.Status.Replicas == replicas &&
+		dep.Status.UpdatedReplicas == replicas &&
+		dep.Status.ReadyReplicas == replicas &&
+		dep.Status.AvailableReplicas == replicas
+}
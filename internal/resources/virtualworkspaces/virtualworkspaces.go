@@ -0,0 +1,232 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package virtualworkspaces renders the objects for running kcp's virtual workspaces in a standalone
+// server next to a (root) shard.
+package virtualworkspaces
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/ptr"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/resources/certificates"
+)
+
+const (
+	kubeconfigKey       = "kubeconfig"
+	kubeconfigMountPath = "/etc/virtual-workspaces/kubeconfig"
+	serverCertMountPath = "/etc/virtual-workspaces/tls/server"
+	clientCertMountPath = "/etc/virtual-workspaces/tls/client"
+	clientCAMountPath   = "/etc/virtual-workspaces/tls/client-ca"
+)
+
+// Options describes the virtual-workspaces server for a single (root) shard.
+type Options struct {
+	// ShardDeploymentName is the name of the shard Deployment the virtual workspaces belong to.
+	ShardDeploymentName string
+	// Namespace is the namespace of the shard.
+	Namespace string
+	// ShardURL is the internal URL of the shard the server connects to.
+	ShardURL string
+	// Image configures the container image, typically the same as the shard's.
+	Image *operatorkcpiov1alpha1.ImageSpec
+	// Version is the kcp version the shard runs, used unless Image sets a tag.
+	Version string
+	// ResolvedImage is the image digest the shard has been pinned to, if any.
+	ResolvedImage *operatorkcpiov1alpha1.ResolvedImage
+	// Spec is the shard's virtual workspaces configuration.
+	Spec *operatorkcpiov1alpha1.VirtualWorkspacesSpec
+	// IssuerName is the cert-manager Issuer signing the server and client certificates.
+	IssuerName string
+}
+
+// Name returns the name of the virtual-workspaces Deployment and Service.
+func (o Options) Name() string {
+	return resources.GetVirtualWorkspacesName(o.ShardDeploymentName)
+}
+
+// ClientCertificateName returns the name of the Certificate (and Secret) the server uses to authenticate against the shard.
+func (o Options) ClientCertificateName() string {
+	return fmt.Sprintf("%s-client", o.Name())
+}
+
+// KubeconfigName returns the name of the ConfigMap holding the kubeconfig pointing to the shard.
+func (o Options) KubeconfigName() string {
+	return fmt.Sprintf("%s-kubeconfig", o.Name())
+}
+
+// ServerCertificate returns the options for the server's serving certificate.
+func (o Options) ServerCertificate() certificates.Options {
+	return certificates.Options{
+		SecretName: resources.GetServerCertificateName(o.Name()),
+		IssuerName: o.IssuerName,
+		CommonName: o.Name(),
+		DNSNames:   resources.GetServiceDNSNames(o.Name(), o.Namespace),
+		Usages:     []string{"server auth"},
+	}
+}
+
+// ClientCertificate returns the options for the certificate the server authenticates against the shard with.
+func (o Options) ClientCertificate() certificates.Options {
+	return certificates.Options{
+		SecretName:    o.ClientCertificateName(),
+		IssuerName:    o.IssuerName,
+		CommonName:    "system:kcp:virtual-workspaces",
+		Organizations: []string{"system:masters"},
+		Usages:        []string{"client auth"},
+	}
+}
+
+// MutateKubeconfig configures the ConfigMap holding the kubeconfig the server uses to connect to the shard.
+// The kubeconfig only references the mounted client certificate, so it contains no secrets.
+func MutateKubeconfig(cm *corev1.ConfigMap, opts Options) error {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["shard"] = &clientcmdapi.Cluster{
+		Server:               opts.ShardURL,
+		CertificateAuthority: fmt.Sprintf("%s/ca.crt", clientCertMountPath),
+	}
+	config.AuthInfos["virtual-workspaces"] = &clientcmdapi.AuthInfo{
+		ClientCertificate: fmt.Sprintf("%s/tls.crt", clientCertMountPath),
+		ClientKey:         fmt.Sprintf("%s/tls.key", clientCertMountPath),
+	}
+	config.Contexts["shard"] = &clientcmdapi.Context{
+		Cluster:  "shard",
+		AuthInfo: "virtual-workspaces",
+	}
+	config.CurrentContext = "shard"
+
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+
+	cm.Labels = resources.GetVirtualWorkspacesResourceLabels(opts.ShardDeploymentName)
+	cm.Data = map[string]string{
+		kubeconfigKey: string(data),
+	}
+
+	return nil
+}
+
+// MutateDeployment configures the virtual-workspaces Deployment.
+func MutateDeployment(dep *appsv1.Deployment, opts Options) {
+	labels := resources.GetVirtualWorkspacesResourceLabels(opts.ShardDeploymentName)
+	image, pullSecrets := resources.GetImageSettings(opts.Image, opts.Version, opts.ResolvedImage)
+
+	httpGet := func(path string) corev1.ProbeHandler {
+		return corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   path,
+				Port:   intstr.FromInt32(resources.VirtualWorkspacesPort),
+				Scheme: corev1.URISchemeHTTPS,
+			},
+		}
+	}
+
+	dep.Labels = labels
+	dep.Spec.Replicas = ptr.To(ptr.Deref(opts.Spec.Replicas, 1))
+	dep.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dep.Spec.Template.Labels = labels
+	dep.Spec.Template.Spec.ImagePullSecrets = pullSecrets
+	dep.Spec.Template.Spec.Volumes = []corev1.Volume{
+		{
+			Name: "kubeconfig",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: opts.KubeconfigName()},
+				},
+			},
+		},
+		{
+			Name: "server-cert",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: resources.GetServerCertificateName(opts.Name())},
+			},
+		},
+		{
+			Name: "client-cert",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: opts.ClientCertificateName()},
+			},
+		},
+		{
+			Name: "client-ca",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: resources.GetClientCABundleName(opts.ShardDeploymentName)},
+			},
+		},
+	}
+
+	kubeconfig := fmt.Sprintf("%s/%s", kubeconfigMountPath, kubeconfigKey)
+
+	dep.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "virtual-workspaces",
+		Image:   image,
+		Command: []string{"/virtual-workspaces", "start"},
+		Args: []string{
+			fmt.Sprintf("--secure-port=%d", resources.VirtualWorkspacesPort),
+			fmt.Sprintf("--kubeconfig=%s", kubeconfig),
+			fmt.Sprintf("--authentication-kubeconfig=%s", kubeconfig),
+			fmt.Sprintf("--tls-cert-file=%s/tls.crt", serverCertMountPath),
+			fmt.Sprintf("--tls-private-key-file=%s/tls.key", serverCertMountPath),
+			fmt.Sprintf("--client-ca-file=%s/%s", clientCAMountPath, resources.ClientCABundleKey),
+		},
+		Ports: []corev1.ContainerPort{{
+			Name:          "https",
+			ContainerPort: resources.VirtualWorkspacesPort,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "kubeconfig", MountPath: kubeconfigMountPath, ReadOnly: true},
+			{Name: "server-cert", MountPath: serverCertMountPath, ReadOnly: true},
+			{Name: "client-cert", MountPath: clientCertMountPath, ReadOnly: true},
+			{Name: "client-ca", MountPath: clientCAMountPath, ReadOnly: true},
+		},
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler:  httpGet("/readyz"),
+			PeriodSeconds: 5,
+		},
+		LivenessProbe: &corev1.Probe{
+			ProbeHandler:     httpGet("/livez"),
+			PeriodSeconds:    10,
+			FailureThreshold: 6,
+		},
+	}}
+}
+
+// MutateService configures the Service fronting the virtual-workspaces Deployment.
+func MutateService(svc *corev1.Service, opts Options) {
+	labels := resources.GetVirtualWorkspacesResourceLabels(opts.ShardDeploymentName)
+
+	svc.Labels = labels
+	svc.Spec.Type = corev1.ServiceTypeClusterIP
+	svc.Spec.Selector = labels
+	svc.Spec.Ports = []corev1.ServicePort{{
+		Name:       "https",
+		Port:       resources.VirtualWorkspacesPort,
+		TargetPort: intstr.FromInt32(resources.VirtualWorkspacesPort),
+		Protocol:   corev1.ProtocolTCP,
+	}}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version contains the compatibility matrix of kcp versions supported by the operator and
+// the version skew policy between the components of a kcp setup.
+package version
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/version"
+
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+)
+
+// SupportedMinorVersions lists the kcp minor versions the operator can deploy.
+var SupportedMinorVersions = []string{"0.25", "0.26"}
+
+// skew is the number of minor versions a component may be behind or ahead of the root shard.
+type skew struct {
+	behind uint
+	ahead  uint
+}
+
+// skewPolicy defines the supported version skew per component kind. Shards and front-proxies are
+// upgraded after the root shard and may therefore lag behind by one minor version, while the cache
+// server is upgraded before it and may be one minor version ahead.
+var skewPolicy = map[string]skew{
+	reference.KindShard:       {behind: 1},
+	reference.KindFrontProxy:  {behind: 1},
+	reference.KindCacheServer: {ahead: 1},
+}
+
+// ErrUnknownVersion is returned for versions (e.g. image tags like "main") that cannot be parsed
+// and are therefore not checked against the compatibility matrix.
+var ErrUnknownVersion = errors.New("unknown version")
+
+// Parse parses a kcp version like "v0.26.0".
+func Parse(v string) (*version.Version, error) {
+	parsed, err := version.ParseGeneric(v)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownVersion, v)
+	}
+
+	return parsed, nil
+}
+
+// IsSupported returns an error if the given kcp version is not part of the compatibility matrix.
+func IsSupported(v string) error {
+	parsed, err := Parse(v)
+	if err != nil {
+		return err
+	}
+
+	for _, supported := range SupportedMinorVersions {
+		minor := version.MustParseMajorMinor(supported)
+		if parsed.Major() == minor.Major() && parsed.Minor() == minor.Minor() {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("kcp %s is not supported, supported minor versions are %v", v, SupportedMinorVersions)
+}
+
+// CheckSkew returns an error if a component of the given kind running componentVersion is outside
+// of the supported version skew relative to a root shard running rootShardVersion.
+func CheckSkew(kind, componentVersion, rootShardVersion string) error {
+	component, err := Parse(componentVersion)
+	if err != nil {
+		return err
+	}
+
+	root, err := Parse(rootShardVersion)
+	if err != nil {
+		return err
+	}
+
+	policy := skewPolicy[kind]
+
+	lowest := root
+	if root.Minor() >= policy.behind {
+		lowest = root.SubtractMinor(policy.behind)
+	}
+	highest := root.AddMinor(policy.ahead)
+
+	if component.Major() != root.Major() || component.Minor() < lowest.Minor() || component.Minor() > highest.Minor() {
+		return fmt.Errorf("%s version %s is outside of the supported skew relative to RootShard version %s (allowed: %d.%d to %d.%d)",
+			kind, componentVersion, rootShardVersion, lowest.Major(), lowest.Minor(), highest.Major(), highest.Minor())
+	}
+
+	return nil
+}
+
+// CheckUpgrade returns an error if changing the kcp version of a setup from one version to another is
+// not supported. Upgrades may advance by at most one minor version at a time, and downgrades are not
+// supported.
+func CheckUpgrade(from, to string) error {
+	current, err := Parse(from)
+	if err != nil {
+		return err
+	}
+
+	target, err := Parse(to)
+	if err != nil {
+		return err
+	}
+
+	if target.Major() != current.Major() || target.Minor() > current.Minor()+1 {
+		return fmt.Errorf("upgrading from kcp %s to %s skips a minor version, upgrade one minor version at a time", from, to)
+	}
+
+	if target.LessThan(current) {
+		return fmt.Errorf("downgrading from kcp %s to %s is not supported", from, to)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+)
+
+func TestIsSupported(t *testing.T) {
+	testcases := []struct {
+		version   string
+		supported bool
+		unknown   bool
+	}{
+		{version: "v0.26.0", supported: true},
+		{version: "v0.25.3", supported: true},
+		{version: "v0.26.0-rc.1", supported: true},
+		{version: "v0.20.0"},
+		{version: "v1.26.0"},
+		{version: "main", unknown: true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.version, func(t *testing.T) {
+			err := IsSupported(tc.version)
+			if tc.unknown != errors.Is(err, ErrUnknownVersion) {
+				t.Fatalf("expected unknown=%v, got %v", tc.unknown, err)
+			}
+			if !tc.unknown && tc.supported != (err == nil) {
+				t.Fatalf("expected supported=%v, got %v", tc.supported, err)
+			}
+		})
+	}
+}
+
+func TestCheckSkew(t *testing.T) {
+	testcases := []struct {
+		name      string
+		kind      string
+		component string
+		rootShard string
+		valid     bool
+	}{
+		{name: "shard on same version", kind: reference.KindShard, component: "v0.26.1", rootShard: "v0.26.0", valid: true},
+		{name: "shard one minor behind", kind: reference.KindShard, component: "v0.25.0", rootShard: "v0.26.0", valid: true},
+		{name: "shard two minors behind", kind: reference.KindShard, component: "v0.24.0", rootShard: "v0.26.0"},
+		{name: "shard ahead", kind: reference.KindShard, component: "v0.27.0", rootShard: "v0.26.0"},
+		{name: "front-proxy one minor behind", kind: reference.KindFrontProxy, component: "v0.25.0", rootShard: "v0.26.0", valid: true},
+		{name: "cache server one minor ahead", kind: reference.KindCacheServer, component: "v0.27.0", rootShard: "v0.26.0", valid: true},
+		{name: "cache server behind", kind: reference.KindCacheServer, component: "v0.25.0", rootShard: "v0.26.0"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckSkew(tc.kind, tc.component, tc.rootShard)
+			if tc.valid != (err == nil) {
+				t.Fatalf("expected valid=%v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	testcases := []struct {
+		from  string
+		to    string
+		valid bool
+	}{
+		{from: "v0.25.0", to: "v0.25.1", valid: true},
+		{from: "v0.25.0", to: "v0.26.0", valid: true},
+		{from: "v0.24.0", to: "v0.26.0"},
+		{from: "v0.26.0", to: "v0.25.0"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.from+"->"+tc.to, func(t *testing.T) {
+			err := CheckUpgrade(tc.from, tc.to)
+			if tc.valid != (err == nil) {
+				t.Fatalf("expected valid=%v, got %v", tc.valid, err)
+			}
+		})
+	}
+}
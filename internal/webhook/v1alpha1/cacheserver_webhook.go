@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var cacheserverlog = logf.Log.WithName("cacheserver-resource")
+
+// SetupCacheServerWebhookWithManager registers the webhook for CacheServer in the manager.
+func SetupCacheServerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&operatorkcpiov1alpha1.CacheServer{}).
+		WithValidator(&CacheServerCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-kcp-io-v1alpha1-cacheserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=operator.kcp.io,resources=cacheservers,verbs=create;update,versions=v1alpha1,name=vcacheserver-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// CacheServerCustomValidator validates CacheServer objects, in particular that a pinned kcp version is
+// compatible with the RootShard referencing the cache server.
+type CacheServerCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &CacheServerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type CacheServer.
+func (v *CacheServerCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cacheServer, ok := obj.(*operatorkcpiov1alpha1.CacheServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a CacheServer object but got %T", obj)
+	}
+	cacheserverlog.Info("Validation for CacheServer upon creation", "name", cacheServer.GetName())
+
+	if err := v.validateEtcd(ctx, cacheServer); err != nil {
+		return nil, err
+	}
+
+	return v.validateVersion(ctx, cacheServer)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type CacheServer.
+func (v *CacheServerCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCacheServer, ok := oldObj.(*operatorkcpiov1alpha1.CacheServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a CacheServer object for the oldObj but got %T", oldObj)
+	}
+	cacheServer, ok := newObj.(*operatorkcpiov1alpha1.CacheServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a CacheServer object for the newObj but got %T", newObj)
+	}
+	cacheserverlog.Info("Validation for CacheServer upon update", "name", cacheServer.GetName())
+
+	if etcdStorageChanged(oldCacheServer.Spec.Etcd, cacheServer.Spec.Etcd) {
+		if err := v.validateEtcd(ctx, cacheServer); err != nil {
+			return nil, err
+		}
+	}
+
+	if equality.Semantic.DeepEqual(oldCacheServer.Spec.Image, cacheServer.Spec.Image) {
+		return nil, nil
+	}
+
+	return v.validateVersion(ctx, cacheServer)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type CacheServer.
+func (v *CacheServerCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *CacheServerCustomValidator) validateEtcd(ctx context.Context, cacheServer *operatorkcpiov1alpha1.CacheServer) error {
+	return validateEtcdKeyPrefix(ctx, v.Client, etcdUser{kind: reference.KindCacheServer, namespace: cacheServer.Namespace, name: cacheServer.Name, etcd: cacheServer.Spec.Etcd})
+}
+
+func (v *CacheServerCustomValidator) validateVersion(ctx context.Context, cacheServer *operatorkcpiov1alpha1.CacheServer) (admission.Warnings, error) {
+	var rootShards operatorkcpiov1alpha1.RootShardList
+	if err := v.Client.List(ctx, &rootShards, client.InNamespace(cacheServer.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list RootShards: %w", err)
+	}
+
+	for _, rs := range rootShards.Items {
+		if ref := rs.Spec.Cache.Reference; ref != nil && ref.Name == cacheServer.Name {
+			return validateVersionSkew(reference.KindCacheServer, cacheServer.Spec.Image, resources.GetRootShardVersion(&rs))
+		}
+	}
+
+	return validateVersionSkew(reference.KindCacheServer, cacheServer.Spec.Image, "")
+}
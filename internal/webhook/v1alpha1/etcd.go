@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// etcdUser is a component storing its data in an etcd cluster.
+type etcdUser struct {
+	kind      string
+	namespace string
+	name      string
+	etcd      operatorkcpiov1alpha1.EtcdConfig
+}
+
+// validateEtcdKeyPrefix checks that no other RootShard, Shard or CacheServer shares an etcd endpoint with
+// the given component while using the same or a nested key prefix, as they would overwrite each other's
+// data. Endpoints are compared by host and port, with in-cluster Service names qualified by namespace.
+func validateEtcdKeyPrefix(ctx context.Context, c client.Reader, self etcdUser) error {
+	users, err := listEtcdUsers(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	endpoints := map[string]struct{}{}
+	for _, endpoint := range self.etcd.Endpoints {
+		endpoints[normalizeEtcdEndpoint(endpoint, self.namespace)] = struct{}{}
+	}
+
+	prefix := resources.GetEtcdKeyPrefix(self.etcd)
+
+	for _, other := range users {
+		if other.kind == self.kind && other.namespace == self.namespace && other.name == self.name {
+			continue
+		}
+
+		otherPrefix := resources.GetEtcdKeyPrefix(other.etcd)
+		if !etcdKeyPrefixesOverlap(prefix, otherPrefix) {
+			continue
+		}
+
+		for _, endpoint := range other.etcd.Endpoints {
+			if _, ok := endpoints[normalizeEtcdEndpoint(endpoint, other.namespace)]; ok {
+				return fmt.Errorf("spec.etcd.keyPrefix: key prefix %q overlaps with prefix %q of %s %s/%s on etcd endpoint %s",
+					prefix, otherPrefix, other.kind, other.namespace, other.name, endpoint)
+			}
+		}
+	}
+
+	return nil
+}
+
+// etcdStorageChanged returns true if the etcd endpoints or the key prefix differ.
+func etcdStorageChanged(oldEtcd, newEtcd operatorkcpiov1alpha1.EtcdConfig) bool {
+	return oldEtcd.KeyPrefix != newEtcd.KeyPrefix || !slices.Equal(oldEtcd.Endpoints, newEtcd.Endpoints)
+}
+
+func listEtcdUsers(ctx context.Context, c client.Reader) ([]etcdUser, error) {
+	var users []etcdUser
+
+	var rootShards operatorkcpiov1alpha1.RootShardList
+	if err := c.List(ctx, &rootShards); err != nil {
+		return nil, fmt.Errorf("failed to list RootShards: %w", err)
+	}
+	for _, rs := range rootShards.Items {
+		users = append(users, etcdUser{kind: reference.KindRootShard, namespace: rs.Namespace, name: rs.Name, etcd: rs.Spec.Etcd})
+	}
+
+	var shards operatorkcpiov1alpha1.ShardList
+	if err := c.List(ctx, &shards); err != nil {
+		return nil, fmt.Errorf("failed to list Shards: %w", err)
+	}
+	for _, s := range shards.Items {
+		users = append(users, etcdUser{kind: reference.KindShard, namespace: s.Namespace, name: s.Name, etcd: s.Spec.Etcd})
+	}
+
+	var cacheServers operatorkcpiov1alpha1.CacheServerList
+	if err := c.List(ctx, &cacheServers); err != nil {
+		return nil, fmt.Errorf("failed to list CacheServers: %w", err)
+	}
+	for _, cs := range cacheServers.Items {
+		users = append(users, etcdUser{kind: reference.KindCacheServer, namespace: cs.Namespace, name: cs.Name, etcd: cs.Spec.Etcd})
+	}
+
+	return users, nil
+}
+
+// etcdKeyPrefixesOverlap returns true if the key prefixes are equal or one is nested in the other.
+func etcdKeyPrefixesOverlap(a, b string) bool {
+	a = strings.TrimSuffix(a, "/") + "/"
+	b = strings.TrimSuffix(b, "/") + "/"
+
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// normalizeEtcdEndpoint returns the host and port of the given endpoint, with short in-cluster Service
+// names qualified by the given namespace, so that "etcd:2379" used in two namespaces is not mistaken
+// for the same etcd cluster, while "etcd.kcp.svc:2379" and "etcd:2379" in namespace "kcp" are.
+func normalizeEtcdEndpoint(endpoint, namespace string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimSuffix(host, ".cluster.local")
+	host = strings.TrimSuffix(host, ".svc")
+	if !strings.Contains(host, ".") && net.ParseIP(host) == nil && host != "localhost" {
+		host = fmt.Sprintf("%s.%s", host, namespace)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "2379"
+	}
+
+	return host + ":" + port
+}
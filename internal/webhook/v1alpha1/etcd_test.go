@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+func TestValidateEtcdKeyPrefix(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := operatorkcpiov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	existing := &operatorkcpiov1alpha1.Shard{
+		ObjectMeta: metav1.ObjectMeta{Name: "alpha", Namespace: "kcp"},
+		Spec: operatorkcpiov1alpha1.ShardSpec{
+			CommonShardSpec: operatorkcpiov1alpha1.CommonShardSpec{
+				Etcd: operatorkcpiov1alpha1.EtcdConfig{
+					Endpoints: []string{"https://etcd:2379"},
+					KeyPrefix: "/kcp/alpha",
+				},
+			},
+		},
+	}
+
+	validator := &ShardCustomValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()}
+	ctx := context.Background()
+
+	testcases := []struct {
+		name        string
+		namespace   string
+		etcd        operatorkcpiov1alpha1.EtcdConfig
+		expectedErr bool
+	}{
+		{
+			name:      "distinct prefix on the same etcd",
+			namespace: "kcp",
+			etcd:      operatorkcpiov1alpha1.EtcdConfig{Endpoints: []string{"https://etcd:2379"}, KeyPrefix: "/kcp/beta"},
+		},
+		{
+			name:        "same prefix on the same etcd",
+			namespace:   "kcp",
+			etcd:        operatorkcpiov1alpha1.EtcdConfig{Endpoints: []string{"https://etcd.kcp.svc.cluster.local:2379"}, KeyPrefix: "/kcp/alpha"},
+			expectedErr: true,
+		},
+		{
+			name:        "nested prefix on the same etcd",
+			namespace:   "kcp",
+			etcd:        operatorkcpiov1alpha1.EtcdConfig{Endpoints: []string{"https://etcd:2379"}, KeyPrefix: "/kcp"},
+			expectedErr: true,
+		},
+		{
+			name:      "same prefix on another etcd",
+			namespace: "kcp",
+			etcd:      operatorkcpiov1alpha1.EtcdConfig{Endpoints: []string{"https://other-etcd:2379"}, KeyPrefix: "/kcp/alpha"},
+		},
+		{
+			name:      "same short Service name in another namespace",
+			namespace: "tenant",
+			etcd:      operatorkcpiov1alpha1.EtcdConfig{Endpoints: []string{"https://etcd:2379"}, KeyPrefix: "/kcp/alpha"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			shard := &operatorkcpiov1alpha1.Shard{
+				ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: tc.namespace},
+				Spec: operatorkcpiov1alpha1.ShardSpec{
+					CommonShardSpec: operatorkcpiov1alpha1.CommonShardSpec{Etcd: tc.etcd},
+				},
+			}
+
+			err := validator.validateEtcd(ctx, shard)
+			if tc.expectedErr && err == nil {
+				t.Error("expected the key prefix to be rejected")
+			}
+			if !tc.expectedErr && err != nil {
+				t.Errorf("expected the key prefix to be accepted, got %v", err)
+			}
+		})
+	}
+
+	// updating the existing shard itself must not conflict with its own prefix
+	if err := validator.validateEtcd(ctx, existing); err != nil {
+		t.Errorf("expected a shard not to conflict with itself, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var frontproxylog = logf.Log.WithName("frontproxy-resource")
+
+// SetupFrontProxyWebhookWithManager registers the webhook for FrontProxy in the manager.
+func SetupFrontProxyWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&operatorkcpiov1alpha1.FrontProxy{}).
+		WithValidator(&FrontProxyCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-kcp-io-v1alpha1-frontproxy,mutating=false,failurePolicy=fail,sideEffects=None,groups=operator.kcp.io,resources=frontproxies,verbs=create;update,versions=v1alpha1,name=vfrontproxy-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// FrontProxyCustomValidator validates FrontProxy objects, in particular that references to RootShards in
+// other namespaces are permitted by a ReferenceGrant and that a pinned kcp version is compatible with
+// the RootShard.
+type FrontProxyCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &FrontProxyCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type FrontProxy.
+func (v *FrontProxyCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	frontProxy, ok := obj.(*operatorkcpiov1alpha1.FrontProxy)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrontProxy object but got %T", obj)
+	}
+	frontproxylog.Info("Validation for FrontProxy upon creation", "name", frontProxy.GetName())
+
+	if err := v.validateRootShardReference(ctx, frontProxy); err != nil {
+		return nil, err
+	}
+
+	return v.validateVersion(ctx, frontProxy)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type FrontProxy.
+func (v *FrontProxyCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldFrontProxy, ok := oldObj.(*operatorkcpiov1alpha1.FrontProxy)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrontProxy object for the oldObj but got %T", oldObj)
+	}
+	frontProxy, ok := newObj.(*operatorkcpiov1alpha1.FrontProxy)
+	if !ok {
+		return nil, fmt.Errorf("expected a FrontProxy object for the newObj but got %T", newObj)
+	}
+	frontproxylog.Info("Validation for FrontProxy upon update", "name", frontProxy.GetName())
+
+	// only re-check the reference and version when they change, so that revoking a grant or upgrading
+	// the RootShard does not block unrelated updates (the reconciler reports those cases instead).
+	refChanged := !equality.Semantic.DeepEqual(oldFrontProxy.Spec.RootShard, frontProxy.Spec.RootShard)
+	if refChanged {
+		if err := v.validateRootShardReference(ctx, frontProxy); err != nil {
+			return nil, err
+		}
+	}
+
+	if refChanged || !equality.Semantic.DeepEqual(oldFrontProxy.Spec.Image, frontProxy.Spec.Image) {
+		return v.validateVersion(ctx, frontProxy)
+	}
+
+	return nil, nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type FrontProxy.
+func (v *FrontProxyCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *FrontProxyCustomValidator) validateRootShardReference(ctx context.Context, frontProxy *operatorkcpiov1alpha1.FrontProxy) error {
+	if _, err := reference.CheckRootShardReference(ctx, v.Client, reference.KindFrontProxy, frontProxy.Namespace, frontProxy.Spec.RootShard); err != nil {
+		return fmt.Errorf("spec.rootShard.ref: %w", err)
+	}
+
+	return nil
+}
+
+func (v *FrontProxyCustomValidator) validateVersion(ctx context.Context, frontProxy *operatorkcpiov1alpha1.FrontProxy) (admission.Warnings, error) {
+	rootShard, err := reference.ResolveRootShard(ctx, v.Client, reference.KindFrontProxy, frontProxy.Namespace, frontProxy.Spec.RootShard)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return validateVersionSkew(reference.KindFrontProxy, frontProxy.Spec.Image, "")
+		}
+		return nil, err
+	}
+
+	return validateVersionSkew(reference.KindFrontProxy, frontProxy.Spec.Image, resources.GetRootShardVersion(rootShard))
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+	"github.com/kcp-dev/kcp-operator/internal/version"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var rootshardlog = logf.Log.WithName("rootshard-resource")
+
+// SetupRootShardWebhookWithManager registers the webhook for RootShard in the manager.
+func SetupRootShardWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&operatorkcpiov1alpha1.RootShard{}).
+		WithValidator(&RootShardCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-kcp-io-v1alpha1-rootshard,mutating=false,failurePolicy=fail,sideEffects=None,groups=operator.kcp.io,resources=rootshards,verbs=create;update,versions=v1alpha1,name=vrootshard-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RootShardCustomValidator validates RootShard objects, in particular that the configured kcp version is
+// supported, that version changes are supported upgrades and that components with a pinned version stay
+// within the supported version skew.
+type RootShardCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &RootShardCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type RootShard.
+func (v *RootShardCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	rootShard, ok := obj.(*operatorkcpiov1alpha1.RootShard)
+	if !ok {
+		return nil, fmt.Errorf("expected a RootShard object but got %T", obj)
+	}
+	rootshardlog.Info("Validation for RootShard upon creation", "name", rootShard.GetName())
+
+	if err := v.validateEtcd(ctx, rootShard); err != nil {
+		return nil, err
+	}
+
+	return v.validateVersion(ctx, rootShard)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type RootShard.
+func (v *RootShardCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldRootShard, ok := oldObj.(*operatorkcpiov1alpha1.RootShard)
+	if !ok {
+		return nil, fmt.Errorf("expected a RootShard object for the oldObj but got %T", oldObj)
+	}
+	rootShard, ok := newObj.(*operatorkcpiov1alpha1.RootShard)
+	if !ok {
+		return nil, fmt.Errorf("expected a RootShard object for the newObj but got %T", newObj)
+	}
+	rootshardlog.Info("Validation for RootShard upon update", "name", rootShard.GetName())
+
+	if etcdStorageChanged(oldRootShard.Spec.Etcd, rootShard.Spec.Etcd) {
+		if err := v.validateEtcd(ctx, rootShard); err != nil {
+			return nil, err
+		}
+	}
+
+	oldVersion := resources.GetEffectiveVersion(oldRootShard.Spec.Image, resources.GetDesiredVersion(oldRootShard))
+	newVersion := resources.GetEffectiveVersion(rootShard.Spec.Image, resources.GetDesiredVersion(rootShard))
+	if oldVersion == newVersion {
+		return nil, nil
+	}
+
+	if err := validateUpgrade(oldRootShard, resources.GetDesiredVersion(rootShard)); err != nil {
+		return nil, err
+	}
+
+	return v.validateVersion(ctx, rootShard)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type RootShard.
+func (v *RootShardCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RootShardCustomValidator) validateEtcd(ctx context.Context, rootShard *operatorkcpiov1alpha1.RootShard) error {
+	return validateEtcdKeyPrefix(ctx, v.Client, etcdUser{kind: reference.KindRootShard, namespace: rootShard.Namespace, name: rootShard.Name, etcd: rootShard.Spec.Etcd})
+}
+
+// validateUpgrade checks that changing the kcp version of the given root shard to target is a supported
+// upgrade. Rolling back a failed upgrade to the previous version is always allowed.
+func validateUpgrade(rootShard *operatorkcpiov1alpha1.RootShard, target string) error {
+	current := resources.GetDesiredVersion(rootShard)
+	if upgrade := rootShard.Status.Upgrade; upgrade != nil {
+		if upgrade.Failed && upgrade.FromVersion == target {
+			return nil
+		}
+		current = upgrade.TargetVersion
+	}
+
+	if err := version.CheckUpgrade(current, target); err != nil && !errors.Is(err, version.ErrUnknownVersion) {
+		return fmt.Errorf("spec.version: %w", err)
+	}
+
+	return nil
+}
+
+// validateVersion checks the root shard's kcp version against the compatibility matrix and verifies that
+// all components pinning their version via the image tag are within the supported skew.
+func (v *RootShardCustomValidator) validateVersion(ctx context.Context, rootShard *operatorkcpiov1alpha1.RootShard) (admission.Warnings, error) {
+	rootShardVersion := resources.GetEffectiveVersion(rootShard.Spec.Image, resources.GetDesiredVersion(rootShard))
+
+	var warnings admission.Warnings
+	if err := version.IsSupported(rootShardVersion); err != nil {
+		if !errors.Is(err, version.ErrUnknownVersion) {
+			return nil, fmt.Errorf("spec.version: %w", err)
+		}
+		return admission.Warnings{fmt.Sprintf("kcp version compatibility cannot be verified: %v", err)}, nil
+	}
+
+	check := func(kind, name string, image *operatorkcpiov1alpha1.ImageSpec) error {
+		w, err := validateVersionSkew(kind, image, rootShardVersion)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", kind, name, err)
+		}
+		warnings = append(warnings, w...)
+		return nil
+	}
+
+	if ref := rootShard.Spec.Cache.Reference; ref != nil {
+		var cacheServer operatorkcpiov1alpha1.CacheServer
+		if err := v.Client.Get(ctx, client.ObjectKey{Namespace: rootShard.Namespace, Name: ref.Name}, &cacheServer); client.IgnoreNotFound(err) != nil {
+			return nil, fmt.Errorf("failed to get CacheServer: %w", err)
+		} else if err == nil {
+			if err := check(reference.KindCacheServer, ref.Name, cacheServer.Spec.Image); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var shards operatorkcpiov1alpha1.ShardList
+	if err := v.Client.List(ctx, &shards); err != nil {
+		return nil, fmt.Errorf("failed to list Shards: %w", err)
+	}
+
+	for _, s := range shards.Items {
+		if key, err := reference.GetRootShardKey(s.Namespace, s.Spec.RootShard); err == nil && key == client.ObjectKeyFromObject(rootShard) {
+			if err := check(reference.KindShard, client.ObjectKeyFromObject(&s).String(), s.Spec.Image); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var frontProxies operatorkcpiov1alpha1.FrontProxyList
+	if err := v.Client.List(ctx, &frontProxies); err != nil {
+		return nil, fmt.Errorf("failed to list FrontProxies: %w", err)
+	}
+
+	for _, fp := range frontProxies.Items {
+		if key, err := reference.GetRootShardKey(fp.Namespace, fp.Spec.RootShard); err == nil && key == client.ObjectKeyFromObject(rootShard) {
+			if err := check(reference.KindFrontProxy, client.ObjectKeyFromObject(&fp).String(), fp.Spec.Image); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/reference"
+	"github.com/kcp-dev/kcp-operator/internal/resources"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var shardlog = logf.Log.WithName("shard-resource")
+
+// SetupShardWebhookWithManager registers the webhook for Shard in the manager.
+func SetupShardWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&operatorkcpiov1alpha1.Shard{}).
+		WithValidator(&ShardCustomValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-operator-kcp-io-v1alpha1-shard,mutating=false,failurePolicy=fail,sideEffects=None,groups=operator.kcp.io,resources=shards,verbs=create;update,versions=v1alpha1,name=vshard-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ShardCustomValidator validates Shard objects, in particular that references to RootShards in
+// other namespaces are permitted by a ReferenceGrant and that a pinned kcp version is compatible with
+// the RootShard.
+type ShardCustomValidator struct {
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &ShardCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Shard.
+func (v *ShardCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	shard, ok := obj.(*operatorkcpiov1alpha1.Shard)
+	if !ok {
+		return nil, fmt.Errorf("expected a Shard object but got %T", obj)
+	}
+	shardlog.Info("Validation for Shard upon creation", "name", shard.GetName())
+
+	if err := v.validateRootShardReference(ctx, shard); err != nil {
+		return nil, err
+	}
+
+	if err := v.validateEtcd(ctx, shard); err != nil {
+		return nil, err
+	}
+
+	return v.validateVersion(ctx, shard)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Shard.
+func (v *ShardCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldShard, ok := oldObj.(*operatorkcpiov1alpha1.Shard)
+	if !ok {
+		return nil, fmt.Errorf("expected a Shard object for the oldObj but got %T", oldObj)
+	}
+	shard, ok := newObj.(*operatorkcpiov1alpha1.Shard)
+	if !ok {
+		return nil, fmt.Errorf("expected a Shard object for the newObj but got %T", newObj)
+	}
+	shardlog.Info("Validation for Shard upon update", "name", shard.GetName())
+
+	// only re-check the reference and version when they change, so that revoking a grant or upgrading
+	// the RootShard does not block unrelated updates (the reconciler reports those cases instead).
+	refChanged := !equality.Semantic.DeepEqual(oldShard.Spec.RootShard, shard.Spec.RootShard)
+	if refChanged {
+		if err := v.validateRootShardReference(ctx, shard); err != nil {
+			return nil, err
+		}
+	}
+
+	if etcdStorageChanged(oldShard.Spec.Etcd, shard.Spec.Etcd) {
+		if err := v.validateEtcd(ctx, shard); err != nil {
+			return nil, err
+		}
+	}
+
+	if refChanged || !equality.Semantic.DeepEqual(oldShard.Spec.Image, shard.Spec.Image) {
+		return v.validateVersion(ctx, shard)
+	}
+
+	return nil, nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Shard.
+func (v *ShardCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ShardCustomValidator) validateRootShardReference(ctx context.Context, shard *operatorkcpiov1alpha1.Shard) error {
+	if _, err := reference.CheckRootShardReference(ctx, v.Client, reference.KindShard, shard.Namespace, shard.Spec.RootShard); err != nil {
+		return fmt.Errorf("spec.rootShard.ref: %w", err)
+	}
+
+	return nil
+}
+
+func (v *ShardCustomValidator) validateEtcd(ctx context.Context, shard *operatorkcpiov1alpha1.Shard) error {
+	return validateEtcdKeyPrefix(ctx, v.Client, etcdUser{kind: reference.KindShard, namespace: shard.Namespace, name: shard.Name, etcd: shard.Spec.Etcd})
+}
+
+func (v *ShardCustomValidator) validateVersion(ctx context.Context, shard *operatorkcpiov1alpha1.Shard) (admission.Warnings, error) {
+	rootShard, err := reference.ResolveRootShard(ctx, v.Client, reference.KindShard, shard.Namespace, shard.Spec.RootShard)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return validateVersionSkew(reference.KindShard, shard.Spec.Image, "")
+		}
+		return nil, err
+	}
+
+	return validateVersionSkew(reference.KindShard, shard.Spec.Image, resources.GetRootShardVersion(rootShard))
+}
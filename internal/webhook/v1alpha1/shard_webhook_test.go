@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+)
+
+func TestShardValidateRootShardReference(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := operatorkcpiov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	newShard := func(refNamespace string) *operatorkcpiov1alpha1.Shard {
+		return &operatorkcpiov1alpha1.Shard{
+			ObjectMeta: metav1.ObjectMeta{Name: "shard", Namespace: "tenant-a"},
+			Spec: operatorkcpiov1alpha1.ShardSpec{
+				RootShard: operatorkcpiov1alpha1.RootShardConfig{
+					Reference: &corev1.ObjectReference{Name: "root", Namespace: refNamespace},
+				},
+			},
+		}
+	}
+
+	validator := &ShardCustomValidator{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	ctx := context.Background()
+
+	if _, err := validator.ValidateCreate(ctx, newShard("")); err != nil {
+		t.Errorf("expected same-namespace reference to be accepted, got %v", err)
+	}
+
+	if _, err := validator.ValidateCreate(ctx, newShard("platform")); err == nil {
+		t.Error("expected cross-namespace reference without ReferenceGrant to be rejected")
+	}
+
+	if _, err := validator.ValidateUpdate(ctx, newShard("platform"), newShard("platform")); err != nil {
+		t.Errorf("expected update with unchanged reference to be accepted, got %v", err)
+	}
+
+	if _, err := validator.ValidateUpdate(ctx, newShard(""), newShard("platform")); err == nil {
+		t.Error("expected update to a non-permitted cross-namespace reference to be rejected")
+	}
+
+	rootShard := &operatorkcpiov1alpha1.RootShard{
+		ObjectMeta: metav1.ObjectMeta{Name: "root", Namespace: "tenant-a"},
+		Spec:       operatorkcpiov1alpha1.RootShardSpec{Version: "v0.26.0"},
+	}
+	validator = &ShardCustomValidator{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(rootShard).Build()}
+
+	pinned := newShard("")
+	pinned.Spec.Image = &operatorkcpiov1alpha1.ImageSpec{Tag: "v0.24.0"}
+	if _, err := validator.ValidateCreate(ctx, pinned); err == nil {
+		t.Error("expected shard pinned outside of the supported version skew to be rejected")
+	}
+
+	pinned.Spec.Image.Tag = "v0.25.0"
+	if _, err := validator.ValidateCreate(ctx, pinned); err != nil {
+		t.Errorf("expected shard pinned within the supported version skew to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2024 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	operatorkcpiov1alpha1 "github.com/kcp-dev/kcp-operator/api/v1alpha1"
+	"github.com/kcp-dev/kcp-operator/internal/version"
+)
+
+// validateVersionSkew checks the kcp version pinned via the image tag of a component of the given kind
+// against the compatibility matrix and, if set, the version of its root shard. Components inheriting
+// their version from the root shard are always compatible. Versions that cannot be parsed only produce
+// a warning.
+func validateVersionSkew(kind string, image *operatorkcpiov1alpha1.ImageSpec, rootShardVersion string) (admission.Warnings, error) {
+	if image == nil || image.Tag == "" {
+		return nil, nil
+	}
+
+	err := version.IsSupported(image.Tag)
+	if err == nil && rootShardVersion != "" {
+		err = version.CheckSkew(kind, image.Tag, rootShardVersion)
+	}
+
+	switch {
+	case errors.Is(err, version.ErrUnknownVersion):
+		return admission.Warnings{fmt.Sprintf("kcp version compatibility cannot be verified: %v", err)}, nil
+	case err != nil:
+		return nil, fmt.Errorf("spec.image.tag: %w", err)
+	default:
+		return nil, nil
+	}
+}